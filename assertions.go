@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Assertion checks a result and returns an error describing why it does not hold
+type Assertion func(result *Result) error
+
+// runAssertions records the failure of every assertion on the result
+func runAssertions(result *Result, assertions []Assertion) {
+	for _, assert := range assertions {
+		err := assert(result)
+		if err == nil {
+			continue
+		}
+
+		// Assertions that find several problems return them joined, record each one
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				result.Failures = append(result.Failures, e.Error())
+			}
+			continue
+		}
+		result.Failures = append(result.Failures, err.Error())
+	}
+}
+
+// assertBodyNotEmpty fails a successful response that has no body
+func assertBodyNotEmpty(result *Result) error {
+	if result.Response.StatusCode < 300 && len(result.Body) == 0 {
+		return fmt.Errorf("response body is empty despite status %s", result.Response.Status)
+	}
+	return nil
+}
+
+// assertBodyNotMatching fails a response whose body still matches the -retry-on-body-regex pattern
+func assertBodyNotMatching(pattern *regexp.Regexp) Assertion {
+	return func(result *Result) error {
+		if pattern.Match(result.Body) {
+			return fmt.Errorf("response body still matches -retry-on-body-regex %q", pattern)
+		}
+		return nil
+	}
+}
+
+// assertStatus fails a response whose status code is not the expected one
+func assertStatus(expected int) Assertion {
+	return func(result *Result) error {
+		if result.Response.StatusCode != expected {
+			return fmt.Errorf("expected status %d, got %s", expected, result.Response.Status)
+		}
+		return nil
+	}
+}
+
+// assertRedirect fails a response that is not a redirect with the expected status, 0 accepts any 3xx status,
+// or whose Location does not match location when it is set
+func assertRedirect(status int, location string) Assertion {
+	return func(result *Result) error {
+		response := result.Response
+		if status != 0 && response.StatusCode != status {
+			return fmt.Errorf("expected redirect %d, got %s", status, response.Status)
+		}
+		if status == 0 && (response.StatusCode < 300 || response.StatusCode > 399) {
+			return fmt.Errorf("expected a redirect, got %s", response.Status)
+		}
+
+		if location == "" {
+			return nil
+		}
+		got := response.Header.Get("Location")
+		if got == location {
+			return nil
+		}
+		// A relative Location matches the absolute URL it resolves to
+		if resolved, err := response.Location(); err == nil && resolved.String() == location {
+			return nil
+		}
+		return fmt.Errorf("expected Location %s, got %q", location, got)
+	}
+}
+
+// assertContentType fails a response whose media type, ignoring parameters such as charset, does not start with expected
+func assertContentType(expected string) Assertion {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	return func(result *Result) error {
+		contentType := result.Response.Header.Get("Content-Type")
+		mediaType, _, _ := strings.Cut(contentType, ";")
+		if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(mediaType)), expected) {
+			return fmt.Errorf("expected Content-Type %s, got %q", expected, contentType)
+		}
+		return nil
+	}
+}
+
+// assertSHA256 fails a response whose body does not hash to the expected hex digest
+func assertSHA256(expected string) Assertion {
+	return func(result *Result) error {
+		if !strings.EqualFold(result.SHA256, expected) {
+			return fmt.Errorf("response body sha256 %s does not match expected %s", result.SHA256, expected)
+		}
+		return nil
+	}
+}
+
+// assertSchema fails a response whose body does not conform to the JSON Schema
+func assertSchema(schema map[string]any) Assertion {
+	return func(result *Result) error {
+		var body any
+		if err := json.Unmarshal(result.Body, &body); err != nil {
+			return fmt.Errorf("schema: response body is not valid JSON: %v", err)
+		}
+
+		var errs []error
+		for _, violation := range ValidateSchema(schema, body) {
+			errs = append(errs, fmt.Errorf("schema: %s", violation))
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFailOnBodyEmpty(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/full" {
+			w.Write([]byte("data"))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/full", "-fail-on-body-empty", "-output", "full"); code != 0 {
+		t.Errorf("non-empty body: exit code %d, want 0: %s", code, stderr)
+	}
+
+	_, stderr, code := runMain(t, dir, "-url", srv.URL+"/empty", "-fail-on-body-empty", "-output", "empty")
+	if code != 1 || !strings.Contains(stderr, "response body is empty despite status 200 OK") {
+		t.Errorf("empty body: exit code %d, want 1 with an empty body failure: %s", code, stderr)
+	}
+}
+
+func TestExpectStatusDirective(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "expect.http", "# @expect 404\nGET "+srv.URL+"/missing\n\n###\n# @expect 503\nGET "+srv.URL+"/down\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-retry", "3", "-output", "out"); code != 0 {
+		t.Errorf("expected statuses: exit code %d, want 0: %s", code, stderr)
+	}
+	if calls != 2 {
+		t.Errorf("server got %d requests, want 2 since an expected 503 is not retried", calls)
+	}
+
+	_, stderr, code := runMain(t, dir, "-url", srv.URL+"/ok", "-expect-status", "201", "-output", "ok")
+	if code != 1 || !strings.Contains(stderr, "expected status 201, got 200 OK") {
+		t.Errorf("unexpected status: exit code %d, want 1 with the status failure: %s", code, stderr)
+	}
+}
+
+func TestExpectRedirectAndLocation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/old", "-expect-redirect", "301", "-expect-location", srv.URL+"/new", "-output", "match"); code != 0 {
+		t.Errorf("matching redirect: exit code %d, want 0: %s", code, stderr)
+	}
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/old", "-expect-location", "/new", "-output", "relative"); code != 0 {
+		t.Errorf("relative Location: exit code %d, want 0: %s", code, stderr)
+	}
+
+	_, stderr, code := runMain(t, dir, "-url", srv.URL+"/old", "-expect-redirect", "301", "-expect-location", srv.URL+"/elsewhere", "-output", "location")
+	if code != 1 || !strings.Contains(stderr, `expected Location `+srv.URL+`/elsewhere, got "/new"`) {
+		t.Errorf("wrong Location: exit code %d, want 1 with a Location failure: %s", code, stderr)
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL+"/old", "-expect-redirect", "302", "-output", "status")
+	if code != 1 || !strings.Contains(stderr, "expected redirect 302, got 301 Moved Permanently") {
+		t.Errorf("wrong status: exit code %d, want 1 with a redirect failure: %s", code, stderr)
+	}
+}
+
+func TestAssertContentTypeFailsHTMLErrorPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"ok":true}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body>Something went wrong</body></html>"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/json", "-assert-content-type", "application/json", "-output", "json"); code != 0 {
+		t.Errorf("JSON with a charset: exit code %d, want 0: %s", code, stderr)
+	}
+
+	_, stderr, code := runMain(t, dir, "-url", srv.URL+"/html", "-assert-content-type", "application/json", "-output", "html")
+	if code != 1 || !strings.Contains(stderr, `expected Content-Type application/json, got "text/html; charset=utf-8"`) {
+		t.Errorf("HTML page: exit code %d, want 1 with a Content-Type failure: %s", code, stderr)
+	}
+}
+
+func TestDryValidateAgainstRecordedResponse(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":7,"name":"alice"}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	// Text reports only hold the response headers when asked to
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-format", "txt,json", "-report-include", "status,response-headers,response-body", "-output", "recorded"); code != 0 {
+		t.Fatalf("recording: exit code %d: %s", code, stderr)
+	}
+	srv.Close()
+
+	for _, ext := range []string{"txt", "json"} {
+		reports, _ := filepath.Glob(filepath.Join(dir, "recorded|*."+ext))
+		if len(reports) != 1 {
+			t.Fatalf("recorded %d %s reports, want 1", len(reports), ext)
+		}
+		report := reports[0]
+		stdout, stderr, code := runMain(t, dir, "-dry-validate", report, "-expect-status", "200", "-assert-content-type", "application/json", "-assert-expr", `body.name == "alice"`)
+		if code != 0 || !strings.Contains(stdout, "3 assertions passed") {
+			t.Errorf("%s, matching assertions: exit code %d, want 0 with 3 passed: %s", ext, code, stderr)
+		}
+
+		_, stderr, code = runMain(t, dir, "-dry-validate", report, "-expect-status", "201", "-assert-expr", `body.id == 8`)
+		if code != 1 || !strings.Contains(stderr, "expected status 201, got 200") || !strings.Contains(stderr, "2 assertion failures") {
+			t.Errorf("%s, non-matching assertions: exit code %d, want 1 with both failures: %s", ext, code, stderr)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("server got %d requests, want only the recording one", calls)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Attempt records the outcome of a single try of a request
+type Attempt struct {
+	Number     int
+	StatusCode int
+	Status     string
+	Latency    time.Duration
+	Err        error
+}
+
+// Failed reports whether the attempt should be retried
+func (a Attempt) Failed() bool {
+	return a.Err != nil || a.StatusCode >= http.StatusInternalServerError
+}
+
+// NewAttempt builds an Attempt from the result of Execute
+func NewAttempt(number int, result *Result, latency time.Duration, err error) Attempt {
+	attempt := Attempt{Number: number, Latency: latency, Err: err}
+	if result != nil {
+		attempt.StatusCode = result.Response.StatusCode
+		attempt.Status = result.Response.Status
+	}
+	return attempt
+}
+
+// GenerateConsolidatedReport writes every attempt of a request, in order, into a single file
+func GenerateConsolidatedReport(sink ReportSink, outputPath string, reqData RequestData, attempts []Attempt) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-attempts.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nRequest URL: %s\n\nAttempts:\n", reqData.Method, reqData.URL))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attempts {
+		line := fmt.Sprintf("#%d status: %s latency: %s", a.Number, a.Status, a.Latency)
+		if a.Err != nil {
+			line = fmt.Sprintf("#%d error: %v latency: %s", a.Number, a.Err, a.Latency)
+		}
+		_, err = io.WriteString(file, line+"\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RetryLog records every attempt of the requests that still failed after their last retry
+type RetryLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRetryLog creates the retry log file
+func NewRetryLog(path string) (*RetryLog, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RetryLog{file: file}, nil
+}
+
+// Record writes the request and the status or error of each of its attempts, in order
+func (l *RetryLog) Record(outcome Outcome) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := fmt.Sprintf("%s %s %s failed after %d attempts\n", time.Now().Format(time.RFC3339), outcome.Request.Method, outcome.Request.URL, len(outcome.Attempts))
+	for _, a := range outcome.Attempts {
+		if a.Err != nil {
+			entry += fmt.Sprintf("#%d error: %v latency: %s\n", a.Number, a.Err, a.Latency)
+		} else {
+			entry += fmt.Sprintf("#%d status: %s latency: %s\n", a.Number, a.Status, a.Latency)
+		}
+	}
+	if outcome.Result != nil {
+		for _, failure := range outcome.Result.Failures {
+			entry += "assertion failed: " + failure + "\n"
+		}
+	}
+
+	if _, err := io.WriteString(l.file, entry+"\n"); err != nil {
+		printError("retry log:", err)
+	}
+}
+
+func (l *RetryLog) Close() error {
+	return l.file.Close()
+}
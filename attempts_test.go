@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConsolidatedReportListsEveryAttempt(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 3, Consolidated: true, Report: ReportOptions{Sink: sink}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, "-attempts.txt")
+	lines := []string{"#1 status: 500 Internal Server Error", "#2 status: 500 Internal Server Error", "#3 status: 200 OK"}
+	last := -1
+	for _, line := range lines {
+		i := strings.Index(report, line)
+		if i <= last {
+			t.Fatalf("report does not list %q after the attempts before it:\n%s", line, report)
+		}
+		last = i
+	}
+}
+
+func TestRetryLogRecordsEveryFailedAttempt(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// A body shorter than its Content-Length fails the attempt with a read error
+			w.Header().Set("Content-Length", "10")
+			w.Write([]byte("ok"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "retries.log")
+	retryLog, err := NewRetryLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &Runner{Client: srv.Client(), Retry: 3, RetryLog: retryLog, Report: ReportOptions{Sink: DiscardSink{}}}
+	if outcome := runner.Run(NewURLRequest(srv.URL+"/flaky"), "out"); outcome.Passed {
+		t.Fatal("request passed, want it to fail after its last retry")
+	}
+	retryLog.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	lines := []string{"GET " + srv.URL + "/flaky failed after 3 attempts", "#1 status: 503 Service Unavailable", "#2 error: unexpected EOF", "#3 status: 502 Bad Gateway"}
+	last := -1
+	for _, line := range lines {
+		i := strings.Index(log, line)
+		if i <= last {
+			t.Fatalf("retry log does not list %q after the lines before it:\n%s", line, log)
+		}
+		last = i
+	}
+}
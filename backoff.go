@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryDelay returns the wait before the next attempt, adding up to jitter*base of random delay
+func RetryDelay(base time.Duration, jitter float64, rng *rand.Rand) time.Duration {
+	if jitter <= 0 || base <= 0 {
+		return base
+	}
+	return base + time.Duration(rng.Float64()*jitter*float64(base))
+}
+
+// SplayDelay returns a random wait of up to splay before the first request, spreading out runs scheduled at the same time
+func SplayDelay(splay time.Duration, rng *rand.Rand) time.Duration {
+	if splay <= 0 {
+		return 0
+	}
+	return time.Duration(rng.Int63n(int64(splay) + 1))
+}
+
+// adaptiveBackoffFactor is how many times the last attempt latency -backoff=adaptive waits
+const adaptiveBackoffFactor = 2
+
+// AdaptiveDelay returns the wait before the next attempt scaled from the latency of the previous one,
+// so a slow server gets more time to recover and a fast one is retried sooner
+func AdaptiveDelay(latency time.Duration) time.Duration {
+	return adaptiveBackoffFactor * latency
+}
+
+// newJitterRand returns the RNG used for jitter and -splay, seeded from the clock when seed is 0
+func newJitterRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// delays returns the first n retry delays with a jitter RNG seeded with seed
+func delays(seed int64, n int) []time.Duration {
+	rng := newJitterRand(seed)
+	var out []time.Duration
+	for range n {
+		out = append(out, RetryDelay(time.Second, 0.5, rng))
+	}
+	return out
+}
+
+func TestRetryJitterSeedRepeatsDelays(t *testing.T) {
+	first, second := delays(42, 5), delays(42, 5)
+	if !slices.Equal(first, second) {
+		t.Errorf("seed 42 gave %v and then %v", first, second)
+	}
+	if other := delays(7, 5); slices.Equal(first, other) {
+		t.Errorf("seeds 42 and 7 both gave %v", first)
+	}
+
+	for _, d := range first {
+		if d < time.Second || d > 1500*time.Millisecond {
+			t.Errorf("delay %s is outside 1s to 1.5s", d)
+		}
+	}
+}
+
+func TestAdaptiveBackoffFollowsLatency(t *testing.T) {
+	if AdaptiveDelay(200*time.Millisecond) <= AdaptiveDelay(10*time.Millisecond) {
+		t.Error("adaptive delay does not grow with the latency")
+	}
+
+	// retryGap returns the time between the end of the first attempt and the start of the retry
+	retryGap := func(t *testing.T, latency time.Duration) time.Duration {
+		var ends, starts []time.Time
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			starts = append(starts, time.Now())
+			time.Sleep(latency)
+			w.WriteHeader(http.StatusInternalServerError)
+			ends = append(ends, time.Now())
+		}))
+		defer srv.Close()
+
+		runner := &Runner{Client: srv.Client(), Retry: 2, Backoff: "adaptive", Report: ReportOptions{Sink: DiscardSink{}}}
+		runner.Run(NewURLRequest(srv.URL), "out")
+		if len(starts) != 2 {
+			t.Fatalf("server got %d requests, want 2", len(starts))
+		}
+		return starts[1].Sub(ends[0])
+	}
+
+	slow, fast := retryGap(t, 150*time.Millisecond), retryGap(t, 0)
+	if slow < 2*150*time.Millisecond-20*time.Millisecond {
+		t.Errorf("retry after a 150ms attempt waited %s, want about twice the latency", slow)
+	}
+	if fast >= slow/2 {
+		t.Errorf("retry after a fast attempt waited %s, want much less than the %s after a slow one", fast, slow)
+	}
+}
+
+func TestSplaySeedKeepsDelayInRange(t *testing.T) {
+	const splay = 200 * time.Millisecond
+	for seed := int64(1); seed <= 100; seed++ {
+		delay := SplayDelay(splay, newJitterRand(seed))
+		if delay < 0 || delay > splay {
+			t.Fatalf("seed %d: splay delay %s, want 0 to %s", seed, delay, splay)
+		}
+		if again := SplayDelay(splay, newJitterRand(seed)); again != delay {
+			t.Fatalf("seed %d: splay delays %s and %s, want the seed to repeat it", seed, delay, again)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	want := SplayDelay(splay, newJitterRand(42))
+	start := time.Now()
+	stdout, stderr, code := runMain(t, t.TempDir(), "-url", srv.URL, "-splay", "200ms", "-splay-seed", "42", "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "splay: waiting "+want.Round(time.Millisecond).String()+" before the first request") {
+		t.Errorf("output does not show the seeded delay of %s:\n%s", want.Round(time.Millisecond), stdout)
+	}
+	if elapsed := time.Since(start); elapsed < want {
+		t.Errorf("run took %s, want at least the %s splay", elapsed, want)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// includePattern matches {{include "fragment.json"}} directives in a body template
+var includePattern = regexp.MustCompile(`\{\{\s*include\s+"([^"]+)"\s*\}\}`)
+
+// ReadBodyTemplate reads a body template with its {{include "path"}} directives replaced by the included files.
+// Paths are relative to the file that includes them and fragments may include other fragments.
+func ReadBodyTemplate(path string) (string, error) {
+	return readBodyTemplate(path, nil)
+}
+
+func readBodyTemplate(path string, including []string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(including, abs) {
+		return "", fmt.Errorf("body template %s includes itself", path)
+	}
+	including = append(including, abs)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var includeErr error
+	body := includePattern.ReplaceAllStringFunc(string(data), func(match string) string {
+		if includeErr != nil {
+			return match
+		}
+		fragment := includePattern.FindStringSubmatch(match)[1]
+		if !filepath.IsAbs(fragment) {
+			fragment = filepath.Join(filepath.Dir(path), fragment)
+		}
+		text, err := readBodyTemplate(fragment, including)
+		if err != nil {
+			includeErr = err
+			return match
+		}
+		// The final newline most editors add would otherwise end up in the middle of the body
+		return strings.TrimSuffix(text, "\n")
+	})
+	if includeErr != nil {
+		return "", includeErr
+	}
+	return body, nil
+}
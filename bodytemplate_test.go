@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBodyTemplateInlinesFragments(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer srv.Close()
+
+	// Fragments resolve relative to the file including them, not the working directory
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bodies", "parts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "bodies/parts/address.json", `{"city":"Izmir"}`+"\n")
+	writeFile(t, dir, "bodies/parts/customer.json", `{"name":"alice","address":{{include "address.json"}}}`+"\n")
+	template := writeFile(t, dir, "bodies/order.json", `{"customer":{{include "parts/customer.json"}},"items":[1,2]}`)
+
+	if _, stderr, code := runMain(t, t.TempDir(), "-url", srv.URL, "-method", "POST", "-body-template-file", template, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := `{"customer":{"name":"alice","address":{"city":"Izmir"}},"items":[1,2]}`; gotBody != want {
+		t.Errorf("server got body %s, want %s", gotBody, want)
+	}
+}
+
+func TestBodyTemplateRejectsIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "b.json", `{{include "a.json"}}`)
+	a := writeFile(t, dir, "a.json", `{{include "b.json"}}`)
+	if _, err := ReadBodyTemplate(a); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Errorf("include cycle: error %v, want an includes itself error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// CacheEntry holds the validators remembered for a URL
+type CacheEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ResponseCache persists ETag and Last-Modified validators between runs, keyed by URL
+type ResponseCache struct {
+	path    string
+	Entries map[string]CacheEntry
+
+	mu sync.Mutex
+}
+
+// LoadResponseCache reads the cache file, starting empty when it does not exist yet
+func LoadResponseCache(path string) (*ResponseCache, error) {
+	cache := &ResponseCache{path: path, Entries: make(map[string]CacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &cache.Entries); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// Apply adds conditional headers for a cached URL, only for methods that are safe to revalidate
+func (c *ResponseCache) Apply(reqData *RequestData) {
+	if reqData.Method != http.MethodGet && reqData.Method != http.MethodHead {
+		return
+	}
+
+	c.mu.Lock()
+	entry, ok := c.Entries[reqData.URL]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	if entry.ETag != "" {
+		reqData.Headers["If-None-Match"] = entry.ETag
+	}
+	if entry.LastModified != "" {
+		reqData.Headers["If-Modified-Since"] = entry.LastModified
+	}
+}
+
+// Update records the validators of a response and reports whether it was a cache hit
+func (c *ResponseCache) Update(url string, response *http.Response) bool {
+	if response.StatusCode == http.StatusNotModified {
+		return true
+	}
+
+	entry := CacheEntry{
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+	}
+	if entry.ETag != "" || entry.LastModified != "" {
+		c.mu.Lock()
+		c.Entries[url] = entry
+		c.mu.Unlock()
+	}
+	return false
+}
+
+// Save writes the cache back to its file
+func (c *ResponseCache) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.Entries, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
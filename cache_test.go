@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheFileRevalidatesAcrossRuns(t *testing.T) {
+	var ifNoneMatch []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("fresh"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cacheFile := filepath.Join(dir, "cache.json")
+	args := []string{"-url", srv.URL, "-cache-file", cacheFile, "-output", "out"}
+
+	stdout, stderr, code := runMain(t, dir, args...)
+	if code != 0 || !strings.Contains(stdout, "cache miss: "+srv.URL) {
+		t.Fatalf("first run exit code %d, want a cache miss:\n%s%s", code, stdout, stderr)
+	}
+
+	stdout, stderr, code = runMain(t, dir, args...)
+	if code != 0 || !strings.Contains(stdout, "cache hit: "+srv.URL) {
+		t.Fatalf("second run exit code %d, want a cache hit:\n%s%s", code, stdout, stderr)
+	}
+
+	if len(ifNoneMatch) != 2 || ifNoneMatch[0] != "" || ifNoneMatch[1] != `"v1"` {
+		t.Errorf("If-None-Match sent = %q, want none and then \"v1\"", ifNoneMatch)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// takeCanary counts a request and reports whether it goes to the canary, spreading CanaryPercent
+// of the requests evenly over the batch instead of picking them at random
+func (r *Runner) takeCanary() bool {
+	n := float64(r.canaryCount.Add(1))
+	return int(n*r.CanaryPercent/100) > int((n-1)*r.CanaryPercent/100)
+}
+
+// CanaryGroup is the success and latency of the requests sent to one side of a canary run
+type CanaryGroup struct {
+	Requests    int           `json:"requests"`
+	Passed      int           `json:"passed"`
+	SuccessRate float64       `json:"success_rate"`
+	Latency     *LatencyStats `json:"latency,omitempty"`
+}
+
+// CanaryComparison compares the requests sent to -canary-url with the ones sent to their own URL
+type CanaryComparison struct {
+	Baseline CanaryGroup `json:"baseline"`
+	Canary   CanaryGroup `json:"canary"`
+}
+
+// NewCanaryComparison splits the outcomes into baseline and canary and summarizes each side
+func NewCanaryComparison(outcomes []Outcome) CanaryComparison {
+	var baseline, canary []Outcome
+	for _, o := range outcomes {
+		if o.Canary {
+			canary = append(canary, o)
+		} else {
+			baseline = append(baseline, o)
+		}
+	}
+	return CanaryComparison{Baseline: newCanaryGroup(baseline), Canary: newCanaryGroup(canary)}
+}
+
+func newCanaryGroup(outcomes []Outcome) CanaryGroup {
+	group := CanaryGroup{Requests: len(outcomes)}
+	for _, o := range outcomes {
+		if o.Passed {
+			group.Passed++
+		}
+	}
+	if group.Requests > 0 {
+		group.SuccessRate = float64(group.Passed) / float64(group.Requests)
+	}
+	if latencies := outcomeLatencies(outcomes); len(latencies) > 0 {
+		stats := NewLatencyStats(latencies)
+		group.Latency = &stats
+	}
+	return group
+}
+
+// writeCanaryComparison prints the baseline and canary side by side
+func writeCanaryComparison(w io.Writer, c CanaryComparison) error {
+	latency := func(g CanaryGroup, value func(LatencyStats) float64) string {
+		if g.Latency == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.1fms", value(*g.Latency))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tBASELINE\tCANARY")
+	fmt.Fprintf(tw, "requests\t%d\t%d\n", c.Baseline.Requests, c.Canary.Requests)
+	fmt.Fprintf(tw, "success\t%.1f%%\t%.1f%%\n", c.Baseline.SuccessRate*100, c.Canary.SuccessRate*100)
+	for _, row := range []struct {
+		name  string
+		value func(LatencyStats) float64
+	}{
+		{"mean", func(s LatencyStats) float64 { return s.MeanMS }},
+		{"p50", func(s LatencyStats) float64 { return s.P50MS }},
+		{"p95", func(s LatencyStats) float64 { return s.P95MS }},
+		{"p99", func(s LatencyStats) float64 { return s.P99MS }},
+	} {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", row.name, latency(c.Baseline, row.value), latency(c.Canary, row.value))
+	}
+	return tw.Flush()
+}
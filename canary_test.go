@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCanarySplitsTrafficAndCompares(t *testing.T) {
+	var baselineCalls, canaryCalls atomic.Int32
+	baseline := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		baselineCalls.Add(1)
+	}))
+	defer baseline.Close()
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canaryCalls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer canary.Close()
+
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "-url", baseline.URL+"/items", "-repeat", "20", "-canary-url", canary.URL, "-canary-percent", "25", "-output", "out")
+	if code != 1 {
+		t.Errorf("exit code %d, want 1 for the failing canary: %s", code, stderr)
+	}
+
+	// Canary requests are spread evenly, so a quarter of 20 is exactly 5
+	if baselineCalls.Load() != 15 || canaryCalls.Load() != 5 {
+		t.Errorf("baseline got %d requests and canary %d, want 15 and 5", baselineCalls.Load(), canaryCalls.Load())
+	}
+	for _, row := range []string{`\s+BASELINE\s+CANARY\n`, `requests\s+15\s+5\n`, `success\s+100\.0%\s+0\.0%\n`, `p95\s+[0-9.]+ms\s+[0-9.]+ms\n`} {
+		if !regexp.MustCompile(row).MatchString(stdout) {
+			t.Errorf("comparison has no row matching %s:\n%s", row, stdout)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+// certExpiry returns when the leaf certificate of the connection expires and the days left until then
+func certExpiry(state *tls.ConnectionState, now time.Time) (time.Time, int, bool) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return time.Time{}, 0, false
+	}
+	notAfter := state.PeerCertificates[0].NotAfter
+	return notAfter, int(notAfter.Sub(now).Hours() / 24), true
+}
+
+// checkCertExpiry warns when the server certificate expires within CertExpiryWarn, once per host
+// so a batch against the same server prints it once. -fail-on-warnings turns the warning into a failure.
+func (r *Runner) checkCertExpiry(result *Result) {
+	notAfter, days, ok := certExpiry(result.Response.TLS, time.Now())
+	if !ok || time.Until(notAfter) > r.CertExpiryWarn {
+		return
+	}
+
+	host := result.Response.Request.URL.Host
+	if _, warned := r.certWarned.LoadOrStore(host, true); warned {
+		return
+	}
+	if days < 0 {
+		printWarning(fmt.Sprintf("certificate of %s expired %d days ago, on %s", host, -days, notAfter.Format(time.DateOnly)))
+		return
+	}
+	printWarning(fmt.Sprintf("certificate of %s expires in %d days, on %s, within -cert-expiry-warn %s", host, days, notAfter.Format(time.DateOnly), r.CertExpiryWarn))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertExpiryWarnFiresForNearExpiryCert(t *testing.T) {
+	// An hour past ten days keeps the days left at 10 while the test runs
+	notAfter := time.Now().Add(10*24*time.Hour + time.Hour)
+	srv := newTLSServerWithCert(t, newSelfSignedCert(t, notAfter), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	dir := t.TempDir()
+	warning := "expires in 10 days, on " + notAfter.Format(time.DateOnly) + ", within -cert-expiry-warn 720h0m0s"
+	_, stderr, code := runMain(t, dir, "-url", srv.URL, "-insecure", "-cert-expiry-warn", "720h", "-output", "warn")
+	if code != 0 || !strings.Contains(stderr, warning) {
+		t.Errorf("within the window: exit code %d, want 0 with the expiry warning: %s", code, stderr)
+	}
+	if report := readReport(t, filepath.Join(dir, "warn|*.txt")); !strings.Contains(report, "(10 days)") {
+		t.Errorf("report does not record the days left:\n%s", report)
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL, "-insecure", "-cert-expiry-warn", "720h", "-fail-on-warnings", "-output", "fail")
+	if code != 1 || !strings.Contains(stderr, warning) {
+		t.Errorf("with -fail-on-warnings: exit code %d, want 1 with the expiry warning: %s", code, stderr)
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL, "-insecure", "-cert-expiry-warn", "72h", "-fail-on-warnings", "-output", "outside")
+	if code != 0 || strings.Contains(stderr, "expires in") {
+		t.Errorf("outside the window: exit code %d, want 0 without a warning: %s", code, stderr)
+	}
+}
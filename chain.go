@@ -0,0 +1,87 @@
+package main
+
+import "fmt"
+
+// chain decides which request of a batch runs next from the @on-success and @on-failure directives.
+// Requests named as a branch target only run when a branch selects them, sequential flow skips them.
+type chain struct {
+	requests []RequestData
+	byName   map[string]int
+	targets  map[int]bool
+	ran      map[int]bool
+}
+
+// newChain indexes the named requests and checks that every branch target exists
+func newChain(requests []RequestData) (*chain, error) {
+	c := &chain{
+		requests: requests,
+		byName:   make(map[string]int),
+		targets:  make(map[int]bool),
+		ran:      make(map[int]bool),
+	}
+
+	for i, reqData := range requests {
+		if reqData.Name == "" {
+			continue
+		}
+		if _, ok := c.byName[reqData.Name]; ok {
+			return nil, fmt.Errorf("duplicate request name %q", reqData.Name)
+		}
+		c.byName[reqData.Name] = i
+	}
+
+	for _, reqData := range requests {
+		for _, target := range []string{reqData.OnSuccess, reqData.OnFailure} {
+			if target == "" {
+				continue
+			}
+			i, ok := c.byName[target]
+			if !ok {
+				return nil, fmt.Errorf("unknown request %q in branch of %s %s", target, reqData.Method, reqData.URL)
+			}
+			c.targets[i] = true
+		}
+	}
+
+	return c, nil
+}
+
+// hasBranches reports whether any request uses @on-success or @on-failure
+func (c *chain) hasBranches() bool {
+	return len(c.targets) > 0
+}
+
+// first returns the index of the first request to run, or -1 when there is none
+func (c *chain) first() int {
+	return c.sequential(0)
+}
+
+// next returns the index of the request to run after request i, or -1 when the batch is done
+func (c *chain) next(i int, passed bool) (int, error) {
+	c.ran[i] = true
+
+	target := c.requests[i].OnFailure
+	if passed {
+		target = c.requests[i].OnSuccess
+	}
+	if target == "" {
+		return c.sequential(i + 1), nil
+	}
+
+	// Each request runs at most once so a branch cannot loop forever
+	j := c.byName[target]
+	if c.ran[j] {
+		return -1, fmt.Errorf("request %q already ran, branches cannot loop", target)
+	}
+	return j, nil
+}
+
+// sequential returns the first request from i on that is not a branch target, or -1
+func (c *chain) sequential(i int) int {
+	for ; i < len(c.requests); i++ {
+		if !c.targets[i] {
+			return i
+		}
+	}
+	return -1
+}
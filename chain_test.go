@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChainFollowsBranches(t *testing.T) {
+	for _, tt := range []struct {
+		loginStatus int
+		want        []string
+	}{
+		{http.StatusOK, []string{"/login", "/dashboard", "/done"}},
+		{http.StatusInternalServerError, []string{"/login", "/alert", "/done"}},
+	} {
+		t.Run(fmt.Sprint(tt.loginStatus), func(t *testing.T) {
+			var paths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				if r.URL.Path == "/login" {
+					w.WriteHeader(tt.loginStatus)
+				}
+			}))
+			defer srv.Close()
+
+			dir := t.TempDir()
+			source := writeFile(t, dir, "flow.http", fmt.Sprintf(`# @on-success next=dashboard
+# @on-failure next=alert
+POST %[1]s/login
+
+###
+# @name dashboard
+# @on-success next=done
+GET %[1]s/dashboard
+
+###
+# @name alert
+# @on-success next=done
+GET %[1]s/alert
+
+###
+# @name done
+GET %[1]s/done
+`, srv.URL))
+			runMain(t, dir, "-source", source, "-output", "out")
+
+			if fmt.Sprint(paths) != fmt.Sprint(tt.want) {
+				t.Errorf("requests sent = %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainRejectsUnknownTarget(t *testing.T) {
+	_, err := newChain([]RequestData{{Method: "GET", URL: "http://a", OnSuccess: "missing"}})
+	if err == nil {
+		t.Error("branch to an unknown request was accepted")
+	}
+}
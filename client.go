@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ClientOptions holds the settings used to build the HTTP client
+type ClientOptions struct {
+	// Resolve maps a "host:port" pair to the "addr:port" that should be dialed instead
+	Resolve map[string]string
+	// ConnectTo maps a "host:port" pair to another "host:port" to connect to, which is resolved as usual
+	ConnectTo map[string]string
+	// DNSServer is an "ip:port" resolver that host names are looked up with instead of the system one
+	DNSServer string
+	// SOCKS5 is a "[user:password@]host:port" SOCKS5 proxy that connections are tunneled through
+	SOCKS5 string
+	// UnixSocket dials this socket path for every request, the URL still sets the Host and path
+	UnixSocket string
+	// DisableCompression stops the transport from requesting gzip and decompressing responses
+	DisableCompression bool
+
+	// DisableKeepAlives opens a new connection for every request, so
+	// connection reuse across retries and batch requests is turned off
+	DisableKeepAlives bool
+
+	// MaxIdleConns, MaxIdleConnsPerHost and IdleConnTimeout tune the connection pool of the shared transport
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// Insecure skips certificate verification entirely
+	Insecure bool
+	// CABundle is a PEM file of CA certificates to trust, added to the system roots
+	// unless CABundleOnly is set
+	CABundle     string
+	CABundleOnly bool
+
+	// ServerName overrides the TLS SNI and the name the certificate is verified against
+	ServerName string
+	// MinTLSVersion is the lowest TLS version accepted: 1.0, 1.1, 1.2 or 1.3, Go's default when empty
+	MinTLSVersion string
+	// AllowInsecureCiphers offers the legacy cipher suites Go leaves out by default and accepts TLS 1.0
+	AllowInsecureCiphers bool
+	// VerifyHostname is the name the certificate is verified against, without changing the SNI
+	VerifyHostname string
+
+	// HTTP2 forces HTTP/2 over TLS, negotiated through ALPN
+	HTTP2 bool
+	// H2C sends HTTP/2 over cleartext using prior knowledge instead of TLS
+	H2C bool
+
+	// HTTP10 sends requests with an HTTP/1.0 request line and no keep-alive
+	HTTP10 bool
+
+	// NoRedirects returns redirect responses as they are instead of following them
+	NoRedirects bool
+	// Cookies keeps the cookies responses set in a jar shared by every request and sends them back
+	Cookies bool
+
+	// Timeout bounds the whole request, including reading the body
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	// ExpectContinueTimeout is how long a request with Expect: 100-continue waits before sending its body anyway
+	ExpectContinueTimeout time.Duration
+}
+
+// NewClient builds an http.Client configured from ClientOptions
+func NewClient(opts ClientOptions) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if opts.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(opts.DNSServer); err != nil {
+			return nil, fmt.Errorf("invalid -dns-server %q: %w", opts.DNSServer, err)
+		}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, opts.DNSServer)
+			},
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.DisableCompression = opts.DisableCompression
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	transport.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	transport.ExpectContinueTimeout = opts.ExpectContinueTimeout
+	if opts.HTTP2 || opts.H2C {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP2(opts.HTTP2)
+		protocols.SetUnencryptedHTTP2(opts.H2C)
+		transport.Protocols = protocols
+	}
+
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error) = dialer.DialContext
+	if opts.SOCKS5 != "" {
+		socks, err := newSOCKS5Dialer(opts.SOCKS5, dialer)
+		if err != nil {
+			return nil, err
+		}
+		dial = socks.DialContext
+	}
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if opts.UnixSocket != "" {
+			return dialer.DialContext(ctx, "unix", opts.UnixSocket)
+		}
+
+		// Only the dialed address changes, the URL host is still used for SNI and the Host header
+		if target, ok := opts.ConnectTo[addr]; ok {
+			addr = target
+		}
+		if override, ok := opts.Resolve[addr]; ok {
+			addr = override
+		}
+		return dial(ctx, network, addr)
+	}
+
+	client := &http.Client{Transport: transport, Timeout: opts.Timeout}
+	if opts.HTTP10 {
+		client.Transport = &http10Transport{base: transport}
+	}
+	if opts.Cookies {
+		// Without a public suffix list cookies are only shared between hosts with the same name
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		client.Jar = jar
+	}
+	if opts.NoRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return client, nil
+}
+
+// newSOCKS5Dialer returns a dialer that tunnels through the "[user:password@]host:port" SOCKS5 proxy
+func newSOCKS5Dialer(address string, forward *net.Dialer) (proxy.ContextDialer, error) {
+	var auth *proxy.Auth
+	if credentials, host, ok := strings.Cut(address, "@"); ok {
+		user, password, _ := strings.Cut(credentials, ":")
+		auth = &proxy.Auth{User: user, Password: password}
+		address = host
+	}
+
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("invalid -socks5 %q: %w", address, err)
+	}
+
+	socks, err := proxy.SOCKS5("tcp", address, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+	return socks.(proxy.ContextDialer), nil
+}
+
+// tlsVersions maps the -min-tls-version values to their TLS versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig builds the client TLS configuration from ClientOptions
+func newTLSConfig(opts ClientOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.Insecure,
+		// New connections resume earlier TLS sessions, reports show whether they did
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+
+	if opts.AllowInsecureCiphers {
+		config.MinVersion = tls.VersionTLS10
+		for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+			config.CipherSuites = append(config.CipherSuites, suite.ID)
+		}
+	}
+
+	if opts.MinTLSVersion != "" {
+		version, ok := tlsVersions[opts.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid -min-tls-version %q, expected 1.0, 1.1, 1.2 or 1.3", opts.MinTLSVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if opts.CABundle != "" {
+		pool, err := loadCertPool(opts.CABundle, !opts.CABundleOnly)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	} else if opts.CABundleOnly {
+		return nil, fmt.Errorf("-ca-bundle-only requires -ca-bundle")
+	}
+
+	// The default verification is replaced by one that checks the chain as usual
+	// but matches the certificate against VerifyHostname
+	if opts.VerifyHostname != "" && !opts.Insecure {
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("server sent no certificate")
+			}
+			verify := x509.VerifyOptions{
+				DNSName:       opts.VerifyHostname,
+				Roots:         config.RootCAs,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range state.PeerCertificates[1:] {
+				verify.Intermediates.AddCert(cert)
+			}
+			_, err := state.PeerCertificates[0].Verify(verify)
+			return err
+		}
+	}
+
+	return config, nil
+}
+
+// loadCertPool reads PEM certificates into a pool, starting from the system roots when withSystem is set
+func loadCertPool(path string, withSystem bool) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if withSystem {
+		if system, err := x509.SystemCertPool(); err == nil {
+			pool = system
+		}
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+	return pool, nil
+}
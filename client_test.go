@@ -0,0 +1,764 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// writeServerCA writes the certificate of a TLS test server to a PEM file for -ca-bundle
+func writeServerCA(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveKeepsSNIAndHost(t *testing.T) {
+	var gotHost, gotSNI string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotSNI = r.Host, r.TLS.ServerName
+	}))
+	defer srv.Close()
+
+	port := srv.Listener.Addr().(*net.TCPAddr).Port
+	resolve := resolveFlag{}
+	if err := resolve.Set(fmt.Sprintf("example.com:%d:127.0.0.1", port)); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(ClientOptions{Resolve: resolve, CABundle: writeServerCA(t, srv), CABundleOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := fmt.Sprintf("https://example.com:%d/", port)
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	u, _ := url.Parse(target)
+	if gotHost != u.Host {
+		t.Errorf("Host = %q, want %q", gotHost, u.Host)
+	}
+	if gotSNI != "example.com" {
+		t.Errorf("SNI = %q, want example.com", gotSNI)
+	}
+}
+
+func TestDisableCompressionKeepsRawBody(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("hello"))
+	zw.Close()
+
+	var acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{DisableCompression: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(context.Background(), client, NewURLRequest(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if acceptEncoding != "" {
+		t.Errorf("Accept-Encoding = %q, want none", acceptEncoding)
+	}
+	if !bytes.Equal(result.Body, compressed.Bytes()) {
+		t.Errorf("body = %x, want the gzip bytes %x", result.Body, compressed.Bytes())
+	}
+}
+
+// unroutableAddr is in a private range nothing answers on, so dialing it hangs until the dial timeout
+const unroutableAddr = "10.255.255.1:80"
+
+func TestDialTimeoutToUnroutableAddress(t *testing.T) {
+	if conn, err := net.DialTimeout("tcp", unroutableAddr, 200*time.Millisecond); err == nil {
+		conn.Close()
+		t.Skip("this network accepts connections to", unroutableAddr)
+	}
+
+	client, err := NewClient(ClientOptions{DialTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = Execute(context.Background(), client, NewURLRequest("http://"+unroutableAddr+"/"))
+	if !isTimeout(err) {
+		t.Fatalf("error = %v, want a dial timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("dial gave up after %s, want about 100ms", elapsed)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client, err := NewClient(ClientOptions{ResponseHeaderTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Execute(context.Background(), client, NewURLRequest(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Errorf("error = %v, want a response header timeout", err)
+	}
+}
+
+func TestHTTP2NegotiatedThroughALPN(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{HTTP2: true, CABundle: writeServerCA(t, srv), CABundleOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(context.Background(), client, NewURLRequest(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Response.ProtoMajor != 2 {
+		t.Errorf("protocol = %s, want HTTP/2", result.Response.Proto)
+	}
+	if got := negotiatedProtocol(result.Response); got != "h2 (negotiated via ALPN)" {
+		t.Errorf("ALPN = %q, want h2 (negotiated via ALPN)", got)
+	}
+}
+
+func TestDisableKeepAlivesOpensConnectionPerRequest(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{DisableKeepAlives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		result, err := Execute(context.Background(), client, NewURLRequest(srv.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ConnReused {
+			t.Error("response came over a reused connection")
+		}
+	}
+
+	if n := conns.Load(); n != 3 {
+		t.Errorf("server saw %d connections for 3 requests, want 3", n)
+	}
+}
+
+func TestServerNameOverridesSNI(t *testing.T) {
+	var gotSNI string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSNI = r.TLS.ServerName
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{ServerName: "example.com", CABundle: writeServerCA(t, srv), CABundleOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Execute(context.Background(), client, NewURLRequest(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if gotSNI != "example.com" {
+		t.Errorf("SNI = %q, want example.com instead of the URL host", gotSNI)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotHost, gotPath string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotPath = r.Host, r.URL.Path
+		w.Write([]byte("over the socket"))
+	}))
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.Start()
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{UnixSocket: socket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(context.Background(), client, NewURLRequest("http://api.local/v1/status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result.Body) != "over the socket" {
+		t.Errorf("body = %q, want over the socket", result.Body)
+	}
+	if gotHost != "api.local" || gotPath != "/v1/status" {
+		t.Errorf("server got Host %q and path %q, want the URL host and path", gotHost, gotPath)
+	}
+}
+
+// newSelfSignedCert creates a self-signed certificate for 127.0.0.1 and example.com that expires at notAfter
+func newSelfSignedCert(t *testing.T, notAfter time.Time) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"example.com"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// newTLSServerWithCert starts a TLS test server presenting cert
+func newTLSServerWithCert(t *testing.T, cert tls.Certificate, handler http.Handler) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCABundleOnlyTrustsItsCertificates(t *testing.T) {
+	trusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer trusted.Close()
+	untrusted := newTLSServerWithCert(t, newSelfSignedCert(t, time.Now().Add(time.Hour)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	client, err := NewClient(ClientOptions{CABundle: writeServerCA(t, trusted), CABundleOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Execute(context.Background(), client, NewURLRequest(trusted.URL)); err != nil {
+		t.Errorf("server in the bundle: %v", err)
+	}
+	_, err = Execute(context.Background(), client, NewURLRequest(untrusted.URL))
+	var unknown x509.UnknownAuthorityError
+	if !errors.As(err, &unknown) {
+		t.Errorf("server outside the bundle: error = %v, want an unknown authority", err)
+	}
+
+	insecure, err := NewClient(ClientOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Execute(context.Background(), insecure, NewURLRequest(untrusted.URL)); err != nil {
+		t.Errorf("-insecure: %v", err)
+	}
+}
+
+func TestTransportPoolSettings(t *testing.T) {
+	client, err := NewClient(ClientOptions{MaxIdleConns: 7, MaxIdleConnsPerHost: 3, IdleConnTimeout: 42 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.MaxIdleConns != 7 || transport.MaxIdleConnsPerHost != 3 || transport.IdleConnTimeout != 42*time.Second {
+		t.Errorf("pool = %d idle, %d per host, %s timeout, want 7, 3 and 42s", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+}
+
+// startMockDNS serves A records from answers over UDP and counts the queries it gets
+func startMockDNS(t *testing.T, answers map[string]net.IP) (string, *atomic.Int64) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	queries := new(atomic.Int64)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			queries.Add(1)
+
+			question := query.Questions[0]
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			if ip, ok := answers[question.Name.String()]; ok && question.Type == dnsmessage.TypeA {
+				reply.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte(ip.To4())},
+				}}
+			} else if !ok {
+				reply.RCode = dnsmessage.RCodeNameError
+			}
+			packed, err := reply.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), queries
+}
+
+func TestDNSServerResolvesThroughMock(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer srv.Close()
+	dnsServer, queries := startMockDNS(t, map[string]net.IP{"api.internal.test.": net.IPv4(127, 0, 0, 1)})
+
+	client, err := NewClient(ClientOptions{DNSServer: dnsServer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	result, err := Execute(context.Background(), client, NewURLRequest("http://api.internal.test:"+port+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if queries.Load() == 0 {
+		t.Error("mock DNS server got no queries")
+	}
+	if string(result.Body) != "api.internal.test:"+port {
+		t.Errorf("server saw Host %q, want api.internal.test:%s", result.Body, port)
+	}
+}
+
+// startSOCKS5 runs a SOCKS5 proxy that accepts the user and password and records the addresses it connects to
+func startSOCKS5(t *testing.T, user, password string) (string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				target, err := socks5Handshake(conn, user, password)
+				if err != nil {
+					return
+				}
+				targets <- target
+
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String(), targets
+}
+
+// socks5Handshake negotiates username/password authentication and returns the requested host:port
+func socks5Handshake(conn net.Conn, user, password string) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	if !bytes.Contains(methods, []byte{2}) {
+		conn.Write([]byte{5, 0xff})
+		return "", errors.New("client does not offer username/password authentication")
+	}
+	conn.Write([]byte{5, 2})
+
+	// Username/password subnegotiation: version, ulen, user, plen, password
+	readField := func() (string, error) {
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return "", err
+		}
+		field := make([]byte, n[0])
+		_, err := io.ReadFull(conn, field)
+		return string(field), err
+	}
+	if _, err := io.ReadFull(conn, header[:1]); err != nil {
+		return "", err
+	}
+	gotUser, err := readField()
+	if err != nil {
+		return "", err
+	}
+	gotPassword, err := readField()
+	if err != nil {
+		return "", err
+	}
+	if gotUser != user || gotPassword != password {
+		conn.Write([]byte{1, 1})
+		return "", errors.New("wrong credentials")
+	}
+	conn.Write([]byte{1, 0})
+
+	// Connect request: version, command, reserved, address type, address, port
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		if host, err = readField(); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported address type %d", request[3])
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, fmt.Sprint(int(port[0])<<8|int(port[1]))), nil
+}
+
+func TestSOCKS5ReachesOrigin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("from origin"))
+	}))
+	defer srv.Close()
+	proxyAddr, targets := startSOCKS5(t, "alice", "s3cret")
+
+	client, err := NewClient(ClientOptions{SOCKS5: "alice:s3cret@" + proxyAddr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(context.Background(), client, NewURLRequest(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result.Body) != "from origin" {
+		t.Errorf("body = %q, want the origin's", result.Body)
+	}
+	select {
+	case target := <-targets:
+		if target != srv.Listener.Addr().String() {
+			t.Errorf("proxy connected to %s, want %s", target, srv.Listener.Addr())
+		}
+	default:
+		t.Error("request did not go through the SOCKS5 proxy")
+	}
+
+	client, err = NewClient(ClientOptions{SOCKS5: "alice:wrong@" + proxyAddr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Execute(context.Background(), client, NewURLRequest(srv.URL)); err == nil {
+		t.Error("request with wrong proxy credentials succeeded")
+	}
+}
+
+func TestConnectToKeepsHostAndSNI(t *testing.T) {
+	var gotHost, gotSNI string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotSNI = r.Host, r.TLS.ServerName
+	}))
+	defer srv.Close()
+
+	// The original port is not the server's, only the override target reaches it
+	connectTo := connectToFlag{}
+	if err := connectTo.Set(fmt.Sprintf("example.com:443:localhost:%d", srv.Listener.Addr().(*net.TCPAddr).Port)); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(ClientOptions{ConnectTo: connectTo, CABundle: writeServerCA(t, srv), CABundleOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if gotHost != "example.com" {
+		t.Errorf("Host = %q, want example.com", gotHost)
+	}
+	if gotSNI != "example.com" {
+		t.Errorf("SNI = %q, want example.com", gotSNI)
+	}
+}
+
+func TestTLSSessionResumption(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Without keep-alive the second request needs a new handshake, which resumes the first session
+	client, err := NewClient(ClientOptions{CABundle: writeServerCA(t, srv), CABundleOnly: true, DisableKeepAlives: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &memorySink{}
+	runner := &Runner{Client: client, Retry: 1, Report: ReportOptions{Sink: sink}}
+	for _, output := range []string{"first", "second"} {
+		if outcome := runner.Run(NewURLRequest(srv.URL), output); !outcome.Passed {
+			t.Fatalf("%s request failed: %v", output, outcome.Err)
+		}
+	}
+
+	if report := sink.report(t, "first"); !strings.Contains(report, "TLS Session Resumed: false\n") {
+		t.Errorf("first report does not show a full handshake:\n%s", report)
+	}
+	if report := sink.report(t, "second"); !strings.Contains(report, "TLS Session Resumed: true\n") {
+		t.Errorf("second report does not show the session resumed:\n%s", report)
+	}
+}
+
+func TestVerifyHostnameChecksAnotherName(t *testing.T) {
+	// The certificate is for example.com and 127.0.0.1, the URL host localhost matches neither
+	srv := newTLSServerWithCert(t, newSelfSignedCert(t, time.Now().Add(time.Hour)), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target := "https://localhost:" + fmt.Sprint(srv.Listener.Addr().(*net.TCPAddr).Port) + "/"
+	ca := writeServerCA(t, srv)
+
+	for _, tt := range []struct {
+		verifyHostname string
+		ok             bool
+	}{{"", false}, {"example.com", true}, {"other.example.com", false}} {
+		client, err := NewClient(ClientOptions{CABundle: ca, CABundleOnly: true, VerifyHostname: tt.verifyHostname})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(target)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("-verify-hostname %q: error %v, want success %t", tt.verifyHostname, err, tt.ok)
+		}
+	}
+}
+
+func TestAllowInsecureCiphersReachesLegacyServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA256},
+	}
+	srv.StartTLS()
+	defer srv.Close()
+	ca := writeServerCA(t, srv)
+
+	for _, allow := range []bool{false, true} {
+		client, err := NewClient(ClientOptions{CABundle: ca, CABundleOnly: true, AllowInsecureCiphers: allow})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			if suite := resp.TLS.CipherSuite; suite != tls.TLS_RSA_WITH_AES_128_CBC_SHA256 {
+				t.Errorf("negotiated %s, want the legacy suite", tls.CipherSuiteName(suite))
+			}
+			resp.Body.Close()
+		}
+		if (err == nil) != allow {
+			t.Errorf("-allow-insecure-ciphers %t: error %v, want success only with the flag", allow, err)
+		}
+	}
+}
+
+func TestMinTLSVersionRejectsOlderServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS11, MaxVersion: tls.VersionTLS11}
+	srv.StartTLS()
+	defer srv.Close()
+	ca := writeServerCA(t, srv)
+
+	client, err := NewClient(ClientOptions{CABundle: ca, CABundleOnly: true, MinTLSVersion: "1.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatalf("handshake with a TLS 1.1 server succeeded with -min-tls-version 1.2, negotiated %s", tls.VersionName(resp.TLS.Version))
+	}
+
+	client, err = NewClient(ClientOptions{CABundle: ca, CABundleOnly: true, MinTLSVersion: "1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &memorySink{}
+	runner := &Runner{Client: client, Retry: 1, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request with -min-tls-version 1.1 failed: %v", outcome.Err)
+	}
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "TLS Version: TLS 1.1\n") {
+		t.Errorf("report does not show the negotiated TLS 1.1:\n%s", report)
+	}
+}
+
+func TestExpectContinueWithholdsBodyOn417(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The server rejects the request from its headers and then watches for body bytes. Without
+	// Connection: close net/http would send the body anyway to keep the connection usable.
+	leaked := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var data []byte
+		buf := make([]byte, 4096)
+		for !bytes.Contains(data, []byte("\r\n\r\n")) {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			data = append(data, buf[:n]...)
+		}
+		conn.Write([]byte("HTTP/1.1 417 Expectation Failed\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+
+		_, body, _ := bytes.Cut(data, []byte("\r\n\r\n"))
+		conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+		n, _ := conn.Read(buf)
+		leaked <- string(body) + string(buf[:n])
+	}()
+
+	client, err := NewClient(ClientOptions{ExpectContinueTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqData := NewURLRequest("http://" + ln.Addr().String() + "/upload")
+	reqData.Method = http.MethodPut
+	reqData.Headers["Expect"] = "100-continue"
+	reqData.Body = strings.Repeat("large upload ", 1000)
+	result, err := Execute(context.Background(), client, reqData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Response.StatusCode != http.StatusExpectationFailed || result.Got100Continue {
+		t.Errorf("got %s with 100 Continue %t, want 417 without 100 Continue", result.Response.Status, result.Got100Continue)
+	}
+	if body := <-leaked; body != "" {
+		t.Errorf("server received %d body bytes after rejecting the request", len(body))
+	}
+}
+
+func TestExpectContinueReportsContinue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{ExpectContinueTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &memorySink{}
+	runner := &Runner{Client: client, Retry: 1, Report: ReportOptions{Sink: sink}}
+	reqData := NewURLRequest(srv.URL)
+	reqData.Method = http.MethodPut
+	reqData.Headers["Expect"] = "100-continue"
+	reqData.Body = "upload"
+	if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "100 Continue Received: true\n") {
+		t.Errorf("report does not show the 100 Continue:\n%s", report)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxDiffCells bounds the size of the line diff table to keep large bodies cheap to compare
+const maxDiffCells = 10_000_000
+
+// rebaseURL replaces the scheme and host of rawURL with those of base, keeping the path and query
+func rebaseURL(rawURL, base string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if b.Scheme == "" || b.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q, expected scheme://host", base)
+	}
+
+	u.Scheme, u.Host = b.Scheme, b.Host
+	if p := strings.TrimSuffix(b.Path, "/"); p != "" {
+		u.Path = p + u.Path
+	}
+	return u.String(), nil
+}
+
+// rewriteHost replaces the host of rawURL when a rule matches it, keeping the scheme, path and query.
+// A rule for host:port only matches that port, a rule for a bare host matches every port and keeps it.
+func rewriteHost(rawURL string, rules map[string]string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL, false
+	}
+
+	if to, ok := rules[u.Host]; ok {
+		u.Host = to
+	} else if to, ok := rules[u.Hostname()]; ok {
+		if port := u.Port(); port != "" && !strings.Contains(to, ":") {
+			to += ":" + port
+		}
+		u.Host = to
+	} else {
+		return rawURL, false
+	}
+	return u.String(), true
+}
+
+// replacePath swaps the path, query and fragment of rawURL for path, keeping its scheme and host.
+// It works on the text so URLs with {{placeholders}} in the host are handled too.
+func replacePath(rawURL, path string) string {
+	prefix, rest := "", rawURL
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		prefix, rest = rawURL[:i+3], rawURL[i+3:]
+	}
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	return prefix + rest + path
+}
+
+// diffLines returns a line diff of a and b, prefixing removed lines with "- ", added with "+ " and common with "  "
+func diffLines(a, b []string) []string {
+	if len(a)*len(b) > maxDiffCells {
+		var out []string
+		for _, line := range a {
+			out = append(out, "- "+line)
+		}
+		for _, line := range b {
+			out = append(out, "+ "+line)
+		}
+		return out
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
+
+// GenerateCompareReport writes the status and body differences between the primary and compared responses
+func GenerateCompareReport(sink ReportSink, outputPath string, reqData RequestData, primary, compared *Result, comparedURL string) (bool, error) {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-compare.txt")
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	same := primary.Response.StatusCode == compared.Response.StatusCode && string(primary.Body) == string(compared.Body)
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nPrimary URL: %s\nCompared URL: %s\n\n", reqData.Method, reqData.URL, comparedURL))
+	if err != nil {
+		return same, err
+	}
+
+	statusLine := fmt.Sprintf("Status: %s (same)\n", primary.Response.Status)
+	if primary.Response.StatusCode != compared.Response.StatusCode {
+		statusLine = fmt.Sprintf("Status: %s -> %s (differs)\n", primary.Response.Status, compared.Response.Status)
+	}
+	_, err = io.WriteString(file, statusLine)
+	if err != nil {
+		return same, err
+	}
+
+	if string(primary.Body) == string(compared.Body) {
+		_, err = io.WriteString(file, "Body: same\n")
+		return same, err
+	}
+
+	_, err = io.WriteString(file, "Body: differs\n\nBody Diff (- primary, + compared):\n")
+	if err != nil {
+		return same, err
+	}
+
+	for _, line := range diffLines(strings.Split(string(primary.Body), "\n"), strings.Split(string(compared.Body), "\n")) {
+		_, err = io.WriteString(file, line+"\n")
+		if err != nil {
+			return same, err
+		}
+	}
+
+	return same, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareBaseDiffsTwoServers(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id: 1\nname: old\n" + r.URL.Path))
+	}))
+	defer primary.Close()
+	base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("id: 1\nname: new\n" + r.URL.Path))
+	}))
+	defer base.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: primary.Client(), Retry: 1, CompareBase: base.URL, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(primary.URL+"/users"), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, "-compare.txt")
+	for _, want := range []string{
+		"Compared URL: " + base.URL + "/users",
+		"Status: 200 OK -> 202 Accepted (differs)",
+		"Body: differs",
+		"  id: 1\n- name: old\n+ name: new\n  /users\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("compare report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestPathReplacesPathAndKeepsHost(t *testing.T) {
+	for _, tt := range []struct{ url, path, want string }{
+		{"https://api.example.com/v1/users?page=2#top", "/v2/users", "https://api.example.com/v2/users"},
+		{"http://127.0.0.1:8080", "/health?verbose=1", "http://127.0.0.1:8080/health?verbose=1"},
+		{"https://user:pw@example.com:8443/a/b", "/c", "https://user:pw@example.com:8443/c"},
+	} {
+		if got := replacePath(tt.url, tt.path); got != tt.want {
+			t.Errorf("replacePath(%s, %s) = %s, want %s", tt.url, tt.path, got, tt.want)
+		}
+	}
+
+	var gotURL, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL, gotHeader = r.URL.String(), r.Header.Get("X-From-File")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "template.http", "GET "+srv.URL+"/v1/users?page=2\nX-From-File: yes\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-path", "/v2/users?active=true", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gotURL != "/v2/users?active=true" || gotHeader != "yes" {
+		t.Errorf("server got %s with X-From-File %q, want /v2/users?active=true with the file's header", gotURL, gotHeader)
+	}
+}
+
+func TestRewriteHostSendsToTheOtherHost(t *testing.T) {
+	var gotURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+	}))
+	defer srv.Close()
+
+	// api.prod.invalid does not resolve, so the request only succeeds when it is rewritten
+	_, port, _ := strings.Cut(strings.TrimPrefix(srv.URL, "http://"), ":")
+	dir := t.TempDir()
+	_, stderr, code := runMain(t, dir, "-url", "http://api.prod.invalid:"+port+"/items?page=2", "-rewrite-host", "api.prod.invalid=127.0.0.1", "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gotURL != "/items?page=2" {
+		t.Errorf("rewritten host received %q, want the path and query kept", gotURL)
+	}
+
+	if got, ok := rewriteHost("https://api.prod.invalid:8443/a", map[string]string{"api.prod.invalid:443": "other"}); ok {
+		t.Errorf("a rule for another port rewrote the URL to %s", got)
+	}
+}
+
+func TestIgnoreJSONPathsMatchesBaseline(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1, "timestamp": "2026-10-14T09:00:00Z", "name": "` + r.URL.Query().Get("name") + `"}`))
+	}))
+	defer primary.Close()
+	base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"timestamp": "2026-10-14T09:00:07Z", "id": 1, "name": "alice"}`))
+	}))
+	defer base.Close()
+
+	paths, err := parseJSONPath("$.timestamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compare := func(name string) string {
+		t.Helper()
+		sink := &memorySink{}
+		runner := &Runner{Client: primary.Client(), Retry: 1, CompareBase: base.URL, IgnoreJSONPaths: [][]jsonPathSegment{paths}, Report: ReportOptions{Sink: sink}}
+		runner.Run(NewURLRequest(primary.URL+"/users?name="+name), "out")
+		return sink.report(t, "-compare.txt")
+	}
+
+	if report := compare("alice"); !strings.Contains(report, "Body: same\n") {
+		t.Errorf("bodies differing only in $.timestamp do not match:\n%s", report)
+	}
+	if report := compare("bob"); !strings.Contains(report, "Body: differs\n") || strings.Contains(report, "timestamp") {
+		t.Errorf("bodies differing in name are not diffed without the timestamp:\n%s", report)
+	}
+}
+
+func TestCompareBaseSendsAuthorizedRequest(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"abc123","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("orders"))
+	})
+	primary := httptest.NewServer(api)
+	defer primary.Close()
+	base := httptest.NewServer(api)
+	defer base.Close()
+
+	dir := t.TempDir()
+	_, stderr, code := runMain(t, dir, "-url", primary.URL+"/orders", "-compare-base", base.URL,
+		"-oauth-token-url", tokenSrv.URL, "-oauth-client-id", "id", "-oauth-client-secret", "secret", "-output", "oauth")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if report := readReport(t, filepath.Join(dir, "oauth|*-compare.txt")); !strings.Contains(report, "Status: 200 OK (same)") || !strings.Contains(report, "Body: same") {
+		t.Errorf("compared host did not get the bearer token:\n%s", report)
+	}
+}
+
+func TestCompareBaseSignsSigV4ForRebasedHost(t *testing.T) {
+	const secretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	var baseErr error
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if verifySigV4(r, secretKey) != nil {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer primary.Close()
+	base := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if baseErr = verifySigV4(r, secretKey); baseErr != nil {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer base.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{
+		Client:      primary.Client(),
+		Retry:       1,
+		CompareBase: base.URL,
+		SigV4:       &SigV4Config{AccessKey: "AKIDEXAMPLE", SecretKey: secretKey, Region: "us-east-1", Service: "execute-api"},
+		Report:      ReportOptions{Sink: sink},
+	}
+	if outcome := runner.Run(NewURLRequest(primary.URL+"/documents?a=1"), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if baseErr != nil {
+		t.Fatalf("compared request is not signed for its host: %v", baseErr)
+	}
+	if report := sink.report(t, "-compare.txt"); !strings.Contains(report, "Status: 200 OK (same)") {
+		t.Errorf("compared host rejected the signature:\n%s", report)
+	}
+}
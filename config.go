@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// secretFlags are the flags whose values -print-config writes as ***
+var secretFlags = []string{"oauth-client-secret", "aws-secret-key"}
+
+// printConfig writes the effective value of every flag and whether it is the default or was set,
+// followed by the -env-file variables. Secrets, sensitive headers and proxy passwords are written as ***.
+func printConfig(w io.Writer, env map[string]string) {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	flag.VisitAll(func(f *flag.Flag) {
+		source := "default"
+		if set[f.Name] {
+			source = "flag"
+		}
+
+		// Headers are listed one per line so each value is redacted by its own name
+		if headers, ok := f.Value.(headerFlag); ok && len(headers) > 0 {
+			names := make([]string, 0, len(headers))
+			for name := range headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(w, "%s = %s: %s (%s)\n", f.Name, name, redactHeader(name, headers[name], defaultRedactHeaders), source)
+			}
+			return
+		}
+
+		value := f.Value.String()
+		switch {
+		case value == "":
+		case slices.Contains(secretFlags, f.Name):
+			value = redactedValue
+		case f.Name == "socks5":
+			if credentials, host, ok := strings.Cut(value, "@"); ok {
+				user, _, _ := strings.Cut(credentials, ":")
+				value = user + ":" + redactedValue + "@" + host
+			}
+		}
+		fmt.Fprintf(w, "%s = %s (%s)\n", f.Name, value, source)
+	})
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		source := "env-file"
+		if _, ok := os.LookupEnv(name); ok {
+			source = "environment"
+		}
+		fmt.Fprintf(w, "{{%s}} = %s (%s)\n", name, redactVariable(name, env[name]), source)
+	}
+}
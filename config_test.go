@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrintConfigShowsPrecedence(t *testing.T) {
+	t.Setenv("REGION", "us-east-1")
+
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.env", "HOST=base.example.com\nREGION=eu-west-1\nAPI_TOKEN=t0ps3cret\n")
+	staging := writeFile(t, dir, "staging.env", "HOST=staging.example.com\n")
+	stdout, stderr, code := runMain(t, dir, "-print-config", "-env-file", base, "-env-file", staging,
+		"-retry", "5", "-header", "Authorization: Bearer abc", "-header", "X-Team: payments", "-oauth-client-secret", "hunter2")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	for _, want := range []string{
+		"retry = 5 (flag)\n",
+		"sleep = 0 (default)\n",
+		"header = Authorization: *** (flag)\n",
+		"header = X-Team: payments (flag)\n",
+		"oauth-client-secret = *** (flag)\n",
+		// The later env file wins over the earlier one and the process environment over both
+		"{{HOST}} = staging.example.com (env-file)\n",
+		"{{REGION}} = us-east-1 (environment)\n",
+		"{{API_TOKEN}} = *** (env-file)\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("config is missing %q:\n%s", want, stdout)
+		}
+	}
+	for _, secret := range []string{"Bearer abc", "hunter2", "t0ps3cret"} {
+		if strings.Contains(stdout, secret) {
+			t.Errorf("config leaks %q:\n%s", secret, stdout)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// sentCookieNames returns the names of the cookies in the Cookie headers the transport wrote, in the order they were sent
+func sentCookieNames(sent http.Header) []string {
+	var names []string
+	for _, line := range sent.Values("Cookie") {
+		cookies, err := http.ParseCookie(line)
+		if err != nil {
+			continue
+		}
+		for _, cookie := range cookies {
+			if !slices.Contains(names, cookie.Name) {
+				names = append(names, cookie.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCookiesCarriedThroughLoginFlow(t *testing.T) {
+	var gotSession string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", Path: "/"})
+		case "/profile":
+			if cookie, err := r.Cookie("session"); err == nil {
+				gotSession = cookie.Value
+			}
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "flow.http", "# @output login\nPOST "+srv.URL+"/login\n\n###\n# @output profile\nGET "+srv.URL+"/profile\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-cookies", "-format", "txt,json", "-output", filepath.Join(dir, "out")); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if gotSession != "s3cr3t" {
+		t.Errorf("profile request had session cookie %q, want the one login set", gotSession)
+	}
+	if report := readReport(t, filepath.Join(dir, "login|*.txt")); strings.Contains(report, "Cookies Sent:") {
+		t.Errorf("login report lists cookies sent before any were set:\n%s", report)
+	}
+	if report := readReport(t, filepath.Join(dir, "profile|*.txt")); !strings.Contains(report, "Cookies Sent: session\n") {
+		t.Errorf("profile txt report does not note the session cookie:\n%s", report)
+	}
+	if report := readReport(t, filepath.Join(dir, "profile|*.json")); !strings.Contains(report, `"cookies_sent": [`) || !strings.Contains(report, `"session"`) {
+		t.Errorf("profile json report does not note the session cookie:\n%s", report)
+	}
+}
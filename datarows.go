@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadDataRows reads the rows of a -data-rows file: a CSV file whose first line names the fields,
+// or a .json file holding an array of objects
+func ReadDataRows(path string) ([]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var objects []map[string]any
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return nil, fmt.Errorf("%s: expected a JSON array of objects: %w", path, err)
+		}
+		rows := make([]map[string]string, 0, len(objects))
+		for _, object := range objects {
+			row := make(map[string]string, len(object))
+			for name, value := range object {
+				row[name] = jsonValueString(value)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: missing the header line naming the fields", path)
+	}
+
+	fields := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(fields))
+		for i, field := range fields {
+			row[strings.TrimSpace(field)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// expandDataRows repeats the requests once per row, in file order for every row, with the row fields as variables
+func expandDataRows(requests []RequestData, rows []map[string]string) ([]RequestData, error) {
+	expanded := make([]RequestData, 0, len(requests)*len(rows))
+	for _, row := range rows {
+		for _, reqData := range requests {
+			// Every row would repeat the name, which @on-success, @on-failure and references need to be unique
+			if reqData.Name != "" {
+				return nil, fmt.Errorf("-data-rows cannot be combined with named requests, %s %s is named %q", reqData.Method, reqData.URL, reqData.Name)
+			}
+			reqData.Headers = maps.Clone(reqData.Headers)
+			reqData.Row = row
+			expanded = append(expanded, reqData)
+		}
+	}
+	return expanded, nil
+}
+
+// ParseDataJSON reads the -data-json object into {{.field}} variables. Nested fields are reached
+// with dots, such as {{.user.name}}, and objects and arrays are also available whole as JSON.
+func ParseDataJSON(text string) (map[string]string, error) {
+	var data map[string]any
+	if err := json.Unmarshal([]byte(text), &data); err != nil {
+		return nil, fmt.Errorf("invalid -data-json, expected a JSON object: %w", err)
+	}
+
+	vars := make(map[string]string)
+	var flatten func(prefix string, value any)
+	flatten = func(prefix string, value any) {
+		vars[prefix] = jsonValueString(value)
+		switch v := value.(type) {
+		case map[string]any:
+			for name, child := range v {
+				flatten(prefix+"."+name, child)
+			}
+		case []any:
+			for i, child := range v {
+				flatten(fmt.Sprintf("%s.%d", prefix, i), child)
+			}
+		}
+	}
+	for name, value := range data {
+		flatten("."+name, value)
+	}
+	return vars, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDataRowsSendsRequestPerCSVRow(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, r.URL.Path+" "+string(body))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	rows := writeFile(t, dir, "users.csv", "id,name\n1,alice\n2,\"bob, jr\"\n")
+	source := writeFile(t, dir, "user.http", "PUT "+srv.URL+"/users/{{id}}\nContent-Type: application/json\n\n{\"name\":\"{{name}}\"}\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-data-rows", rows, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	want := []string{`/users/1 {"name":"alice"}`, `/users/2 {"name":"bob, jr"}`}
+	if !slices.Equal(got, want) {
+		t.Errorf("server got %q, want one request per row %q", got, want)
+	}
+	if reports, _ := filepath.Glob(filepath.Join(dir, "out*.txt")); len(reports) != 2 {
+		t.Errorf("got reports %v, want one per row", reports)
+	}
+}
+
+func TestDataJSONSubstitutesIntoBody(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = r.URL.Path + " " + string(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "order.http", "POST "+srv.URL+"/orders/{{.id}}\nContent-Type: application/json\n\n{\"id\": {{.id}}, \"user\": \"{{.user.name}}\", \"tags\": {{.tags}}}\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-data-json", `{"id":5,"user":{"name":"alice"},"tags":["a","b"]}`, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := `/orders/5 {"id": 5, "user": "alice", "tags": ["a","b"]}`; got != want {
+		t.Errorf("server got %q, want %q", got, want)
+	}
+
+	_, stderr, code := runMain(t, dir, "-source", source, "-data-json", `{"id":5`, "-output", "malformed")
+	if code == 0 || !strings.Contains(stderr, "invalid -data-json, expected a JSON object") {
+		t.Errorf("malformed JSON: exit code %d, want a clear -data-json error: %s", code, stderr)
+	}
+}
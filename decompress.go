@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// keepEncodedKey is the context key that makes execute keep br and zstd response bodies as the server sent them
+type keepEncodedKey struct{}
+
+// withKeepEncoded returns a context in which execute does not decompress br and zstd response bodies
+func withKeepEncoded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keepEncodedKey{}, true)
+}
+
+// decodeContentEncoding replaces a br or zstd response body with its decompressed stream, as net/http does for gzip.
+// The Content-Encoding and Content-Length headers are removed since they describe the compressed body.
+func decodeContentEncoding(response *http.Response) error {
+	var decoded io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding"))) {
+	case "br":
+		decoded = readCloser{brotli.NewReader(response.Body), response.Body}
+	case "zstd":
+		decoder, err := zstd.NewReader(response.Body)
+		if err != nil {
+			return err
+		}
+		decoded = readCloser{decoder.IOReadCloser(), response.Body}
+	default:
+		return nil
+	}
+
+	response.Body = decoded
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return nil
+}
+
+// readCloser reads from a decompressor and closes both it and the body underneath
+type readCloser struct {
+	io.Reader
+	body io.Closer
+}
+
+func (r readCloser) Close() error {
+	if closer, ok := r.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	return r.body.Close()
+}
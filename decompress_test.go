@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestBrotliAndZstdBodiesDecompressed(t *testing.T) {
+	// Repeated text compresses well, so it only appears in the report once decoded
+	text := strings.Repeat("decompressed text ", 50)
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		"br": func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+		"zstd": func(w io.Writer) io.WriteCloser {
+			encoder, _ := zstd.NewWriter(w)
+			return encoder
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.TrimPrefix(r.URL.Path, "/")
+		var body bytes.Buffer
+		writer := compress[encoding](&body)
+		writer.Write([]byte(text))
+		writer.Close()
+		w.Header().Set("Content-Encoding", encoding)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(body.Bytes())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for encoding := range compress {
+		if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/"+encoding, "-output", encoding); code != 0 {
+			t.Fatalf("%s: exit code %d: %s", encoding, code, stderr)
+		}
+		if report := readReport(t, filepath.Join(dir, encoding+"|*.txt")); !strings.Contains(report, text) {
+			t.Errorf("%s: report does not show the decompressed body:\n%s", encoding, report)
+		}
+
+		if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/"+encoding, "-no-auto-decompress", "-output", encoding+"-raw"); code != 0 {
+			t.Fatalf("%s raw: exit code %d: %s", encoding, code, stderr)
+		}
+		if report := readReport(t, filepath.Join(dir, encoding+"-raw|*.txt")); strings.Contains(report, text) {
+			t.Errorf("%s: -no-auto-decompress report shows the decompressed body:\n%s", encoding, report)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httputil"
+	"strings"
+)
+
+// dumpRaw renders the request and response of a result as they went over the wire.
+// The response body is the one that was read, so it is only the literal wire bytes with -no-auto-decompress.
+func dumpRaw(reqData RequestData, result *Result, redact []string) (string, error) {
+	req := result.Response.Request.Clone(context.Background())
+	for name, values := range req.Header {
+		for i, v := range values {
+			values[i] = redactHeader(name, v, redact)
+		}
+	}
+	req.Body = io.NopCloser(strings.NewReader(reqData.Body))
+
+	rawRequest, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return "", err
+	}
+
+	response := *result.Response
+	response.Header = redactHeaderValues(result.Response.Header, redact)
+	rawResponse, err := httputil.DumpResponse(&response, false)
+	if err != nil {
+		return "", err
+	}
+
+	return string(rawRequest) + "\n" + string(rawResponse) + string(result.Body), nil
+}
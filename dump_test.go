@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDumpRawExchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc123")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("pong"))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{
+		Sink:    sink,
+		DumpRaw: true,
+		Redact:  redactHeaderNames(""),
+	}}
+	reqData := NewURLRequest(srv.URL + "/ping?x=1")
+	reqData.Method = http.MethodPost
+	reqData.Body = "ping"
+	reqData.Headers["Authorization"] = "Bearer token"
+	if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, ".txt")
+	_, raw, found := strings.Cut(report, "Raw Exchange:\n")
+	if !found {
+		t.Fatalf("report has no raw exchange:\n%s", report)
+	}
+	for _, want := range []string{"POST /ping?x=1 HTTP/1.1\r\n", "Authorization: ***\r\n", "\r\n\r\nping", "HTTP/1.1 200 OK\r\n", "X-Request-Id: abc123\r\n", "Set-Cookie: ***\r\n", "\r\n\r\npong"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("raw exchange is missing %q:\n%s", want, raw)
+		}
+	}
+	if strings.Contains(raw, "token") || strings.Contains(raw, "secret") {
+		t.Errorf("raw exchange leaks a redacted header:\n%s", raw)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// reportEncodings transcode UTF-8 report text into each -report-encoding, written as ? where a character has no mapping
+var reportEncodings = map[string]func([]byte) []byte{
+	"utf-8":  nil,
+	"latin1": func(b []byte) []byte { return encodeSingleByte(b, 0xff) },
+	"ascii":  func(b []byte) []byte { return encodeSingleByte(b, 0x7f) },
+}
+
+// reportEncoding returns the transcoder for the named encoding, nil for UTF-8
+func reportEncoding(name string) (func([]byte) []byte, error) {
+	switch name = strings.ToLower(name); name {
+	case "", "utf8":
+		name = "utf-8"
+	case "iso-8859-1", "latin-1":
+		name = "latin1"
+	case "us-ascii":
+		name = "ascii"
+	}
+
+	encode, ok := reportEncodings[name]
+	if !ok {
+		names := make([]string, 0, len(reportEncodings))
+		for name := range reportEncodings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown report encoding %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return encode, nil
+}
+
+// encodeSingleByte maps each character of UTF-8 text to the byte of the same code point, up to max
+func encodeSingleByte(b []byte, max rune) []byte {
+	out := make([]byte, 0, len(b))
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		if r == utf8.RuneError || r > max {
+			out = append(out, '?')
+			continue
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+// charsetDecoders decode response bodies in each Content-Type charset to UTF-8
+var charsetDecoders = map[string]func([]byte) []byte{
+	"iso-8859-1":   decodeLatin1,
+	"latin1":       decodeLatin1,
+	"windows-1252": decodeWindows1252,
+	"cp1252":       decodeWindows1252,
+}
+
+// decodeCharset returns body decoded to UTF-8 from the charset of contentType.
+// UTF-8, ASCII and charsets without a decoder are returned as they are.
+func decodeCharset(body []byte, contentType string) []byte {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body
+	}
+	decode, ok := charsetDecoders[strings.ToLower(params["charset"])]
+	if !ok {
+		return body
+	}
+	return decode(body)
+}
+
+// decodeLatin1 maps every byte to the character of the same code point
+func decodeLatin1(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return out
+}
+
+// windows1252 holds the characters of 0x80 to 0x9f, where Windows-1252 differs from Latin-1
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// decodeWindows1252 is decodeLatin1 with the printable characters Windows-1252 puts in 0x80 to 0x9f
+func decodeWindows1252(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		r := rune(c)
+		if c >= 0x80 && c <= 0x9f {
+			r = windows1252[c-0x80]
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out
+}
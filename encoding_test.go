@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportEncodingTranscodesBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("café ✓"))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct{ encoding, want string }{
+		{"utf-8", "café ✓"},
+		{"latin1", "caf\xe9 ?"},
+		{"ascii", "caf? ?"},
+	} {
+		sink := &memorySink{}
+		runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, Encoding: tt.encoding}}
+		if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+			t.Fatalf("%s: request failed: %v", tt.encoding, outcome.Err)
+		}
+		if report := sink.report(t, ".txt"); !strings.Contains(report, "Response Body:\n"+tt.want+"\n") {
+			t.Errorf("%s report body is not %q:\n%q", tt.encoding, tt.want, report)
+		}
+	}
+
+	if _, err := reportEncoding("ebcdic"); err == nil {
+		t.Error("unknown encoding was accepted")
+	}
+}
+
+func TestResponseCharsetDecodedToUTF8(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+		case "/windows1252":
+			w.Header().Set("Content-Type", "text/plain; charset=windows-1252")
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=x-unknown")
+		}
+		// "Café – Zoë" with Latin-1 é and ë and the Windows-1252 en dash 0x96
+		w.Write([]byte("Caf\xe9 \x96 Zo\xeb"))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct{ path, want string }{
+		{"/latin1", "Café \u0096 Zoë"},
+		{"/windows1252", "Café – Zoë"},
+		{"/unknown", "Caf\xe9 \x96 Zo\xeb"},
+	} {
+		sink := &memorySink{}
+		runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink}}
+		if outcome := runner.Run(NewURLRequest(srv.URL+tt.path), "out"); !outcome.Passed {
+			t.Fatalf("%s: request failed: %v", tt.path, outcome.Err)
+		}
+		if report := sink.report(t, ".txt"); !strings.Contains(report, "Response Body:\n"+tt.want+"\n") {
+			t.Errorf("%s report body is not %q:\n%q", tt.path, tt.want, report)
+		}
+	}
+}
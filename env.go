@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultEnvFile is the dotenv file loaded from the working directory when -env-file is not given
+const defaultEnvFile = ".env"
+
+// ReadEnvFile parses a dotenv file of NAME=value lines. Lines may start with export, values may be
+// single quoted, taken literally, or double quoted with escapes such as \n, and unquoted values end at " #".
+func ReadEnvFile(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: invalid variable, expected NAME=value", filePath, lineNumber)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value for %s: %w", filePath, lineNumber, name, err)
+			}
+			value = unquoted
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		env[name] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// loadEnv reads the variables of the -env-files in order, a later file overriding the variables of earlier ones,
+// or of a .env file in the working directory when none is given.
+// A variable that is also set in the process environment takes the process value. Only the variables
+// the files define are looked up, the rest of the process environment is not available to {{NAME}}
+// placeholders, so an env file is the list of what a request file may reference.
+func loadEnv(filePaths []string) (map[string]string, error) {
+	if len(filePaths) == 0 {
+		env, err := ReadEnvFile(defaultEnvFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return overrideFromProcess(env), nil
+	}
+
+	env := make(map[string]string)
+	for _, path := range filePaths {
+		vars, err := ReadEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(env, vars)
+	}
+	return overrideFromProcess(env), nil
+}
+
+// overrideFromProcess replaces the variables that are also set in the process environment with the process value
+func overrideFromProcess(env map[string]string) map[string]string {
+	for name := range env {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDotenvAutoDetectedWithProcessOverride(t *testing.T) {
+	var gotPath, gotToken, gotRegion string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotToken, gotRegion = r.URL.Path, r.Header.Get("X-Token"), r.Header.Get("X-Region")
+	}))
+	defer srv.Close()
+
+	// The child inherits the process environment, API_TOKEN overrides the .env value and
+	// PROCESS_ONLY is not in the file so it is not a variable
+	t.Setenv("API_TOKEN", "from-process")
+	t.Setenv("PROCESS_ONLY", "unused")
+
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "export BASE_URL="+srv.URL+"\nAPI_TOKEN='from-file'\nREGION=eu-west-1 # comment\n")
+	source := writeFile(t, dir, "users.http", "GET {{BASE_URL}}/users/{{PROCESS_ONLY}}\nX-Token: {{API_TOKEN}}\nX-Region: {{REGION}}\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if gotToken != "from-process" {
+		t.Errorf("server got X-Token %q, want the process environment to override .env", gotToken)
+	}
+	if gotRegion != "eu-west-1" {
+		t.Errorf("server got X-Region %q, want eu-west-1 from .env", gotRegion)
+	}
+	if gotPath != "/users/{{PROCESS_ONLY}}" {
+		t.Errorf("server got path %q, want the placeholder left alone since .env does not define it", gotPath)
+	}
+}
+
+func TestEnvFileFlagReplacesDotenv(t *testing.T) {
+	var gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get("X-Token")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "API_TOKEN=dotenv\n")
+	staging := writeFile(t, dir, "staging.env", "API_TOKEN=staging\n")
+	source := writeFile(t, dir, "users.http", "GET "+srv.URL+"\nX-Token: {{API_TOKEN}}\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-env-file", staging, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gotToken != "staging" {
+		t.Errorf("server got X-Token %q, want staging from -env-file instead of .env", gotToken)
+	}
+}
+
+func TestEnvFilesLayerInOrder(t *testing.T) {
+	var gotHost, gotToken, gotRegion string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotToken, gotRegion = r.Header.Get("X-Api-Host"), r.Header.Get("X-Token"), r.Header.Get("X-Region")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.env", "API_HOST=api.example.com\nAPI_TOKEN=base\nREGION=us-east-1\n")
+	staging := writeFile(t, dir, "staging.env", "API_TOKEN=staging\nREGION=eu-west-1\n")
+	local := writeFile(t, dir, "local.env", "API_TOKEN=local\n")
+	source := writeFile(t, dir, "users.http", "GET "+srv.URL+"\nX-Api-Host: {{API_HOST}}\nX-Token: {{API_TOKEN}}\nX-Region: {{REGION}}\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-env-file", base, "-env-file", staging, "-env-file", local, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gotToken != "local" || gotRegion != "eu-west-1" || gotHost != "api.example.com" {
+		t.Errorf("server got token %q, region %q and host %q, want local, eu-west-1 and api.example.com from the last file defining each", gotToken, gotRegion, gotHost)
+	}
+}
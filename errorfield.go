@@ -0,0 +1,40 @@
+package main
+
+import "encoding/json"
+
+// defaultErrorFields are tried in order when -error-field is not set
+var defaultErrorFields = []string{"$.message", "$.error"}
+
+// extractErrorMessage returns the first of the JSONPath fields found in a 4xx or 5xx JSON response body
+func extractErrorMessage(result *Result, fields []string) string {
+	if result.Response.StatusCode < 400 {
+		return ""
+	}
+
+	var doc any
+	if err := json.Unmarshal(result.Body, &doc); err != nil {
+		return ""
+	}
+
+	for _, field := range fields {
+		value, ok, err := lookupJSONPath(doc, field)
+		if err == nil && ok && value != nil {
+			return jsonValueString(value)
+		}
+	}
+	return ""
+}
+
+// extractValue looks the -extract JSON Pointer up in the response body, message says why when there is no value
+func extractValue(result *Result, pointer string) (value string, message string) {
+	var doc any
+	if err := json.Unmarshal(result.Body, &doc); err != nil {
+		return "", "body is not JSON"
+	}
+
+	v, ok := lookupJSONPointer(doc, pointer)
+	if !ok {
+		return "", "not found"
+	}
+	return jsonValueString(v), ""
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptrace"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is a single timestamped request lifecycle event written as one JSON line
+type Event struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	Attempt   int       `json:"attempt"`
+	Status    int       `json:"status,omitempty"`
+	LatencyMS float64   `json:"latency_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// EventLog writes request lifecycle events as NDJSON
+type EventLog struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewEventLog creates the events log file
+func NewEventLog(path string) (*EventLog, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLog{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Emit writes an event, stamping it with the current time
+func (l *EventLog) Emit(event Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event.Time = time.Now()
+	if err := l.enc.Encode(event); err != nil {
+		printError("events log:", err)
+	}
+}
+
+// Trace returns a context that emits a first-byte event when the response starts arriving
+func (l *EventLog) Trace(ctx context.Context, reqData RequestData, attempt int) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			l.Emit(Event{Event: "first-byte", Method: reqData.Method, URL: reqData.URL, Attempt: attempt})
+		},
+	})
+}
+
+// EmitEnd writes the request-end event for a finished attempt
+func (l *EventLog) EmitEnd(reqData RequestData, attempt Attempt) {
+	event := Event{
+		Event:     "request-end",
+		Method:    reqData.Method,
+		URL:       reqData.URL,
+		Attempt:   attempt.Number,
+		Status:    attempt.StatusCode,
+		LatencyMS: float64(attempt.Latency.Microseconds()) / 1000,
+	}
+	if attempt.Err != nil {
+		event.Error = attempt.Err.Error()
+	}
+	l.Emit(event)
+}
+
+// Close closes the events log file
+func (l *EventLog) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEventsLogOrder(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	events, err := NewEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &Runner{Client: srv.Client(), Retry: 2, Events: events, Report: ReportOptions{Sink: DiscardSink{}}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	events.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var got []string
+	var last Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid event line %q: %v", scanner.Text(), err)
+		}
+		if event.Time.Before(last.Time) {
+			t.Errorf("event %s is stamped before the one before it", event.Event)
+		}
+		last = event
+		got = append(got, fmt.Sprintf("%s#%d:%d", event.Event, event.Attempt, event.Status))
+	}
+
+	want := []string{"request-start#1:0", "first-byte#1:0", "request-end#1:500", "request-start#2:0", "first-byte#2:0", "request-end#2:200"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprEnv holds the values of a response that -assert-expr expressions can refer to
+type exprEnv struct {
+	Status  float64
+	Latency float64
+	Headers http.Header
+	Body    any
+	Text    string
+}
+
+// exprHeaders is the headers variable, indexing it joins every value of the header, null when it is missing
+type exprHeaders http.Header
+
+// exprNode is a compiled part of an expression
+type exprNode func(env exprEnv) (any, error)
+
+// assertExpr fails a response the -assert-expr expression does not evaluate to true for
+func assertExpr(source string) (Assertion, error) {
+	node, err := parseExpr(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(result *Result) error {
+		env := exprEnv{
+			Status:  float64(result.Response.StatusCode),
+			Latency: float64(result.Latency.Milliseconds()),
+			Headers: result.Response.Header,
+			Text:    string(result.Body),
+		}
+		// A body that is not JSON is null, text still holds it
+		json.Unmarshal(result.Body, &env.Body)
+
+		value, err := node(env)
+		if err != nil {
+			return fmt.Errorf("-assert-expr %q: %w", source, err)
+		}
+		if value != true {
+			return fmt.Errorf("-assert-expr %q is %s", source, compactJSON(value))
+		}
+		return nil
+	}, nil
+}
+
+// parseExpr compiles an expression in the CEL like language of -assert-expr: comparisons, &&, ||, !,
+// the variables status, latency, headers, body and text, and the string methods contains, startsWith,
+// endsWith, matches and param
+func parseExpr(source string) (exprNode, error) {
+	tokens, err := tokenizeExpr(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression %q, unexpected %q", source, p.tokens[p.pos].text)
+	}
+	return func(env exprEnv) (any, error) {
+		value, err := node(env)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("expression is %s, not a boolean", compactJSON(value))
+		}
+		return value, nil
+	}, nil
+}
+
+// exprToken is a lexed token, kind is "number", "string", "ident" or "op"
+type exprToken struct {
+	kind  string
+	text  string
+	value any
+}
+
+// exprOperators are the operators and punctuation of the language, longest first
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ".", ","}
+
+func tokenizeExpr(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	rest := source
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return tokens, nil
+		}
+
+		c := rest[0]
+		switch {
+		case c == '"' || c == '\'':
+			end := 1
+			for end < len(rest) && rest[end] != c {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, fmt.Errorf("invalid expression %q, unterminated string", source)
+			}
+			quoted := rest[:end+1]
+			if c == '\'' {
+				quoted = `"` + strings.ReplaceAll(quoted[1:end], `"`, `\"`) + `"`
+			}
+			s, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expression %q, bad string %s", source, rest[:end+1])
+			}
+			tokens = append(tokens, exprToken{kind: "string", text: rest[:end+1], value: s})
+			rest = rest[end+1:]
+		case c >= '0' && c <= '9':
+			end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+			if end < 0 {
+				end = len(rest)
+			}
+			n, err := strconv.ParseFloat(rest[:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expression %q, bad number %q", source, rest[:end])
+			}
+			tokens = append(tokens, exprToken{kind: "number", text: rest[:end], value: n})
+			rest = rest[end:]
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := strings.IndexFunc(rest, func(r rune) bool { return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+			if end < 0 {
+				end = len(rest)
+			}
+			tokens = append(tokens, exprToken{kind: "ident", text: rest[:end]})
+			rest = rest[end:]
+		default:
+			op := ""
+			for _, candidate := range exprOperators {
+				if strings.HasPrefix(rest, candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("invalid expression %q near %q", source, rest)
+			}
+			tokens = append(tokens, exprToken{kind: "op", text: op})
+			rest = rest[len(op):]
+		}
+	}
+}
+
+// exprParser is a recursive descent parser over the tokens of an expression
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// accept consumes the next token when it is the operator op
+func (p *exprParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == "op" && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	if p.accept(op) {
+		return nil
+	}
+	if p.pos < len(p.tokens) {
+		return fmt.Errorf("invalid expression, expected %q, got %q", op, p.tokens[p.pos].text)
+	}
+	return fmt.Errorf("invalid expression, expected %q at the end", op)
+}
+
+func (p *exprParser) or() (exprNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr(left, right, true)
+	}
+	return left, nil
+}
+
+func (p *exprParser) and() (exprNode, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr(left, right, false)
+	}
+	return left, nil
+}
+
+// logicalExpr short-circuits: the right side is only evaluated when the left one does not decide the result
+func logicalExpr(left, right exprNode, or bool) exprNode {
+	return func(env exprEnv) (any, error) {
+		for _, side := range []exprNode{left, right} {
+			value, err := side(env)
+			if err != nil {
+				return nil, err
+			}
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("&& and || need booleans, got %s", compactJSON(value))
+			}
+			if b == or {
+				return b, nil
+			}
+		}
+		return !or, nil
+	}
+}
+
+func (p *exprParser) comparison() (exprNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env exprEnv) (any, error) {
+			a, err := left(env)
+			if err != nil {
+				return nil, err
+			}
+			b, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr(op, a, b)
+		}, nil
+	}
+	return left, nil
+}
+
+// compareExpr compares numbers numerically and strings lexically, == and != compare any two values
+func compareExpr(op string, a, b any) (any, error) {
+	switch op {
+	case "==":
+		return compactJSON(a) == compactJSON(b), nil
+	case "!=":
+		return compactJSON(a) != compactJSON(b), nil
+	}
+
+	var order int
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s %s %s", compactJSON(a), op, compactJSON(b))
+		}
+		order = cmp.Compare(x, y)
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s %s %s", compactJSON(a), op, compactJSON(b))
+		}
+		order = cmp.Compare(x, y)
+	default:
+		return nil, fmt.Errorf("cannot compare %s %s %s", compactJSON(a), op, compactJSON(b))
+	}
+
+	switch op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	default:
+		return order >= 0, nil
+	}
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	if p.accept("!") {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(env exprEnv) (any, error) {
+			value, err := operand(env)
+			if err != nil {
+				return nil, err
+			}
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("! needs a boolean, got %s", compactJSON(value))
+			}
+			return !b, nil
+		}, nil
+	}
+	return p.postfix()
+}
+
+// postfix parses a primary value followed by any .field, ["key"], [index] and .method(args)
+func (p *exprParser) postfix() (exprNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != "ident" {
+				return nil, fmt.Errorf("invalid expression, expected a name after .")
+			}
+			name := p.tokens[p.pos].text
+			p.pos++
+
+			if !p.accept("(") {
+				node = indexExpr(node, func(exprEnv) (any, error) { return name, nil })
+				continue
+			}
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			method, ok := exprMethods[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid expression, unknown method %s()", name)
+			}
+			node = callExpr(name, method, node, args)
+		case p.accept("["):
+			key, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = indexExpr(node, key)
+		default:
+			return node, nil
+		}
+	}
+}
+
+// arguments parses a comma separated argument list after its opening parenthesis
+func (p *exprParser) arguments() ([]exprNode, error) {
+	var args []exprNode
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) primary() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression, unexpected end")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case "number", "string":
+		return func(exprEnv) (any, error) { return token.value, nil }, nil
+	case "ident":
+		switch token.text {
+		case "true", "false":
+			return func(exprEnv) (any, error) { return token.text == "true", nil }, nil
+		case "null":
+			return func(exprEnv) (any, error) { return nil, nil }, nil
+		case "status":
+			return func(env exprEnv) (any, error) { return env.Status, nil }, nil
+		case "latency":
+			return func(env exprEnv) (any, error) { return env.Latency, nil }, nil
+		case "headers":
+			return func(env exprEnv) (any, error) { return exprHeaders(env.Headers), nil }, nil
+		case "body":
+			return func(env exprEnv) (any, error) { return env.Body, nil }, nil
+		case "text":
+			return func(env exprEnv) (any, error) { return env.Text, nil }, nil
+		case "size":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 1 {
+				return nil, fmt.Errorf("invalid expression, size() takes one argument")
+			}
+			return func(env exprEnv) (any, error) {
+				value, err := args[0](env)
+				if err != nil {
+					return nil, err
+				}
+				return sizeExpr(value)
+			}, nil
+		}
+		return nil, fmt.Errorf("invalid expression, unknown name %q", token.text)
+	}
+
+	if token.text == "(" {
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	}
+	return nil, fmt.Errorf("invalid expression, unexpected %q", token.text)
+}
+
+// indexExpr looks up a header, an object key or an array index, null when it is missing
+func indexExpr(node, key exprNode) exprNode {
+	return func(env exprEnv) (any, error) {
+		value, err := node(env)
+		if err != nil {
+			return nil, err
+		}
+		k, err := key(env)
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := value.(type) {
+		case exprHeaders:
+			name, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("header names are strings, got %s", compactJSON(k))
+			}
+			values := http.Header(v).Values(name)
+			if len(values) == 0 {
+				return nil, nil
+			}
+			return strings.Join(values, ", "), nil
+		case map[string]any:
+			name, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("object keys are strings, got %s", compactJSON(k))
+			}
+			return v[name], nil
+		case []any:
+			n, ok := k.(float64)
+			if !ok {
+				return nil, fmt.Errorf("array indexes are numbers, got %s", compactJSON(k))
+			}
+			if i := int(n); i >= 0 && i < len(v) {
+				return v[i], nil
+			}
+			return nil, nil
+		case nil:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot index %s", compactJSON(value))
+	}
+}
+
+// exprMethods are the methods strings can be called with
+var exprMethods = map[string]func(s string, args []any) (any, error){
+	"contains": func(s string, args []any) (any, error) {
+		arg, err := stringArgument("contains", args)
+		return strings.Contains(s, arg), err
+	},
+	"startsWith": func(s string, args []any) (any, error) {
+		arg, err := stringArgument("startsWith", args)
+		return strings.HasPrefix(s, arg), err
+	},
+	"endsWith": func(s string, args []any) (any, error) {
+		arg, err := stringArgument("endsWith", args)
+		return strings.HasSuffix(s, arg), err
+	},
+	"matches": func(s string, args []any) (any, error) {
+		arg, err := stringArgument("matches", args)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return pattern.MatchString(s), nil
+	},
+	// param returns the value of a "name=value" directive in a header such as Cache-Control,
+	// as a number when it is one, true when the directive has no value and null when it is missing
+	"param": func(s string, args []any) (any, error) {
+		name, err := stringArgument("param", args)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+			key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			if !found {
+				return true, nil
+			}
+			value = strings.Trim(value, `"`)
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				return n, nil
+			}
+			return value, nil
+		}
+		return nil, nil
+	},
+}
+
+func callExpr(name string, method func(string, []any) (any, error), node exprNode, args []exprNode) exprNode {
+	return func(env exprEnv) (any, error) {
+		value, err := node(env)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() needs a string, got %s", name, compactJSON(value))
+		}
+
+		var values []any
+		for _, arg := range args {
+			v, err := arg(env)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return method(s, values)
+	}
+}
+
+func stringArgument(name string, args []any) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s() takes one argument", name)
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("%s() takes a string, got %s", name, compactJSON(args[0]))
+	}
+	return s, nil
+}
+
+// sizeExpr is the length of a string, array or object
+func sizeExpr(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return float64(len(v)), nil
+	case []any:
+		return float64(len(v)), nil
+	case map[string]any:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("size() needs a string, array or object, got %s", compactJSON(value))
+}
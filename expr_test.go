@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAssertExprCompoundExpression(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "no-store, max-age=30")
+		case "/stale":
+			w.Header().Set("Cache-Control", "no-store, max-age=3600")
+		case "/cached":
+			w.Header().Set("Cache-Control", "public, max-age=30")
+		}
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Origin")
+		w.Write([]byte(`{"items":[{"id":1},{"id":2}],"owner":{"name":"alice"}}`))
+	}))
+	defer srv.Close()
+
+	const cacheRule = `status == 200 && headers["Cache-Control"].contains("no-store") && headers["Cache-Control"].param("max-age") < 60`
+	tests := []struct {
+		path, expr string
+		pass       bool
+	}{
+		{"/fresh", cacheRule, true},
+		{"/stale", cacheRule, false},
+		{"/cached", cacheRule, false},
+		{"/fresh", `size(body.items) == 2 && body.items[1].id == 2 && body.owner.name.startsWith("al")`, true},
+		{"/fresh", `headers["Vary"] == "Accept, Origin" && !(headers["X-Missing"] != null)`, true},
+		{"/fresh", `body.owner.name == "bob" || latency > 60000`, false},
+		{"/fresh", `text.matches("\"id\":\\s*2") && latency >= 0`, true},
+	}
+	for _, tt := range tests {
+		assertion, err := assertExpr(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		runner := &Runner{Client: srv.Client(), Retry: 1, Assertions: []Assertion{assertion}, Report: ReportOptions{Sink: DiscardSink{}}}
+		outcome := runner.Run(NewURLRequest(srv.URL+tt.path), "out")
+		if outcome.Passed != tt.pass {
+			t.Errorf("%s against %s: passed %t, want %t (%v)", tt.expr, tt.path, outcome.Passed, tt.pass, outcome.Result.Failures)
+		}
+		if !tt.pass && !strings.Contains(strings.Join(outcome.Result.Failures, "\n"), "is false") {
+			t.Errorf("%s against %s: failures %q, want the expression reported as false", tt.expr, tt.path, outcome.Result.Failures)
+		}
+	}
+
+	for _, bad := range []string{`status ==`, `headers["A"].shout()`, `unknown > 1`, `(status == 200`} {
+		if _, err := assertExpr(bad); err == nil {
+			t.Errorf("assertExpr(%q) accepted an invalid expression", bad)
+		}
+	}
+}
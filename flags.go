@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// resolveFlag collects repeatable -resolve host:port:addr values
+type resolveFlag map[string]string
+
+func (r resolveFlag) String() string {
+	var entries []string
+	for k, v := range r {
+		entries = append(entries, k+"->"+v)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (r resolveFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("invalid resolve entry %q, expected host:port:addr", value)
+	}
+	host, port, addr := parts[0], parts[1], parts[2]
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+
+	r[net.JoinHostPort(host, port)] = net.JoinHostPort(addr, port)
+	return nil
+}
+
+// connectToFlag collects repeatable -connect-to host:port:connecthost:connectport values
+type connectToFlag map[string]string
+
+func (c connectToFlag) String() string {
+	var entries []string
+	for k, v := range c {
+		entries = append(entries, k+"->"+v)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (c connectToFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 4)
+	if len(parts) != 4 || parts[0] == "" || parts[1] == "" || parts[2] == "" || parts[3] == "" {
+		return fmt.Errorf("invalid connect-to entry %q, expected host:port:connecthost:connectport", value)
+	}
+	c[net.JoinHostPort(parts[0], parts[1])] = net.JoinHostPort(parts[2], parts[3])
+	return nil
+}
+
+// retriesPerStatusFlag collects -retries-per-status status:count values, comma separated or repeated
+type retriesPerStatusFlag map[int]int
+
+func (r retriesPerStatusFlag) String() string {
+	var entries []string
+	for status, count := range r {
+		entries = append(entries, fmt.Sprintf("%d:%d", status, count))
+	}
+	slices.Sort(entries)
+	return strings.Join(entries, ",")
+}
+
+func (r retriesPerStatusFlag) Set(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		status, count, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		code, err := strconv.Atoi(status)
+		if !ok || err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("invalid retries-per-status entry %q, expected status:count", entry)
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid retries-per-status entry %q, count must be at least 1", entry)
+		}
+		r[code] = n
+	}
+	return nil
+}
+
+// rewriteHostFlag collects repeatable -rewrite-host from=to values
+type rewriteHostFlag map[string]string
+
+func (r rewriteHostFlag) String() string {
+	var entries []string
+	for from, to := range r {
+		entries = append(entries, from+"="+to)
+	}
+	slices.Sort(entries)
+	return strings.Join(entries, ",")
+}
+
+func (r rewriteHostFlag) Set(value string) error {
+	from, to, ok := strings.Cut(value, "=")
+	if !ok || from == "" || to == "" || strings.Contains(from, "/") || strings.Contains(to, "/") {
+		return fmt.Errorf("invalid rewrite-host entry %q, expected from=to host names", value)
+	}
+	r[from] = to
+	return nil
+}
+
+// envFileFlag collects repeatable -env-file paths, in the order they were given
+type envFileFlag []string
+
+func (e *envFileFlag) String() string {
+	return strings.Join(*e, ",")
+}
+
+func (e *envFileFlag) Set(value string) error {
+	*e = append(*e, value)
+	return nil
+}
+
+// headerFlag collects repeatable -header "Name: Value" values
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	var entries []string
+	for k, v := range h {
+		entries = append(entries, k+": "+v)
+	}
+	return strings.Join(entries, ",")
+}
+
+func (h headerFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("invalid header %q, expected \"Name: Value\"", value)
+	}
+	h[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+// methodHeader is a header added only to requests whose method is in Methods
+type methodHeader struct {
+	Methods []string
+	Name    string
+	Value   string
+}
+
+// methodHeaderFlag collects repeatable -method-header "METHOD[,METHOD...] Name: Value" values
+type methodHeaderFlag []methodHeader
+
+func (h *methodHeaderFlag) String() string {
+	var entries []string
+	for _, mh := range *h {
+		entries = append(entries, strings.Join(mh.Methods, ",")+" "+mh.Name+": "+mh.Value)
+	}
+	return strings.Join(entries, ";")
+}
+
+func (h *methodHeaderFlag) Set(value string) error {
+	methods, header, ok := strings.Cut(strings.TrimSpace(value), " ")
+	parts := strings.SplitN(header, ":", 2)
+	if !ok || len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("invalid method header %q, expected \"METHOD[,METHOD...] Name: Value\"", value)
+	}
+	*h = append(*h, methodHeader{
+		Methods: strings.Split(strings.ToUpper(methods), ","),
+		Name:    strings.TrimSpace(parts[0]),
+		Value:   strings.TrimSpace(parts[1]),
+	})
+	return nil
+}
+
+// apply adds the headers whose methods include the request method, unless the request already sets them
+func (h methodHeaderFlag) apply(reqData *RequestData) {
+	for _, mh := range h {
+		if slices.Contains(mh.Methods, strings.ToUpper(reqData.Method)) && !hasHeader(reqData.Headers, mh.Name) {
+			reqData.Headers[mh.Name] = mh.Value
+		}
+	}
+}
+
+// genHeadersFlag is the -gen-headers count,size of synthetic headers added to every request
+type genHeadersFlag struct {
+	Count int
+	Size  int
+}
+
+func (g *genHeadersFlag) String() string {
+	if g.Count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d,%d", g.Count, g.Size)
+}
+
+func (g *genHeadersFlag) Set(value string) error {
+	count, size, ok := strings.Cut(value, ",")
+	var err error
+	if ok {
+		if g.Count, err = strconv.Atoi(strings.TrimSpace(count)); err == nil {
+			g.Size, err = strconv.Atoi(strings.TrimSpace(size))
+		}
+	}
+	if !ok || err != nil || g.Count < 1 || g.Size < 0 {
+		return fmt.Errorf("invalid gen-headers %q, expected count,size", value)
+	}
+	return nil
+}
+
+// apply adds Count headers named X-Generated-N with Size byte values to the request
+func (g *genHeadersFlag) apply(reqData *RequestData) {
+	value := strings.Repeat("x", g.Size)
+	for i := 1; i <= g.Count; i++ {
+		reqData.Headers[fmt.Sprintf("X-Generated-%d", i)] = value
+	}
+}
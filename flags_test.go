@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMethodHeaderOnlyOnMutatingRequests(t *testing.T) {
+	tokens := map[string][]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens[r.Method] = r.Header.Values("X-Csrf-Token")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "mixed.http", "POST "+srv.URL+"/items\n\n###\nGET "+srv.URL+"/items\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-method-header", "post,PUT,PATCH,DELETE X-CSRF-Token: abc", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if got := tokens[http.MethodPost]; len(got) != 1 || got[0] != "abc" {
+		t.Errorf("POST got X-CSRF-Token %q, want abc", got)
+	}
+	if got, ok := tokens[http.MethodGet]; !ok || len(got) != 0 {
+		t.Errorf("GET got X-CSRF-Token %q (sent %t), want it sent without the header", got, ok)
+	}
+}
+
+func TestGenHeadersSendsCountOfSize(t *testing.T) {
+	var generated, sized int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		generated, sized = 0, 0
+		for name, values := range r.Header {
+			if strings.HasPrefix(name, "X-Generated-") {
+				generated++
+				if len(values) == 1 && len(values[0]) == 64 {
+					sized++
+				}
+			}
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-gen-headers", "150,64", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if generated != 150 || sized != 150 {
+		t.Errorf("server got %d generated headers, %d of 64 bytes, want 150", generated, sized)
+	}
+	if report := readReport(t, filepath.Join(dir, "out|*.txt")); !strings.Contains(report, "Generated Headers: 150 (accepted: true)") {
+		t.Errorf("report does not record the accepted headers:\n%s", report)
+	}
+
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-gen-headers", "0,64", "-output", "bad"); code == 0 {
+		t.Errorf("-gen-headers 0,64 was accepted: %s", stderr)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// fuzzVariant is a copy of a request with edge-case headers, written to the wire as is
+type fuzzVariant struct {
+	Name string
+	// Headers are sent in order after the request headers, names keep their casing and may repeat
+	Headers [][2]string
+	// Recase rewrites the request header names with alternating casing
+	Recase bool
+}
+
+// fuzzVariants returns the header edge cases -fuzz-headers sends: long and oversized values,
+// many headers, a duplicate Host, alternating name casing, an empty value, a space before
+// the colon and a non-ASCII value
+func fuzzVariants() []fuzzVariant {
+	many := make([][2]string, 200)
+	for i := range many {
+		many[i] = [2]string{fmt.Sprintf("X-Fuzz-%d", i+1), "1"}
+	}
+
+	return []fuzzVariant{
+		{Name: "long-value", Headers: [][2]string{{"X-Fuzz-Long", strings.Repeat("a", 8<<10)}}},
+		{Name: "oversized-value", Headers: [][2]string{{"X-Fuzz-Oversized", strings.Repeat("a", 64<<10)}}},
+		{Name: "many-headers", Headers: many},
+		{Name: "duplicate-host", Headers: [][2]string{{"Host", "fuzz.invalid"}}},
+		{Name: "weird-casing", Headers: [][2]string{{"x-FUZZ-cAsInG", "1"}}, Recase: true},
+		{Name: "empty-value", Headers: [][2]string{{"X-Fuzz-Empty", ""}}},
+		{Name: "space-before-colon", Headers: [][2]string{{"X-Fuzz-Space ", "1"}}},
+		{Name: "non-ascii-value", Headers: [][2]string{{"X-Fuzz-Utf8", "héllo ✓"}}},
+	}
+}
+
+// fuzzResult is the response, or the error, a server gave to one variant
+type fuzzResult struct {
+	Variant string
+	Status  string
+	Err     error
+	Latency time.Duration
+}
+
+// fuzz sends every header variant of the request and reports the status each one got.
+// A variant fails when the server answers it with a 5xx, rejecting it any other way is expected.
+func (r *Runner) fuzz(reqData RequestData, outputPath string) Outcome {
+	outcome := Outcome{Request: reqData}
+
+	var results []fuzzResult
+	failed := 0
+	for i, variant := range fuzzVariants() {
+		start := time.Now()
+		response, err := sendRawVariant(r.Client, reqData, variant)
+		result := fuzzResult{Variant: variant.Name, Err: err, Latency: time.Since(start)}
+
+		attempt := Attempt{Number: i + 1, Latency: result.Latency, Err: err}
+		if response != nil {
+			result.Status = response.Status
+			attempt.StatusCode, attempt.Status = response.StatusCode, response.Status
+		}
+		outcome.Attempts = append(outcome.Attempts, attempt)
+		results = append(results, result)
+
+		if err != nil {
+			printInfo("fuzz", variant.Name+":", "error:", err)
+			continue
+		}
+		printInfo("fuzz", variant.Name+":", response.Status)
+		if response.StatusCode >= 500 {
+			failed++
+		}
+	}
+
+	if err := GenerateFuzzReport(r.Report.sink(), outputPath, reqData, results); err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	if failed > 0 {
+		outcome.Err = fmt.Errorf("%d header variants got a server error", failed)
+		return outcome
+	}
+	outcome.Passed = true
+	return outcome
+}
+
+// sendRawVariant writes the request with the variant headers over a new connection and reads the response.
+// The standard client would canonicalize or reject these headers, so the request is written by hand.
+func sendRawVariant(client *http.Client, reqData RequestData, variant fuzzVariant) (*http.Response, error) {
+	u, err := url.Parse(reqData.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	var headers [][2]string
+	for k, v := range reqData.Headers {
+		if strings.EqualFold(k, "Host") {
+			host = v
+			continue
+		}
+		headers = append(headers, [2]string{k, v})
+	}
+	headers = append([][2]string{{"Host", host}}, headers...)
+	if variant.Recase {
+		for i := range headers {
+			headers[i][0] = alternateCase(headers[i][0])
+		}
+	}
+	headers = append(headers, variant.Headers...)
+	if reqData.Body != "" || expectsBody(reqData.Method) {
+		headers = append(headers, [2]string{"Content-Length", fmt.Sprint(len(reqData.Body))})
+	}
+
+	ctx := context.Background()
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+
+	return exchangeRaw(ctx, client, u, reqData.Method, buildRawRequest(reqData.Method, u, headers, reqData.Body))
+}
+
+// alternateCase turns "Content-Type" into "cOnTeNt-tYpE"
+func alternateCase(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case i%2 == 0 && 'A' <= c && c <= 'Z':
+			b[i] = c + 'a' - 'A'
+		case i%2 == 1 && 'a' <= c && c <= 'z':
+			b[i] = c - ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// GenerateFuzzReport writes the status or error every header variant got
+func GenerateFuzzReport(sink ReportSink, outputPath string, reqData RequestData, results []fuzzResult) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-fuzz.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nRequest URL: %s\nVariants: %d\n\n", reqData.Method, reqData.URL, len(results)))
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		status := result.Status
+		if result.Err != nil {
+			status = "error: " + result.Err.Error()
+		}
+		_, err = io.WriteString(file, fmt.Sprintf("%s: %s (%.1fms)\n", result.Variant, status, milliseconds(result.Latency)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFuzzHeadersReportsEveryVariant(t *testing.T) {
+	var names []string
+	for _, variant := range fuzzVariants() {
+		names = append(names, variant.Name)
+	}
+	want := []string{"long-value", "oversized-value", "many-headers", "duplicate-host", "weird-casing", "empty-value", "space-before-colon", "non-ascii-value"}
+	if !slices.Equal(names, want) {
+		t.Errorf("variants = %v, want the documented edge cases %v", names, want)
+	}
+
+	var longValue int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v := r.Header.Get("X-Fuzz-Long"); v != "" {
+			longValue = len(v)
+		}
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, FuzzHeaders: true, Report: ReportOptions{Sink: sink}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	if !outcome.Passed {
+		t.Fatalf("fuzz run failed: %v", outcome.Err)
+	}
+	if len(outcome.Attempts) != len(want) {
+		t.Errorf("fuzz sent %d variants, want %d", len(outcome.Attempts), len(want))
+	}
+	if longValue != 8<<10 {
+		t.Errorf("server got an X-Fuzz-Long of %d bytes, want %d", longValue, 8<<10)
+	}
+
+	report := sink.report(t, "-fuzz.txt")
+	for _, name := range want {
+		if !strings.Contains(report, "\n"+name+": ") {
+			t.Errorf("fuzz report has no line for %s:\n%s", name, report)
+		}
+	}
+	// The Go server rejects a duplicate Host itself
+	if !strings.Contains(report, "duplicate-host: 400 Bad Request") {
+		t.Errorf("fuzz report does not show the duplicate Host rejected:\n%s", report)
+	}
+}
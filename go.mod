@@ -1,3 +1,16 @@
 module http2test
 
-go 1.21.2
+go 1.24
+
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/itchyny/gojq v0.12.17
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/net v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/itchyny/timefmt-go v0.1.6 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// graphEdge is a dependency of request To on request From, labelled with the branch or variable it comes from
+type graphEdge struct {
+	From, To int
+	Label    string
+	Branch   bool
+}
+
+// requestGraph returns the dependencies between requests: @on-success and @on-failure branches,
+// variables a request uses that an earlier one captures, and {{name.field}} references to named responses
+func requestGraph(requests []RequestData) []graphEdge {
+	byName := make(map[string]int)
+	for i, reqData := range requests {
+		if reqData.Name != "" {
+			byName[reqData.Name] = i
+		}
+	}
+
+	var edges []graphEdge
+	capturedBy := make(map[string]int)
+	for i, reqData := range requests {
+		texts := []string{reqData.URL, reqData.Body}
+		for _, v := range reqData.Headers {
+			texts = append(texts, v)
+		}
+
+		seen := make(map[string]bool)
+		for _, text := range texts {
+			for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+				expr := m[1]
+				if seen[expr] {
+					continue
+				}
+				seen[expr] = true
+
+				if from, ok := capturedBy[expr]; ok {
+					edges = append(edges, graphEdge{From: from, To: i, Label: expr})
+				} else if name, _, ok := strings.Cut(expr, "."); ok {
+					if from, ok := byName[name]; ok && from != i {
+						edges = append(edges, graphEdge{From: from, To: i, Label: expr})
+					}
+				}
+			}
+		}
+
+		for _, c := range reqData.Captures {
+			capturedBy[c.Name] = i
+		}
+	}
+
+	for i, reqData := range requests {
+		if to, ok := byName[reqData.OnSuccess]; ok {
+			edges = append(edges, graphEdge{From: i, To: to, Label: "on success", Branch: true})
+		}
+		if to, ok := byName[reqData.OnFailure]; ok {
+			edges = append(edges, graphEdge{From: i, To: to, Label: "on failure", Branch: true})
+		}
+	}
+	return edges
+}
+
+// WriteDOT writes the requests and their dependencies as a Graphviz DOT digraph,
+// variable dependencies as solid edges and branches as dashed ones
+func WriteDOT(w io.Writer, requests []RequestData) error {
+	var b strings.Builder
+	b.WriteString("digraph requests {\n\tnode [shape=box];\n")
+	for i, reqData := range requests {
+		label := reqData.Method + " " + reqData.URL
+		if reqData.Name != "" {
+			label = reqData.Name + "\n" + label
+		}
+		fmt.Fprintf(&b, "\tr%d [label=%s];\n", i+1, strconv.Quote(label))
+	}
+	for _, e := range requestGraph(requests) {
+		style := ""
+		if e.Branch {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "\tr%d -> r%d [label=%s%s];\n", e.From+1, e.To+1, strconv.Quote(e.Label), style)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
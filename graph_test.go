@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGraphDOTForChainedFile(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "flow.http", `# @capture token $.token
+# @on-failure next=alert
+POST `+srv.URL+`/login
+
+###
+# @name profile
+GET `+srv.URL+`/me
+Authorization: Bearer {{token}}
+
+###
+GET `+srv.URL+`/users/{{profile.id}}/orders
+
+###
+# @name alert
+POST `+srv.URL+`/alert
+`)
+	stdout, stderr, code := runMain(t, dir, "-source", source, "-graph", "dot")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	for _, want := range []string{
+		"digraph requests {\n",
+		`r1 [label="POST ` + srv.URL + `/login"];`,
+		`r2 [label="profile\nGET ` + srv.URL + `/me"];`,
+		`r3 [label="GET ` + srv.URL + `/users/{{profile.id}}/orders"];`,
+		`r4 [label="alert\nPOST ` + srv.URL + `/alert"];`,
+		`r1 -> r2 [label="token"];`,
+		`r2 -> r3 [label="profile.id"];`,
+		`r1 -> r4 [label="on failure", style=dashed];`,
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("DOT output is missing %s:\n%s", want, stdout)
+		}
+	}
+	if n := strings.Count(stdout, "->"); n != 3 {
+		t.Errorf("DOT output has %d edges, want 3:\n%s", n, stdout)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("server got %d requests, want none with -graph", calls.Load())
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const grpcWebTrailerFlag = 0x80
+
+// grpcWebFrame is a single length-prefixed message of a gRPC-Web response
+type grpcWebFrame struct {
+	Flag    byte
+	Payload []byte
+}
+
+// isGRPCWeb reports whether the content type is a gRPC-Web response
+func isGRPCWeb(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/grpc-web")
+}
+
+// parseGRPCWebFrames splits a gRPC-Web body into its frames, decoding the base64 -text variant first
+func parseGRPCWebFrames(contentType string, body []byte) ([]grpcWebFrame, error) {
+	if strings.HasPrefix(contentType, "application/grpc-web-text") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+		if err != nil {
+			return nil, fmt.Errorf("decode grpc-web-text body: %w", err)
+		}
+		body = decoded
+	}
+
+	var frames []grpcWebFrame
+	for len(body) > 0 {
+		if len(body) < 5 {
+			return nil, fmt.Errorf("truncated grpc-web frame header")
+		}
+		length := binary.BigEndian.Uint32(body[1:5])
+		if uint32(len(body)-5) < length {
+			return nil, fmt.Errorf("truncated grpc-web frame, want %d bytes", length)
+		}
+		frames = append(frames, grpcWebFrame{Flag: body[0], Payload: body[5 : 5+length]})
+		body = body[5+length:]
+	}
+
+	return frames, nil
+}
+
+// formatGRPCWebFrames renders the frame structure and trailer status for the report
+func formatGRPCWebFrames(frames []grpcWebFrame, hexdump bool) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("gRPC-Web frames: %d\n", len(frames)))
+
+	for i, f := range frames {
+		kind := "data"
+		if f.Flag&grpcWebTrailerFlag != 0 {
+			kind = "trailer"
+		}
+		b.WriteString(fmt.Sprintf("Frame %d: %s, flag 0x%02x, %d bytes\n", i+1, kind, f.Flag, len(f.Payload)))
+
+		if kind == "trailer" {
+			for _, line := range strings.Split(strings.TrimSpace(string(f.Payload)), "\r\n") {
+				if line != "" {
+					b.WriteString("  " + line + "\n")
+				}
+			}
+		} else if hexdump {
+			b.WriteString(hex.Dump(f.Payload))
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// grpcWebFrameBytes encodes a length-prefixed gRPC-Web frame
+func grpcWebFrameBytes(flag byte, payload string) []byte {
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestGRPCWebFramesReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc-web+proto")
+		w.Write(grpcWebFrameBytes(0, "abc"))
+		w.Write(grpcWebFrameBytes(grpcWebTrailerFlag, "grpc-status: 0\r\ngrpc-message: OK\r\n"))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, GRPCHexDump: true}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, ".txt")
+	for _, want := range []string{
+		"gRPC-Web frames: 2",
+		"Frame 1: data, flag 0x00, 3 bytes",
+		"61 62 63",
+		"Frame 2: trailer, flag 0x80",
+		"  grpc-status: 0\n  grpc-message: OK\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
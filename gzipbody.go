@@ -0,0 +1,54 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipSidecar describes a response body stored gzipped next to its report
+type gzipSidecar struct {
+	Name             string `json:"file"`
+	CompressedSize   int    `json:"compressed_bytes"`
+	UncompressedSize int    `json:"uncompressed_bytes"`
+}
+
+// writeGzipSidecar gzips the body into a new report named name and returns its sizes
+func writeGzipSidecar(sink ReportSink, name string, body []byte) (*gzipSidecar, error) {
+	file, err := sink.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &countingWriter{w: file}
+	gz := gzip.NewWriter(counter)
+	_, err = gz.Write(body)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &gzipSidecar{Name: name, CompressedSize: counter.n, UncompressedSize: len(body)}, nil
+}
+
+// String describes the sidecar in place of the body in text reports
+func (s *gzipSidecar) String() string {
+	return fmt.Sprintf("[response body stored gzipped in %s: %d bytes compressed, %d bytes uncompressed]", s.Name, s.CompressedSize, s.UncompressedSize)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipSidecarHoldsBody(t *testing.T) {
+	body := strings.Repeat("large response body\n", 500)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, GzipBody: true}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	gz, err := gzip.NewReader(strings.NewReader(sink.report(t, ".txt.gz")))
+	if err != nil {
+		t.Fatalf("sidecar is not gzip: %v", err)
+	}
+	got, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte(body)) {
+		t.Errorf("sidecar decompresses to %d bytes, want the %d byte body", len(got), len(body))
+	}
+
+	var report string
+	for _, name := range sink.names() {
+		if strings.HasSuffix(name, ".txt") {
+			report = sink.reports[name].String()
+		}
+	}
+	if strings.Contains(report, "large response body") || !strings.Contains(report, "bytes uncompressed]") {
+		t.Errorf("report does not point at the sidecar in place of the body:\n%s", report)
+	}
+}
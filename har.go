@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// harFile is the subset of the HAR 1.2 format needed to replay requests
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// ReadHARFile parses a HAR file and returns the RequestData of every entry
+func ReadHARFile(filePath string) ([]RequestData, error) {
+	file, err := openSource(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid har file: %w", err)
+	}
+
+	var requests []RequestData
+	for i, entry := range har.Log.Entries {
+		if entry.Request.Method == "" || entry.Request.URL == "" {
+			return nil, fmt.Errorf("invalid har entry %d: missing method or url", i+1)
+		}
+
+		reqData := RequestData{
+			Method:  entry.Request.Method,
+			URL:     entry.Request.URL,
+			Headers: make(map[string]string),
+		}
+
+		for _, h := range entry.Request.Headers {
+			// HTTP/2 pseudo headers and framing headers are recomputed when the request is sent
+			if strings.HasPrefix(h.Name, ":") || strings.EqualFold(h.Name, "Content-Length") {
+				continue
+			}
+			reqData.Headers[h.Name] = h.Value
+		}
+
+		if entry.Request.PostData != nil {
+			reqData.Body = entry.Request.PostData.Text
+			if entry.Request.PostData.MimeType != "" && !hasHeader(reqData.Headers, "Content-Type") {
+				reqData.Headers["Content-Type"] = entry.Request.PostData.MimeType
+			}
+		}
+
+		requests = append(requests, reqData)
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHARReplaysEveryEntry(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, fmt.Sprintf("%s %s %s %s", r.Method, r.URL.Path, r.Header.Get("Content-Type"), body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	har := writeFile(t, dir, "session.har", fmt.Sprintf(`{"log": {"entries": [
+		{"request": {"method": "GET", "url": "%[1]s/first", "headers": [{"name": ":authority", "value": "x"}]}},
+		{"request": {"method": "POST", "url": "%[1]s/second", "postData": {"mimeType": "application/json", "text": "{\"a\":1}"}}}
+	]}}`, srv.URL))
+
+	_, stderr, code := runMain(t, dir, "-source", har, "-format-in", "har", "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	want := []string{"GET /first  ", `POST /second application/json {"a":1}`}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("server got %q, want %q", got, want)
+	}
+	for _, name := range []string{"out-1|*.txt", "out-2|*.txt"} {
+		readReport(t, filepath.Join(dir, name))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// headerChange is one difference between the headers sent and the headers an echo endpoint received
+type headerChange struct {
+	Name     string
+	Sent     string
+	Received string
+}
+
+// echoedHeaders reads the headers an echo endpoint reports receiving from the object at path in its JSON body
+func echoedHeaders(body []byte, path string) (http.Header, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("diff-headers: response is not JSON: %w", err)
+	}
+
+	value, ok, err := lookupJSONPath(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	obj, isObject := value.(map[string]any)
+	if !ok || !isObject {
+		return nil, fmt.Errorf("diff-headers: no header object at %s", path)
+	}
+
+	header := make(http.Header, len(obj))
+	for name, v := range obj {
+		switch v := v.(type) {
+		case []any:
+			for _, item := range v {
+				header.Add(name, jsonValueString(item))
+			}
+		default:
+			header.Add(name, jsonValueString(v))
+		}
+	}
+	return header, nil
+}
+
+// diffHeaders lists the headers added, removed and changed between sent and received, by name
+func diffHeaders(sent, received http.Header) []headerChange {
+	canonical := func(h http.Header) map[string]string {
+		out := make(map[string]string, len(h))
+		for name, values := range h {
+			out[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+		}
+		return out
+	}
+	s, r := canonical(sent), canonical(received)
+
+	names := make([]string, 0, len(s)+len(r))
+	for name := range s {
+		names = append(names, name)
+	}
+	for name := range r {
+		if _, ok := s[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []headerChange
+	for _, name := range names {
+		if s[name] != r[name] {
+			changes = append(changes, headerChange{Name: name, Sent: s[name], Received: r[name]})
+		}
+	}
+	return changes
+}
+
+// diffHeaders compares the headers sent with the ones the echo endpoint reports at DiffHeaders and writes the differences
+func (r *Runner) diffHeaders(reqData RequestData, result *Result, outputPath string) {
+	received, err := echoedHeaders(result.Body, r.DiffHeaders)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	changes := diffHeaders(result.SentHeaders, received)
+	if err := GenerateHeaderDiffReport(r.Report.sink(), outputPath, reqData, changes); err != nil {
+		printError(err)
+		return
+	}
+	if len(changes) > 0 {
+		printInfo("headers changed on the way to the server:", len(changes), reqData.URL)
+	}
+}
+
+// GenerateHeaderDiffReport writes the headers that were added, removed or changed on the way to the server
+func GenerateHeaderDiffReport(sink ReportSink, outputPath string, reqData RequestData, changes []headerChange) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-headers.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nRequest URL: %s\n\nHeader Diff (+ added, - removed, ~ changed on the way to the server):\n", reqData.Method, reqData.URL))
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		_, err = io.WriteString(file, "no differences\n")
+		return err
+	}
+
+	for _, c := range changes {
+		var line string
+		switch {
+		case c.Sent == "":
+			line = fmt.Sprintf("+ %s: %s\n", c.Name, c.Received)
+		case c.Received == "":
+			line = fmt.Sprintf("- %s: %s\n", c.Name, c.Sent)
+		default:
+			line = fmt.Sprintf("~ %s: %s -> %s\n", c.Name, c.Sent, c.Received)
+		}
+		_, err = io.WriteString(file, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDiffHeadersShowsProxyChanges(t *testing.T) {
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"headers": r.Header})
+	}))
+	defer echo.Close()
+
+	target, _ := url.Parse(echo.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Header.Set("Via", "1.1 test-proxy")
+		r.Header.Del("X-Debug")
+		r.Header.Set("X-Tenant", "rewritten")
+	}
+	srv := httptest.NewServer(proxy)
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, DiffHeaders: "$.headers", Report: ReportOptions{Sink: sink}}
+	reqData := NewURLRequest(srv.URL)
+	reqData.Headers["X-Debug"] = "1"
+	reqData.Headers["X-Tenant"] = "acme"
+	if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, "-headers.txt")
+	for _, want := range []string{"+ Via: 1.1 test-proxy\n", "+ X-Forwarded-For: 127.0.0.1\n", "- X-Debug: 1\n", "~ X-Tenant: acme -> rewritten\n"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("header diff is missing %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "User-Agent") {
+		t.Errorf("header diff lists the unchanged User-Agent:\n%s", report)
+	}
+}
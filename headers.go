@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// ReadHeadersFile parses a file of "Name: Value" lines, skipping blank lines and # comments
+func ReadHeadersFile(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	headers := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: invalid header %q, expected \"Name: Value\"", filePath, lineNumber, line)
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+// HostHeaders is a set of default headers for the hosts matching Pattern, a glob such as *.example.com
+type HostHeaders struct {
+	Pattern string
+	Headers map[string]string
+}
+
+// ReadHostHeadersFile parses "[host pattern]" sections, each followed by the "Name: Value" lines sent to matching hosts
+func ReadHostHeadersFile(filePath string) ([]HostHeaders, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var sets []HostHeaders
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			pattern := strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+				return nil, fmt.Errorf("%s:%d: invalid host pattern %q", filePath, lineNumber, line)
+			}
+			sets = append(sets, HostHeaders{Pattern: pattern, Headers: make(map[string]string)})
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s:%d: invalid header %q, expected \"Name: Value\"", filePath, lineNumber, line)
+		}
+		if len(sets) == 0 {
+			return nil, fmt.Errorf("%s:%d: header %q comes before any [host] section", filePath, lineNumber, line)
+		}
+		sets[len(sets)-1].Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+// applyHostHeaders merges the headers of every set whose pattern matches the host of rawURL, earlier sets win
+func applyHostHeaders(headers map[string]string, rawURL string, sets []HostHeaders) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, set := range sets {
+		if ok, _ := path.Match(set.Pattern, host); ok || set.Pattern == strings.ToLower(u.Host) {
+			mergeHeaders(headers, set.Headers)
+		}
+	}
+}
+
+// mergeHeaders copies defaults into headers unless a header with the same name, ignoring case, is already set
+func mergeHeaders(headers, defaults map[string]string) {
+	for name, value := range defaults {
+		if !hasHeader(headers, name) {
+			headers[name] = value
+		}
+	}
+}
+
+// hasHeader reports whether headers contains name, ignoring case
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// preserveHeaderCaseKey is the context key that makes SendRequest keep header names as written
+type preserveHeaderCaseKey struct{}
+
+// withPreserveHeaderCase returns a context in which SendRequest sends header names with their casing
+// from the request instead of canonicalizing them. HTTP/2 still lowercases every name on the wire.
+func withPreserveHeaderCase(ctx context.Context) context.Context {
+	return context.WithValue(ctx, preserveHeaderCaseKey{}, true)
+}
+
+// transportHeaders are the headers net/http writes from request fields and only recognizes by their
+// canonical names, so they are set canonically even when -preserve-header-case is on
+var transportHeaders = []string{"Content-Length", "Transfer-Encoding", "Trailer", "User-Agent"}
+
+// minimalHeadersKey is the context key that makes SendRequest leave out the User-Agent net/http adds
+type minimalHeadersKey struct{}
+
+// withMinimalHeaders returns a context in which SendRequest sends no User-Agent unless the request sets one
+func withMinimalHeaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, minimalHeadersKey{}, true)
+}
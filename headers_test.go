@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHeadersFileMergesUnderRequestHeaders(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "headers.txt", "# shared headers\nX-Team: payments\n\naccept: text/plain\n")
+	shared, err := ReadHeadersFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{"Accept": "application/json"}
+	mergeHeaders(headers, shared)
+
+	if headers["X-Team"] != "payments" {
+		t.Errorf("X-Team = %q, want payments from the file", headers["X-Team"])
+	}
+	if headers["Accept"] != "application/json" || len(headers) != 2 {
+		t.Errorf("headers = %v, want the request Accept to win over the file", headers)
+	}
+}
+
+func TestHeadersFileRejectsMalformedLine(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "headers.txt", "X-Team: payments\nnot a header\n")
+	_, err := ReadHeadersFile(path)
+	if err == nil || !strings.Contains(err.Error(), "headers.txt:2: invalid header") {
+		t.Errorf("error = %v, want an invalid header on line 2", err)
+	}
+}
+
+func TestHostHeadersMatchSubstitutedHost(t *testing.T) {
+	got := make(map[string]http.Header)
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			got[name] = r.Header.Clone()
+		}
+	}
+	srvA := httptest.NewServer(handler("a"))
+	defer srvA.Close()
+	srvB := httptest.NewServer(handler("b"))
+	defer srvB.Close()
+
+	hostA, hostB := strings.TrimPrefix(srvA.URL, "http://"), strings.TrimPrefix(srvB.URL, "http://")
+	path := writeFile(t, t.TempDir(), "hosts.txt", "["+hostA+"]\nX-Api-Key: key-a\n\n["+hostB+"]\nX-Api-Key: key-b\nX-Tenant: b\n\n[*]\nX-Team: payments\n")
+	sets, err := ReadHostHeadersFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &Runner{
+		Client:      http.DefaultClient,
+		Retry:       1,
+		Env:         map[string]string{"apiA": srvA.URL, "apiB": srvB.URL},
+		HostHeaders: sets,
+		Report:      ReportOptions{Sink: DiscardSink{}},
+	}
+	for _, url := range []string{"{{apiA}}/items", "{{apiB}}/items"} {
+		if outcome := runner.Run(NewURLRequest(url), "out"); !outcome.Passed {
+			t.Fatalf("%s failed: %v", url, outcome.Err)
+		}
+	}
+
+	if key := got["a"].Get("X-Api-Key"); key != "key-a" {
+		t.Errorf("host a got X-Api-Key %q, want key-a", key)
+	}
+	if tenant := got["a"].Get("X-Tenant"); tenant != "" {
+		t.Errorf("host a got host b's X-Tenant %q", tenant)
+	}
+	if key := got["b"].Get("X-Api-Key"); key != "key-b" {
+		t.Errorf("host b got X-Api-Key %q, want key-b", key)
+	}
+	for name, header := range got {
+		if team := header.Get("X-Team"); team != "payments" {
+			t.Errorf("host %s got X-Team %q, want payments from the [*] section", name, team)
+		}
+	}
+}
+
+func TestPreserveHeaderCaseOnTheWire(t *testing.T) {
+	addr, received := startRawRecorder(t)
+
+	for _, preserve := range []bool{true, false} {
+		runner := &Runner{Client: &http.Client{}, Retry: 1, PreserveHeaderCase: preserve, Report: ReportOptions{Sink: DiscardSink{}}}
+		reqData := NewURLRequest("http://" + addr + "/")
+		reqData.Headers["x-custom"] = "yes"
+		if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+			t.Fatalf("-preserve-header-case %t: request failed: %v", preserve, outcome.Err)
+		}
+
+		raw := <-received
+		want, unwanted := "\r\nx-custom: yes\r\n", "\r\nX-Custom: yes\r\n"
+		if !preserve {
+			want, unwanted = unwanted, want
+		}
+		if !strings.Contains(raw, want) || strings.Contains(raw, unwanted) {
+			t.Errorf("-preserve-header-case %t: request does not carry %q:\n%s", preserve, strings.TrimSpace(want), raw)
+		}
+	}
+}
+
+func TestTrailerDirectiveSendsTrailers(t *testing.T) {
+	// The trailers are only known once the body has been read
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		fmt.Fprintf(w, "%s Grpc-Status: %s, X-Checksum: %s", r.Proto, r.Trailer.Get("Grpc-Status"), r.Trailer.Get("X-Checksum"))
+	})
+	plain := httptest.NewServer(echo)
+	defer plain.Close()
+	h2 := httptest.NewUnstartedServer(echo)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+
+	dir := t.TempDir()
+	env := writeFile(t, dir, "trailers.env", "sum=abc123\n")
+	for _, tt := range []struct {
+		name, url, proto string
+		args             []string
+	}{
+		{"http1", plain.URL, "HTTP/1.1", nil},
+		{"http2", h2.URL, "HTTP/2.0", []string{"-http2", "-ca-bundle", writeServerCA(t, h2)}},
+	} {
+		source := writeFile(t, dir, tt.name+".http", "# @trailer Grpc-Status: 0\n# @trailer X-Checksum: {{sum}}\nPOST "+tt.url+"/upload\n\npayload\n")
+		args := append([]string{"-source", source, "-env-file", env, "-output", tt.name}, tt.args...)
+		if _, stderr, code := runMain(t, dir, args...); code != 0 {
+			t.Fatalf("%s: exit code %d: %s", tt.name, code, stderr)
+		}
+		if report := readReport(t, filepath.Join(dir, tt.name+"|*.txt")); !strings.Contains(report, tt.proto+" Grpc-Status: 0, X-Checksum: abc123") {
+			t.Errorf("%s: server did not receive the trailers:\n%s", tt.name, report)
+		}
+	}
+}
+
+func TestMinimalHeadersSendsOnlyFileHeaders(t *testing.T) {
+	addr, received := startRawRecorder(t)
+	dir := t.TempDir()
+	source := writeFile(t, dir, "minimal.http", "GET http://"+addr+"/probe\nX-Token: abc\n")
+
+	headerNames := func(args ...string) []string {
+		t.Helper()
+		if _, stderr, code := runMain(t, dir, append([]string{"-source", source}, args...)...); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr)
+		}
+		var names []string
+		lines := strings.Split(strings.TrimSuffix(<-received, "\r\n\r\n"), "\r\n")
+		for _, line := range lines[1:] {
+			name, _, _ := strings.Cut(line, ":")
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got := headerNames("-minimal-headers", "-output", "minimal"); !slices.Equal(got, []string{"Host", "X-Token"}) {
+		t.Errorf("-minimal-headers sent %v, want only Host and the file's X-Token", got)
+	}
+	if got := headerNames("-output", "default"); !slices.Contains(got, "User-Agent") || !slices.Contains(got, "Accept-Encoding") {
+		t.Errorf("without -minimal-headers the request had %v, want Go's User-Agent and Accept-Encoding too", got)
+	}
+}
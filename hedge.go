@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// execute sends one attempt of the request, hedging it with a duplicate when HedgeAfter passes without a response
+func (r *Runner) execute(ctx context.Context, reqData RequestData, outputPath string) (*Result, error) {
+	if r.Stream {
+		return r.stream(ctx, reqData, outputPath)
+	}
+	// A duplicate of a request that is not safe to resend could create the resource twice
+	if r.HedgeAfter <= 0 || !retrySafe(reqData) {
+		return r.executeOnce(ctx, reqData)
+	}
+	return r.executeHedged(ctx, reqData)
+}
+
+// executeOnce sends the request once, reading the body as an event stream in -sse mode
+// and only hashing it in HashOnly mode
+func (r *Runner) executeOnce(ctx context.Context, reqData RequestData) (*Result, error) {
+	if r.SSE {
+		return ExecuteSSE(ctx, r.Client, reqData, r.SSEMaxEvents)
+	}
+	// A body saved with # @save-body is still needed once it has been hashed
+	if r.HashOnly && reqData.SaveBody == "" {
+		return execute(ctx, r.Client, reqData, discardBody)
+	}
+	return Execute(ctx, r.Client, reqData)
+}
+
+// executeHedged sends the request and, if it has not answered within HedgeAfter, a duplicate of it.
+// The first successful response wins and the other request is canceled.
+func (r *Runner) executeHedged(ctx context.Context, reqData RequestData) (*Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type reply struct {
+		result *Result
+		err    error
+		hedged bool
+	}
+	replies := make(chan reply, 2)
+	send := func(hedged bool) {
+		result, err := r.executeOnce(ctx, reqData)
+		replies <- reply{result, err, hedged}
+	}
+
+	go send(false)
+	pending := 1
+
+	timer := time.NewTimer(r.HedgeAfter)
+	defer timer.Stop()
+	hedge := timer.C
+
+	for {
+		select {
+		case <-hedge:
+			hedge = nil
+			go send(true)
+			pending++
+		case rep := <-replies:
+			pending--
+			if rep.err == nil {
+				rep.result.Hedge = "first request"
+				if rep.hedged {
+					rep.result.Hedge = "hedged request"
+				}
+				if hedge == nil {
+					printInfo("hedge:", rep.result.Hedge, "won", reqData.URL)
+				}
+				return rep.result, nil
+			}
+			if pending == 0 {
+				return nil, rep.err
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgedRequestBeatsSlowFirstRequest(t *testing.T) {
+	const slow = 2 * time.Second
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first request stalls until it is canceled, the hedged duplicate answers at once
+		if calls.Add(1) == 1 {
+			select {
+			case <-time.After(slow):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte("done"))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, HedgeAfter: 50 * time.Millisecond, Report: ReportOptions{Sink: sink}}
+	start := time.Now()
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	elapsed := time.Since(start)
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	if elapsed >= slow/2 {
+		t.Errorf("hedged request took %s, want well under the %s the first request stalls", elapsed, slow)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("server got %d requests, want the first and its hedge", calls.Load())
+	}
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "Hedge Winner: hedged request\n") {
+		t.Errorf("report does not name the hedged request as the winner:\n%s", report)
+	}
+}
+
+func TestHedgeNotSentForFastResponse(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, HedgeAfter: time.Second, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("server got %d requests, want no hedge for a fast response", calls.Load())
+	}
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "Hedge Winner: first request\n") {
+		t.Errorf("report does not name the first request as the winner:\n%s", report)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// http10Transport sends requests with an HTTP/1.0 request line over a fresh connection each time
+type http10Transport struct {
+	base *http.Transport
+}
+
+func (t *http10Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+
+	conn, err := t.base.DialContext(req.Context(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL.Scheme == "https" {
+		config := &tls.Config{}
+		if t.base.TLSClientConfig != nil {
+			config = t.base.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = req.URL.Hostname()
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(req.Context()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if err := writeHTTP10Request(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	req.Proto, req.ProtoMajor, req.ProtoMinor = "HTTP/1.0", 1, 0
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body = &connClosingBody{ReadCloser: resp.Body, conn: conn}
+
+	return resp, nil
+}
+
+// writeHTTP10Request writes the request line, headers and body without chunked encoding or keep-alive
+func writeHTTP10Request(w io.Writer, req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %s HTTP/1.0\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), host)
+
+	header := req.Header.Clone()
+	header.Del("Connection")
+	header.Del("Transfer-Encoding")
+	// An empty POST, PUT or PATCH still needs Content-Length: 0, as net/http sends it for HTTP/1.1 and HTTP/2
+	if len(body) > 0 || expectsBody(req.Method) {
+		header.Set("Content-Length", fmt.Sprint(len(body)))
+	}
+	if err := header.Write(bw); err != nil {
+		return err
+	}
+
+	bw.WriteString("\r\n")
+	bw.Write(body)
+	return bw.Flush()
+}
+
+// connClosingBody closes the underlying connection along with the response body
+type connClosingBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (b *connClosingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
+
+// expectsBody reports whether requests with the method carry a body, so an empty one is framed with Content-Length: 0
+func expectsBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
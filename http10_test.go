@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTP10RequestLine(t *testing.T) {
+	var proto string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.Proto
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptions{HTTP10: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(context.Background(), client, NewURLRequest(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proto != "HTTP/1.0" {
+		t.Errorf("server saw %s, want HTTP/1.0", proto)
+	}
+	if string(result.Body) != "ok" {
+		t.Errorf("body = %q, want ok", result.Body)
+	}
+}
+
+func TestEmptyPOSTSendsContentLengthZero(t *testing.T) {
+	for _, http10 := range []bool{false, true} {
+		var contentLength []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentLength = r.Header.Values("Content-Length")
+		}))
+
+		client, err := NewClient(ClientOptions{HTTP10: http10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqData := NewURLRequest(srv.URL)
+		reqData.Method = http.MethodPost
+		if _, err := Execute(context.Background(), client, reqData); err != nil {
+			t.Fatal(err)
+		}
+		srv.Close()
+
+		if len(contentLength) != 1 || contentLength[0] != "0" {
+			t.Errorf("HTTP/1.0 %t: server got Content-Length %q, want 0", http10, contentLength)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Session is the controller behind -interactive, it picks requests by number and runs them with the runner
+type Session struct {
+	Requests []RequestData
+	Runner   *Runner
+	Output   string
+}
+
+// Select parses the number typed at the prompt into a request index
+func (s *Session) Select(input string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || n < 1 || n > len(s.Requests) {
+		return 0, fmt.Errorf("pick a request between 1 and %d", len(s.Requests))
+	}
+	return n - 1, nil
+}
+
+// Run sends the request at index i, writing its report under Output-<n> or the request's # @output name
+func (s *Session) Run(i int) Outcome {
+	if name := s.Requests[i].Output; name != "" {
+		return s.Runner.Run(s.Requests[i], filepath.Join(filepath.Dir(s.Output), name))
+	}
+	return s.Runner.Run(s.Requests[i], fmt.Sprintf("%s-%d", s.Output, i+1))
+}
+
+// Interact lists the requests and runs the ones picked on in until it reads q or reaches EOF
+func (s *Session) Interact(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+
+	s.list(out)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		switch input := strings.TrimSpace(scanner.Text()); input {
+		case "":
+			continue
+		case "q", "quit":
+			return nil
+		case "l", "list":
+			s.list(out)
+		default:
+			i, err := s.Select(input)
+			if err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+
+			outcome := s.Run(i)
+			fmt.Fprintln(out, statusLine(outcome))
+			if outcome.Result != nil {
+				fmt.Fprintf(out, "%s\n", outcome.Result.Body)
+			}
+		}
+	}
+}
+
+// list prints the numbered requests with the available commands
+func (s *Session) list(out io.Writer) {
+	for i, reqData := range s.Requests {
+		label := reqData.Method + " " + reqData.URL
+		if reqData.Name != "" {
+			label = reqData.Name + ": " + label
+		}
+		fmt.Fprintf(out, "%3d  %s\n", i+1, label)
+	}
+	fmt.Fprintln(out, "enter a number to run it, l to list, q to quit")
+}
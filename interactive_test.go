@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessionSelectsAndRunsRequests(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte("body of " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	session := &Session{
+		Requests: []RequestData{NewURLRequest(srv.URL + "/one"), NewURLRequest(srv.URL + "/two")},
+		Runner:   &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink}},
+		Output:   "out",
+	}
+	session.Requests[1].Name = "second"
+
+	for _, input := range []string{"0", "3", "two"} {
+		if _, err := session.Select(input); err == nil {
+			t.Errorf("Select(%q) accepted a request that does not exist", input)
+		}
+	}
+	if i, err := session.Select(" 2 "); err != nil || i != 1 {
+		t.Errorf("Select(2) = %d, %v, want index 1", i, err)
+	}
+
+	var out bytes.Buffer
+	if err := session.Interact(strings.NewReader("2\n9\n1\nq\n2\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(paths, ",") != "/two,/one" {
+		t.Errorf("requests sent = %v, want /two then /one and none after q", paths)
+	}
+	for _, want := range []string{"  2  second: GET " + srv.URL + "/two", "body of /two", "pick a request between 1 and 2", "body of /one"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("session output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if names := sink.names(); len(names) != 2 || !strings.HasPrefix(names[0], "out-1|") || !strings.HasPrefix(names[1], "out-2|") {
+		t.Errorf("reports = %v, want one under out-1 and one under out-2", names)
+	}
+}
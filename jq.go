@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/itchyny/gojq"
+)
+
+// jqFilter is a compiled -jq expression
+type jqFilter struct {
+	expr string
+	code *gojq.Code
+}
+
+// parseJQ parses and compiles a -jq expression with gojq, which implements the full jq language
+func parseJQ(expr string) (*jqFilter, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -jq %q: %w", expr, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -jq %q: %w", expr, err)
+	}
+	return &jqFilter{expr: expr, code: code}, nil
+}
+
+// apply runs the filter over a JSON document and writes every output on its own line as indented JSON, like jq
+func (f *jqFilter) apply(body []byte) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	iter := f.code.Run(doc)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := value.(error); ok {
+			// halt stops the program without an error
+			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
+				break
+			}
+			return nil, fmt.Errorf("jq %q: %w", f.expr, err)
+		}
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		out.Write(data)
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
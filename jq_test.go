@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJQExtractsValuesIntoReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"id":1,"name":"alice","active":true},{"id":2,"name":"bob","active":false}]}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".data[] | .id", "Response Body:\n1\n2\n"},
+		{`[.data[] | select(.active) | .name]`, "Response Body:\n[\n  \"alice\"\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			jq, err := parseJQ(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sink := &memorySink{}
+			runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, JQ: jq}}
+			if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+				t.Fatalf("request failed: %v", outcome.Err)
+			}
+			if report := sink.report(t, ".txt"); !strings.Contains(report, tt.want) || strings.Contains(report, "bob") {
+				t.Errorf("report does not hold the jq output %q:\n%s", tt.want, report)
+			}
+		})
+	}
+
+	if _, err := parseJQ(".data[] |"); err == nil {
+		t.Error("parseJQ accepted an incomplete expression")
+	}
+}
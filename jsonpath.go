@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSegment is one step of a parsed JSONPath: an object key, an array index or a wildcard
+type jsonPathSegment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// parseJSONPath parses the subset of JSONPath used by the tool: $.a.b, $['a'], $.a[0] and $.a[*]
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q, must start with $", path)
+	}
+
+	var segments []jsonPathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q, unclosed [", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, jsonPathSegment{Wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, jsonPathSegment{Key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q, bad index %q", path, inner)
+				}
+				segments = append(segments, jsonPathSegment{Index: n, IsIndex: true})
+			}
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q, empty key", path)
+			}
+			if key == "*" {
+				segments = append(segments, jsonPathSegment{Wildcard: true})
+			} else {
+				segments = append(segments, jsonPathSegment{Key: key})
+			}
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q near %q", path, rest)
+		}
+	}
+
+	return segments, nil
+}
+
+// lookupJSONPath evaluates path against a decoded JSON document.
+// A wildcard collects the matches into an array, ok is false when nothing matches.
+func lookupJSONPath(doc any, path string) (value any, ok bool, err error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok = walkJSONPath(doc, segments)
+	return value, ok, nil
+}
+
+func walkJSONPath(node any, segments []jsonPathSegment) (any, bool) {
+	if len(segments) == 0 {
+		return node, true
+	}
+	seg, rest := segments[0], segments[1:]
+
+	switch {
+	case seg.Wildcard:
+		var children []any
+		switch n := node.(type) {
+		case []any:
+			children = n
+		case map[string]any:
+			for _, k := range sortedKeys(n) {
+				children = append(children, n[k])
+			}
+		default:
+			return nil, false
+		}
+
+		matches := []any{}
+		for _, child := range children {
+			if v, ok := walkJSONPath(child, rest); ok {
+				matches = append(matches, v)
+			}
+		}
+		return matches, len(matches) > 0
+	case seg.IsIndex:
+		arr, ok := node.([]any)
+		if !ok {
+			return nil, false
+		}
+		i := seg.Index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil, false
+		}
+		return walkJSONPath(arr[i], rest)
+	default:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		child, ok := obj[seg.Key]
+		if !ok {
+			return nil, false
+		}
+		return walkJSONPath(child, rest)
+	}
+}
+
+// jsonValueString renders a JSON value for use in text, strings without quotes
+func jsonValueString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return compactJSON(v)
+}
+
+// deleteJSONPath removes every value path matches from a decoded JSON document and returns the document,
+// which is only replaced when an array element is removed
+func deleteJSONPath(node any, segments []jsonPathSegment) any {
+	if len(segments) == 0 {
+		return node
+	}
+	seg, rest := segments[0], segments[1:]
+
+	switch n := node.(type) {
+	case map[string]any:
+		switch {
+		case seg.Wildcard && len(rest) == 0:
+			clear(n)
+		case seg.Wildcard:
+			for k, child := range n {
+				n[k] = deleteJSONPath(child, rest)
+			}
+		case seg.IsIndex:
+		case len(rest) == 0:
+			delete(n, seg.Key)
+		default:
+			if child, ok := n[seg.Key]; ok {
+				n[seg.Key] = deleteJSONPath(child, rest)
+			}
+		}
+	case []any:
+		switch {
+		case seg.Wildcard && len(rest) == 0:
+			return []any{}
+		case seg.Wildcard:
+			for i, child := range n {
+				n[i] = deleteJSONPath(child, rest)
+			}
+		case seg.IsIndex:
+			i := seg.Index
+			if i < 0 {
+				i += len(n)
+			}
+			if i < 0 || i >= len(n) {
+				break
+			}
+			if len(rest) == 0 {
+				return append(n[:i:i], n[i+1:]...)
+			}
+			n[i] = deleteJSONPath(n[i], rest)
+		}
+	}
+	return node
+}
+
+// checkJSONPointer reports whether pointer is a JSON Pointer, empty for the whole document or /a/0 with ~0 and ~1 escapes
+func checkJSONPointer(pointer string) error {
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return fmt.Errorf("invalid JSON Pointer %q, must start with /", pointer)
+	}
+	return nil
+}
+
+// lookupJSONPointer evaluates a JSON Pointer against a decoded JSON document, ok is false when nothing is there
+func lookupJSONPointer(doc any, pointer string) (value any, ok bool) {
+	if pointer == "" {
+		return doc, true
+	}
+
+	value = doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := value.(type) {
+		case map[string]any:
+			if value, ok = node[token]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) || (len(token) > 1 && token[0] == '0') {
+				return nil, false
+			}
+			value = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+)
+
+// jsonReport is the machine-readable report written by -format=json
+type jsonReport struct {
+	Request  jsonReportRequest  `json:"request"`
+	Response jsonReportResponse `json:"response"`
+	Failures []string           `json:"failures,omitempty"`
+}
+
+type jsonReportRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+
+	Variables map[string]string `json:"variables,omitempty"`
+	// CookiesSent are the names of the cookies sent with the request, set with -cookies
+	CookiesSent []string `json:"cookies_sent,omitempty"`
+}
+
+type jsonReportResponse struct {
+	Status     string              `json:"status"`
+	StatusCode int                 `json:"status_code"`
+	Protocol   string              `json:"protocol"`
+	ALPN       string              `json:"alpn"`
+	TLSVersion string              `json:"tls_version,omitempty"`
+	TLSResumed *bool               `json:"tls_resumed,omitempty"`
+	LatencyMS  float64             `json:"latency_ms"`
+	Headers    map[string][]string `json:"headers"`
+	Trailers   map[string][]string `json:"trailers,omitempty"`
+	Body       string              `json:"body"`
+	BodyBytes  int                 `json:"body_bytes"`
+	SHA256     string              `json:"sha256,omitempty"`
+	Hash       string              `json:"response_hash,omitempty"`
+	TraceID    string              `json:"trace_id,omitempty"`
+	Truncated  bool                `json:"truncated,omitempty"`
+	BodyGzip   *gzipSidecar        `json:"body_gzip,omitempty"`
+	ReadError  string              `json:"read_error,omitempty"`
+	Events     []SSEEvent          `json:"events,omitempty"`
+}
+
+// renderJSONReport writes the report as a single JSON document
+func renderJSONReport(file io.Writer, reqData RequestData, result *Result, opts ReportOptions) error {
+	response := result.Response
+	body, fullSize, truncated := reportBody(result, opts)
+
+	headers := make(map[string]string, len(reqData.Headers))
+	for k, v := range reqData.Headers {
+		headers[k] = redactHeader(k, v, opts.Redact)
+	}
+
+	report := jsonReport{
+		Request: jsonReportRequest{
+			Method:  reqData.Method,
+			URL:     reqData.URL,
+			Headers: headers,
+			Body:    reqData.Body,
+		},
+		Response: jsonReportResponse{
+			Status:     response.Status,
+			StatusCode: response.StatusCode,
+			Protocol:   response.Proto,
+			ALPN:       negotiatedProtocol(response),
+			LatencyMS:  milliseconds(result.Latency),
+			Headers:    redactHeaderValues(response.Header, opts.Redact),
+			Body:       string(body),
+			BodyBytes:  fullSize,
+			Truncated:  truncated,
+			Events:     result.Events,
+		},
+		Failures: result.Failures,
+	}
+	if reqData.Variables != nil {
+		report.Request.Variables = make(map[string]string, len(reqData.Variables))
+		for name, value := range reqData.Variables {
+			report.Request.Variables[name] = redactVariable(name, value)
+		}
+	}
+	if opts.CookiesSent {
+		report.Request.CookiesSent = sentCookieNames(result.SentHeaders)
+	}
+	if opts.TraceIDHeader != "" {
+		report.Response.TraceID = response.Header.Get(opts.TraceIDHeader)
+	}
+	if response.TLS != nil {
+		report.Response.TLSVersion = tls.VersionName(response.TLS.Version)
+		report.Response.TLSResumed = &response.TLS.DidResume
+	}
+	if len(response.Trailer) > 0 {
+		report.Response.Trailers = response.Trailer
+	}
+	if opts.bodySidecar != nil {
+		report.Response.Body, report.Response.Truncated = "", false
+		report.Response.BodyGzip = opts.bodySidecar
+	}
+	if opts.SHA256 {
+		report.Response.SHA256 = result.SHA256
+	}
+	if opts.ResponseHash {
+		report.Response.Hash = responseHash(result)
+	}
+	if result.ReadErr != nil {
+		report.Response.ReadError = result.ReadErr.Error()
+	}
+
+	// Compact reports are a single line each, so they can be collected as NDJSON
+	encoder := json.NewEncoder(file)
+	if !opts.CompactJSON {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(report)
+}
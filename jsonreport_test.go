@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatWritesTxtAndJSONReports(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-format", "txt,json", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if report := readReport(t, filepath.Join(dir, "out|*.txt")); !strings.Contains(report, "Response Status: 200 OK") {
+		t.Errorf("txt report is missing the status:\n%s", report)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal([]byte(readReport(t, filepath.Join(dir, "out|*.json"))), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Response.StatusCode != http.StatusOK || report.Response.Body != "hello" {
+		t.Errorf("json report has status %d and body %q, want 200 and hello", report.Response.StatusCode, report.Response.Body)
+	}
+}
+
+func TestCompactJSONWritesOneLine(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{\n  \"items\": [1, 2]\n}\n"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-format", "json", "-compact-json", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	report := readReport(t, filepath.Join(dir, "out|*.json"))
+	line, found := strings.CutSuffix(report, "\n")
+	if !found || strings.Contains(line, "\n") || !json.Valid([]byte(line)) {
+		t.Fatalf("report is not a single line of valid JSON:\n%s", report)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(line)); err != nil || compact.String() != line {
+		t.Errorf("report has extraneous whitespace, compacted it is\n%s\nnot\n%s", compact.String(), line)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// keepAliveProbeStart is the first idle time -keep-alive-timeout tries, it doubles until the server closes the connection
+const keepAliveProbeStart = 500 * time.Millisecond
+
+// keepAliveProbeResolution is how close the kept and closed idle times get before the probe stops
+const keepAliveProbeResolution = 250 * time.Millisecond
+
+// keepAliveStep is one idle time the probe waited and whether the connection survived it
+type keepAliveStep struct {
+	Idle   time.Duration
+	Reused bool
+	Err    error
+}
+
+// keepAliveProbe measures how long the server keeps an idle connection open. Each step opens
+// a connection, idles, then sends the request again and checks whether the connection was reused.
+// Idle times double until the server closes the connection, then the gap is bisected.
+func (r *Runner) keepAliveProbe(reqData RequestData, outputPath string) Outcome {
+	outcome := Outcome{Request: reqData}
+
+	var steps []keepAliveStep
+	step := func(idle time.Duration) (bool, error) {
+		r.Client.CloseIdleConnections()
+		start := time.Now()
+		result, err := Execute(context.Background(), r.Client, reqData)
+		outcome.Attempts = append(outcome.Attempts, NewAttempt(len(outcome.Attempts)+1, result, time.Since(start), err))
+		if err != nil {
+			return false, err
+		}
+		outcome.Result = result
+
+		time.Sleep(idle)
+		start = time.Now()
+		result, err = Execute(context.Background(), r.Client, reqData)
+		outcome.Attempts = append(outcome.Attempts, NewAttempt(len(outcome.Attempts)+1, result, time.Since(start), err))
+
+		// A request failing on the reused connection means the server closed it as it was sent
+		reused := err == nil && result.ConnReused
+		steps = append(steps, keepAliveStep{Idle: idle, Reused: reused, Err: err})
+		if reused {
+			printInfo("keep-alive", "idle", idle, "reused")
+		} else {
+			printInfo("keep-alive", "idle", idle, "closed")
+		}
+		return reused, nil
+	}
+
+	// kept is the longest idle time the connection survived, closed the shortest it did not
+	var kept, closed time.Duration
+	reused, err := step(0)
+	if err == nil && !reused {
+		err = fmt.Errorf("server does not keep connections alive")
+	}
+	for idle := keepAliveProbeStart; err == nil && closed == 0 && kept < r.KeepAliveTimeout; idle *= 2 {
+		idle = min(idle, r.KeepAliveTimeout)
+		if reused, err = step(idle); reused {
+			kept = idle
+		} else {
+			closed = idle
+		}
+	}
+	for err == nil && closed != 0 && closed-kept > keepAliveProbeResolution {
+		idle := kept + (closed-kept)/2
+		if reused, err = step(idle); reused {
+			kept = idle
+		} else {
+			closed = idle
+		}
+	}
+	if err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	if closed == 0 {
+		printInfo("keep-alive:", reqData.URL, "kept the connection open for", kept)
+	} else {
+		printInfo("keep-alive:", reqData.URL, "closed the connection after", kept, "to", closed)
+	}
+
+	if err := GenerateKeepAliveReport(r.Report.sink(), outputPath, reqData, steps, kept, closed); err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	outcome.Passed = true
+	return outcome
+}
+
+// GenerateKeepAliveReport writes every idle time the probe tried and the range the server closed the connection in.
+// closed is 0 when the connection survived every idle time up to kept.
+func GenerateKeepAliveReport(sink ReportSink, outputPath string, reqData RequestData, steps []keepAliveStep, kept, closed time.Duration) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-keepalive.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request URL: %s\nSteps: %d\n", reqData.URL, len(steps)))
+	if err != nil {
+		return err
+	}
+
+	if closed == 0 {
+		_, err = io.WriteString(file, fmt.Sprintf("Idle Timeout: longer than %s\n\n", kept))
+	} else {
+		_, err = io.WriteString(file, fmt.Sprintf("Idle Timeout: between %s and %s\n\n", kept, closed))
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, step := range steps {
+		state := "reused"
+		if step.Err != nil {
+			state = "closed, error: " + step.Err.Error()
+		} else if !step.Reused {
+			state = "closed"
+		}
+		_, err = io.WriteString(file, fmt.Sprintf("Idle %s: %s\n", step.Idle, state))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestKeepAliveTimeoutProbeFindsIdleTimeout(t *testing.T) {
+	const idleTimeout = 700 * time.Millisecond
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.IdleTimeout = idleTimeout
+	srv.Start()
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, KeepAliveTimeout: 10 * time.Second, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("probe failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, "-keepalive.txt")
+	m := regexp.MustCompile(`Idle Timeout: between (\S+) and (\S+)\n`).FindStringSubmatch(report)
+	if m == nil {
+		t.Fatalf("report does not give the range the server closed the connection in:\n%s", report)
+	}
+	kept, _ := time.ParseDuration(m[1])
+	closed, _ := time.ParseDuration(m[2])
+	if kept > idleTimeout || closed < idleTimeout || closed-kept > 500*time.Millisecond {
+		t.Errorf("detected idle timeout between %s and %s, want a narrow range around %s:\n%s", kept, closed, idleTimeout, report)
+	}
+}
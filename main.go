@@ -7,14 +7,25 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -24,74 +35,106 @@ type RequestData struct {
 	URL     string
 	Headers map[string]string
 	Body    string
-}
+	// Trailers are sent after the body, which is then sent chunked, set by # @trailer
+	Trailers map[string]string
 
-// ReadHTTPFile parses the .HTTP file and returns RequestData
-func ReadHTTPFile(filePath string) (RequestData, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return RequestData{}, err
-	}
-	defer file.Close()
+	// Delay is how long to wait before sending the request, set by # @delay
+	Delay time.Duration
+	// SaveBody is a path the response body is written to, set by # @save-body
+	SaveBody string
+	// Output names the reports of the request, in the -output directory, set by # @output
+	Output string
 
-	scanner := bufio.NewScanner(file)
-	reqData := RequestData{
-		Headers: make(map[string]string),
-	}
+	// ExpectStatus is the status code the request must return, set by # @expect
+	ExpectStatus int
 
-	// Read the first line for method and URL
-	if scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) != 2 {
-			return RequestData{}, fmt.Errorf("invalid request line")
-		}
-		reqData.Method, reqData.URL = parts[0], parts[1]
-	}
+	// Name identifies the request for @on-success and @on-failure, which name the request to run next
+	Name      string
+	OnSuccess string
+	OnFailure string
 
-	// Read headers
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			break
-		}
-		parts := strings.SplitN(line, ": ", 2)
-		if len(parts) != 2 {
-			continue // Skip invalid header
-		}
-		reqData.Headers[parts[0]] = parts[1]
-	}
+	// Captures store response values as variables for later requests, set by # @capture and # @capture-header
+	Captures []Capture
+	// Source is the file the request was read from, which selects its variable scope
+	Source string
+	// Variables are the variables visible to the request, recorded for its report by -capture-all
+	Variables map[string]string
+	// Row holds the fields of the -data-rows row the request was generated for
+	Row map[string]string
+}
 
-	// Read body (if any)
-	var bodyLines []string
-	for scanner.Scan() {
-		bodyLines = append(bodyLines, scanner.Text())
-	}
-	reqData.Body = strings.Join(bodyLines, "\n")
+// Capture names a variable set from a JSONPath of the response body, or from a response header when Header is set
+type Capture struct {
+	Name   string
+	Path   string
+	Header string
+}
 
-	if err := scanner.Err(); err != nil {
-		return RequestData{}, err
+// NewURLRequest synthesizes RequestData for a bare URL without a source file
+func NewURLRequest(url string) RequestData {
+	return RequestData{
+		Method:  http.MethodGet,
+		URL:     url,
+		Headers: make(map[string]string),
 	}
+}
 
-	return reqData, nil
+// warnRequest prints warnings for requests that are sent but likely not as intended
+func warnRequest(reqData RequestData) {
+	// Placeholders are only expanded when the request is sent, {{HOST}}/path may well have a scheme by then
+	if u, err := url.Parse(reqData.URL); err == nil && (u.Scheme == "" || u.Host == "") && !strings.Contains(reqData.URL, "{{") {
+		printWarning("URL", reqData.URL, "has no scheme or host")
+	}
+	if reqData.Body != "" && (reqData.Method == http.MethodGet || reqData.Method == http.MethodHead) {
+		printWarning(reqData.Method, reqData.URL, "has a body, which many servers ignore")
+	}
 }
 
 // SendRequest sends an HTTP request based on RequestData
-func SendRequest(reqData RequestData, retryCount int, sleepSec int) (*http.Response, error) {
-	client := &http.Client{}
+func SendRequest(ctx context.Context, client *http.Client, reqData RequestData) (*http.Response, error) {
 	var resp *http.Response
 	var err error
 	b := bytes.NewBufferString(reqData.Body)
 
-	req, err := http.NewRequest(reqData.Method, reqData.URL, b)
+	req, err := http.NewRequestWithContext(ctx, reqData.Method, reqData.URL, b)
 	if err != nil {
 		return nil, err
 	}
 
+	preserveCase, _ := ctx.Value(preserveHeaderCaseKey{}).(bool)
 	for k, v := range reqData.Headers {
+		// net/http ignores a Host entry in the header map, the request Host field sets it instead
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		// The transport writes header map keys as they are, only Set canonicalizes them
+		if preserveCase && !slices.ContainsFunc(transportHeaders, func(name string) bool { return strings.EqualFold(name, k) }) {
+			req.Header[k] = []string{v}
+			continue
+		}
 		req.Header.Set(k, v)
 	}
 
+	// An empty User-Agent stops the transport from writing its default one
+	if minimal, _ := ctx.Value(minimalHeadersKey{}).(bool); minimal && !hasHeader(reqData.Headers, "User-Agent") {
+		req.Header["User-Agent"] = []string{""}
+	}
+
+	// Trailers are only sent with a chunked body, which an unknown length selects
+	if len(reqData.Trailers) > 0 {
+		req.Trailer = make(http.Header, len(reqData.Trailers))
+		for k, v := range reqData.Trailers {
+			req.Trailer.Set(k, v)
+		}
+		req.ContentLength = -1
+	}
+
+	// GetBody still returns the unwrapped buffer, which net/http resends when it follows a 307 or 308
+	if interval, ok := ctx.Value(progressKey{}).(time.Duration); ok && req.ContentLength > 0 {
+		req.Body = &progressReader{body: req.Body, total: req.ContentLength, interval: interval, last: time.Now(), report: printProgress(reqData.URL)}
+	}
+
 	resp, err = client.Do(req)
 	if err != nil {
 		return nil, err
@@ -100,96 +143,1662 @@ func SendRequest(reqData RequestData, retryCount int, sleepSec int) (*http.Respo
 	return resp, err
 }
 
-// GenerateReport creates a report of the request and response
-func GenerateReport(outputPath string, reqData RequestData, response *http.Response) error {
-	file, err := os.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-status:" + fmt.Sprintf("%v", response.StatusCode) + ".txt")
-	if err != nil {
-		return err
+// ReportOptions controls how GenerateReport renders a response
+type ReportOptions struct {
+	// GRPCHexDump adds a hex dump of each gRPC-Web data frame
+	GRPCHexDump bool
+	// HexDump writes the response body as an offset, hex and ASCII dump
+	HexDump bool
+	// MaxResponseBytes truncates the reported response body, 0 means no limit
+	MaxResponseBytes int64
+	// JQ replaces JSON response bodies with the output of a -jq expression
+	JQ *jqFilter
+	// TraceIDHeader is the response header holding the server trace ID, shown near the top of reports
+	TraceIDHeader string
+	// NameTemplate names reports from request fields such as {method}-{host}-{status}, in the -output directory
+	NameTemplate string
+	// NormalizeJSON writes JSON bodies with sorted keys and no insignificant whitespace
+	NormalizeJSON bool
+	// CompactJSON writes json format reports on a single line instead of indented
+	CompactJSON bool
+	// SHA256 adds the hex digest of the response body
+	SHA256 bool
+	// CertExpiry adds when the server certificate expires and the days left
+	CertExpiry bool
+	// CookiesSent adds the names of the cookies sent with the request
+	CookiesSent bool
+	// ResponseHash adds a hash of the status and normalized body for detecting changed responses across runs
+	ResponseHash bool
+	// GzipBody stores the response body gzipped in a .txt.gz sidecar instead of in the report
+	GzipBody bool
+	// DumpRaw appends the raw request and response as they went over the wire
+	DumpRaw bool
+	// Redact lists the header names whose values are written as *** in reports
+	Redact []string
+	// ResponseOnly leaves the request out of text reports and adds the response headers and timing
+	ResponseOnly bool
+	// Include limits text reports to these sections when set, Exclude drops sections from them
+	Include []string
+	Exclude []string
+	// Formats lists the report formats written for each response, txt when empty
+	Formats []string
+	// Encoding is the character encoding reports are written in, UTF-8 when empty
+	Encoding string
+	// Sink receives the reports, files named after the output path are written when it is nil
+	Sink ReportSink
+
+	// SortReports writes reports into pass/ and fail/ directories next to the output path
+	SortReports bool
+	// FailuresOnly writes full reports for failed requests only, passing ones get a one line record
+	FailuresOnly bool
+	// Secrets are the -secrets-file values written as *** in reports
+	Secrets []string
+	// GeneratedHeaders is the number of -gen-headers headers sent, reported with whether the server accepted them
+	GeneratedHeaders int
+
+	// bodySidecar is set by GenerateReport once the gzipped body has been written
+	bodySidecar *gzipSidecar
+	// failed marks a response the runner treats as failed although no assertion failed, such as a 5xx
+	failed bool
+}
+
+// reportSections are the sections of a text report that -report-include and -report-exclude select
+var reportSections = []string{"request", "request-headers", "request-body", "variables", "status", "response-headers", "timing", "response-body", "events", "trailers", "assertions", "raw"}
+
+// optionalSections are only written when -report-include or -response-only asks for them
+var optionalSections = []string{"response-headers", "timing"}
+
+// requestSections are the sections -response-only leaves out
+var requestSections = []string{"request", "request-headers", "request-body"}
+
+// includes reports whether the text report should contain the named section
+func (o ReportOptions) includes(section string) bool {
+	switch {
+	case len(o.Include) > 0:
+		if !slices.Contains(o.Include, section) {
+			return false
+		}
+	case o.ResponseOnly:
+		if slices.Contains(requestSections, section) {
+			return false
+		}
+	case slices.Contains(optionalSections, section):
+		return false
 	}
-	defer file.Close()
+	return !slices.Contains(o.Exclude, section)
+}
 
-	_, err = file.WriteString(fmt.Sprintf("Request Method: %s\nRequest URL: %s\n\n", reqData.Method, reqData.URL))
-	if err != nil {
-		return err
+// parseReportSections splits a comma-separated list of report sections, rejecting unknown names
+func parseReportSections(list string) ([]string, error) {
+	var sections []string
+	for _, section := range strings.Split(list, ",") {
+		section = strings.TrimSpace(section)
+		if section == "" {
+			continue
+		}
+		if !slices.Contains(reportSections, section) {
+			return nil, fmt.Errorf("unknown report section %q, expected one of %s", section, strings.Join(reportSections, ", "))
+		}
+		sections = append(sections, section)
 	}
+	return sections, nil
+}
 
-	_, err = file.WriteString("Request Headers:\n")
+// reportBody returns the response body as reported, normalized and truncated, with its size before truncation
+func reportBody(result *Result, opts ReportOptions) ([]byte, int, bool) {
+	body := result.Body
+	// A hex dump shows the bytes as they were received
+	if !opts.HexDump {
+		body = decodeCharset(body, result.Response.Header.Get("Content-Type"))
+	}
+	if opts.JQ != nil && json.Valid(body) {
+		out, err := opts.JQ.apply(body)
+		if err != nil {
+			out = []byte(err.Error() + "\n")
+		}
+		body = out
+	}
+	if opts.NormalizeJSON {
+		body = normalizeJSON(body)
+	}
+	fullSize := len(body)
+	truncated := opts.MaxResponseBytes > 0 && int64(fullSize) > opts.MaxResponseBytes
+	if truncated {
+		body = body[:opts.MaxResponseBytes]
+	}
+	return body, fullSize, truncated
+}
+
+// sink returns the configured report sink, defaulting to plain files
+func (o ReportOptions) sink() ReportSink {
+	if o.Sink == nil {
+		return FileSink{}
+	}
+	return o.Sink
+}
+
+// reportRenderers write a report in each -format, the key is also the file extension
+var reportRenderers = map[string]func(file io.Writer, reqData RequestData, result *Result, opts ReportOptions) error{
+	"txt":  renderTextReport,
+	"json": renderJSONReport,
+}
+
+// GenerateReport creates a report of the request and response in every configured format
+func GenerateReport(outputPath string, reqData RequestData, result *Result, opts ReportOptions) error {
+	sink := opts.sink()
+	if opts.SortReports {
+		dir := "pass"
+		if opts.failed || result.Failed() {
+			dir = "fail"
+		}
+		outputPath = filepath.Join(filepath.Dir(outputPath), dir, filepath.Base(outputPath))
+	}
+	name := outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-status:" + fmt.Sprintf("%v", result.Response.StatusCode)
+	if opts.NameTemplate != "" {
+		name = filepath.Join(filepath.Dir(outputPath), reportName(opts.NameTemplate, reqData, result.Response.StatusCode, time.Now()))
+	}
 
+	formats := opts.Formats
+	if len(formats) == 0 {
+		formats = []string{"txt"}
+	}
+
+	// Passing requests only get a one line record when full reports are kept for failures
+	minimal := opts.FailuresOnly && !opts.failed && !result.Failed()
+	if minimal {
+		formats = []string{"txt"}
+		opts.GzipBody = false
+	}
+
+	encode, err := reportEncoding(opts.Encoding)
 	if err != nil {
 		return err
 	}
 
-	for k, v := range reqData.Headers {
-		_, err = file.WriteString(fmt.Sprintf("%s: %s\n", k, v))
+	if opts.GzipBody {
+		sidecar, err := writeGzipSidecar(sink, name+".txt.gz", result.Body)
 		if err != nil {
 			return err
 		}
+		opts.bodySidecar = sidecar
 	}
 
-	_, err = file.WriteString(fmt.Sprintf("\nRequest Body:\n%s\n\n", reqData.Body))
-	if err != nil {
-		return err
-	}
+	for _, format := range formats {
+		render, ok := reportRenderers[format]
+		if !ok {
+			return fmt.Errorf("unknown report format %q", format)
+		}
+		if minimal {
+			render = renderPassedRecord
+		}
 
-	responseBody, err := io.ReadAll(response.Body)
-	if err != nil {
-		return err
+		file, err := sink.Create(name + "." + format)
+		if err != nil {
+			return err
+		}
+
+		if encode != nil || len(opts.Secrets) > 0 {
+			var buf bytes.Buffer
+			err = render(&buf, reqData, result, opts)
+			if err == nil {
+				// Secrets are masked wherever they show up, including responses that echo them
+				out := []byte(maskSecrets(buf.String(), opts.Secrets))
+				if encode != nil {
+					out = encode(out)
+				}
+				_, err = file.Write(out)
+			}
+		} else {
+			err = render(file, reqData, result, opts)
+		}
+		closeErr := file.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
 	}
-	response.Body.Close()
 
-	_, err = file.WriteString(fmt.Sprintf("Response Status: %s\nResponse Body:\n%s\n", response.Status, string(responseBody)))
+	return nil
+}
+
+// renderPassedRecord writes the one line record of a passing request under -response-save-on-failure-only
+func renderPassedRecord(file io.Writer, reqData RequestData, result *Result, _ ReportOptions) error {
+	_, err := io.WriteString(file, fmt.Sprintf("Passed: %s %s -> %s (%.1fms)\n", reqData.Method, reqData.URL, result.Response.Status, milliseconds(result.Latency)))
 	return err
 }
 
+// renderTextReport writes the human-readable report
+func renderTextReport(file io.Writer, reqData RequestData, result *Result, opts ReportOptions) error {
+	response := result.Response
+
+	var err error
+
+	if opts.includes("request") {
+		_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nRequest URL: %s\n\n", reqData.Method, reqData.URL))
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.includes("request-headers") {
+		_, err = io.WriteString(file, "Request Headers:\n")
+
+		if err != nil {
+			return err
+		}
+
+		for k, v := range reqData.Headers {
+			_, err = io.WriteString(file, fmt.Sprintf("%s: %s\n", k, redactHeader(k, v, opts.Redact)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.includes("request-body") {
+		_, err = io.WriteString(file, fmt.Sprintf("\nRequest Body:\n%s\n\n", reqData.Body))
+		if err != nil {
+			return err
+		}
+	}
+
+	if reqData.Variables != nil && opts.includes("variables") {
+		_, err = io.WriteString(file, "Variables:\n")
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(reqData.Variables))
+		for name := range reqData.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			_, err = io.WriteString(file, fmt.Sprintf("%s = %s\n", name, redactVariable(name, reqData.Variables[name])))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = io.WriteString(file, "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	responseBody, fullSize, truncated := reportBody(result, opts)
+	body := string(responseBody)
+	if contentType := response.Header.Get("Content-Type"); isGRPCWeb(contentType) {
+		if frames, err := parseGRPCWebFrames(contentType, responseBody); err == nil {
+			body = formatGRPCWebFrames(frames, opts.GRPCHexDump)
+		}
+	} else if opts.HexDump {
+		body = hex.Dump(responseBody)
+	}
+	if opts.bodySidecar != nil {
+		body, truncated = opts.bodySidecar.String(), false
+	}
+
+	if opts.includes("status") {
+		_, err = io.WriteString(file, fmt.Sprintf("Response Status: %s\nProtocol: %s\nALPN: %s\n", response.Status, response.Proto, negotiatedProtocol(response)))
+		if err != nil {
+			return err
+		}
+
+		if response.TLS != nil {
+			_, err = io.WriteString(file, fmt.Sprintf("TLS Version: %s\nTLS Session Resumed: %t\n", tls.VersionName(response.TLS.Version), response.TLS.DidResume))
+			if err != nil {
+				return err
+			}
+		}
+
+		if notAfter, days, ok := certExpiry(response.TLS, time.Now()); ok && opts.CertExpiry {
+			_, err = io.WriteString(file, fmt.Sprintf("Certificate Expires: %s (%d days)\n", notAfter.Format(time.RFC3339), days))
+			if err != nil {
+				return err
+			}
+		}
+
+		if names := sentCookieNames(result.SentHeaders); len(names) > 0 && opts.CookiesSent {
+			_, err = io.WriteString(file, fmt.Sprintf("Cookies Sent: %s\n", strings.Join(names, ", ")))
+			if err != nil {
+				return err
+			}
+		}
+
+		if hasHeader(reqData.Headers, "Expect") {
+			_, err = io.WriteString(file, fmt.Sprintf("100 Continue Received: %t\n", result.Got100Continue))
+			if err != nil {
+				return err
+			}
+		}
+
+		if id := response.Header.Get(opts.TraceIDHeader); opts.TraceIDHeader != "" && id != "" {
+			_, err = io.WriteString(file, fmt.Sprintf("Trace ID (%s): %s\n", opts.TraceIDHeader, id))
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.Hedge != "" {
+			_, err = io.WriteString(file, fmt.Sprintf("Hedge Winner: %s\n", result.Hedge))
+			if err != nil {
+				return err
+			}
+		}
+
+		if opts.GeneratedHeaders > 0 {
+			_, err = io.WriteString(file, fmt.Sprintf("Generated Headers: %d (accepted: %t)\n", opts.GeneratedHeaders, response.StatusCode < 400))
+			if err != nil {
+				return err
+			}
+		}
+
+		if opts.SHA256 {
+			_, err = io.WriteString(file, fmt.Sprintf("Response SHA-256: %s\n", result.SHA256))
+			if err != nil {
+				return err
+			}
+		}
+
+		if opts.ResponseHash {
+			_, err = io.WriteString(file, fmt.Sprintf("Response Hash: %s\n", responseHash(result)))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.includes("response-headers") {
+		_, err = io.WriteString(file, "Response Headers:\n")
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(response.Header))
+		for name := range response.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			for _, v := range response.Header[name] {
+				_, err = io.WriteString(file, fmt.Sprintf("%s: %s\n", name, redactHeader(name, v, opts.Redact)))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if opts.includes("timing") {
+		_, err = io.WriteString(file, fmt.Sprintf("Latency: %.1fms\n", milliseconds(result.Latency)))
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.includes("response-body") {
+		_, err = io.WriteString(file, fmt.Sprintf("Response Body:\n%s\n", body))
+		if err != nil {
+			return err
+		}
+
+		if result.ReadErr != nil {
+			_, err = io.WriteString(file, fmt.Sprintf("[response body incomplete: read timed out after %d bytes: %v]\n", len(result.Body), result.ReadErr))
+			if err != nil {
+				return err
+			}
+		}
+
+		if truncated {
+			_, err = io.WriteString(file, fmt.Sprintf("[response body truncated: showing %d of %d bytes]\n", len(responseBody), fullSize))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(result.Events) > 0 && opts.includes("events") {
+		_, err = io.WriteString(file, fmt.Sprintf("\nServer-Sent Events: %d\n", len(result.Events)))
+		if err != nil {
+			return err
+		}
+
+		for i, event := range result.Events {
+			_, err = io.WriteString(file, fmt.Sprintf("[%d] event: %s\n", i+1, event.Event))
+			if err != nil {
+				return err
+			}
+			if event.ID != "" {
+				_, err = io.WriteString(file, fmt.Sprintf("id: %s\n", event.ID))
+				if err != nil {
+					return err
+				}
+			}
+			_, err = io.WriteString(file, fmt.Sprintf("data: %s\n", strings.ReplaceAll(event.Data, "\n", "\ndata: ")))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Trailers are only populated once the body has been read to EOF
+	if len(response.Trailer) > 0 && opts.includes("trailers") {
+		_, err = io.WriteString(file, "\nResponse Trailers:\n")
+		if err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(response.Trailer))
+		for name := range response.Trailer {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			_, err = io.WriteString(file, fmt.Sprintf("%s: %s\n", name, strings.Join(response.Trailer[name], ", ")))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if result.Failed() && opts.includes("assertions") {
+		_, err = io.WriteString(file, "\nAssertion Failures:\n")
+		if err != nil {
+			return err
+		}
+
+		for _, failure := range result.Failures {
+			_, err = io.WriteString(file, fmt.Sprintf("- %s\n", failure))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.DumpRaw && opts.includes("raw") {
+		raw, err := dumpRaw(reqData, result, opts.Redact)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(file, fmt.Sprintf("\nRaw Exchange:\n%s\n", raw))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// negotiatedProtocol describes how the response protocol was chosen
+func negotiatedProtocol(response *http.Response) string {
+	if response.TLS == nil {
+		if response.ProtoMajor == 2 {
+			return "none (h2c prior knowledge)"
+		}
+		return "none (cleartext)"
+	}
+	if response.TLS.NegotiatedProtocol == "" {
+		return "none (no protocol negotiated)"
+	}
+	return response.TLS.NegotiatedProtocol + " (negotiated via ALPN)"
+}
+
 var (
 	defaultRetry = 1
 	defaultSleep = 0
 )
 
 func main() {
-	source := flag.String("source", "", "Path to .http file")
+	source := flag.String("source", "", "Path or http(s):// URL of a .http file, or a comma separated list of them")
+	var envFiles envFileFlag
+	flag.Var(&envFiles, "env-file", "Dotenv file of NAME=value variables for {{NAME}} placeholders, defaults to .env in the working directory when it exists (repeatable, later files override earlier ones, process environment variables override the variables the files define)")
+	secretsFile := flag.String("secrets-file", "", "File of NAME=value lines that {{secret:NAME}} placeholders resolve from, the values are written as *** in reports")
+	captureAll := flag.Bool("capture-all", false, "Record the variables visible to each request in its report, sensitive names are written as ***")
+	sharedVars := flag.Bool("shared-vars", false, "Share variables captured with # @capture across all -source files instead of scoping them per file")
+	formatIn := flag.String("format-in", "http", "Format of the source file: http or har")
+	rawURL := flag.String("url", "", "URL to request directly instead of reading a .http file")
+	dryValidate := flag.String("dry-validate", "", "Run the assertion flags against the response recorded in a txt or json report instead of sending requests")
+	replayReport := flag.String("replay-report", "", "Resend the request recorded in a txt or json report written by an earlier run")
+	method := flag.String("method", "", "Override the request method")
+	pathFlag := flag.String("path", "", "Replace the path and query of every request URL, keeping its scheme and host")
+	bodyFile := flag.String("body-file", "", "Read the request body as raw bytes from this file")
+	bodyOut := flag.String("body-out", "", "Write the response body to this file, like # @save-body for requests that do not set one")
+	resume := flag.Bool("resume", false, "Continue a partial -body-out or # @save-body file with a Range request for the missing bytes")
+	bodyTemplateFile := flag.String("body-template-file", "", "Read the request body from this template, {{include \"fragment.json\"}} inlines a file relative to it")
+	headersFile := flag.String("headers-file", "", "File of \"Name: Value\" lines added to every request unless the request sets them")
+	hostHeadersFile := flag.String("host-headers-file", "", "File of [host pattern] sections with \"Name: Value\" lines added to requests to matching hosts")
+	headers := headerFlag{}
+	flag.Var(headers, "header", "Add a request header, format \"Name: Value\" (repeatable)")
+	var methodHeaders methodHeaderFlag
+	flag.Var(&methodHeaders, "method-header", "Add a header only to requests with one of the methods, format \"POST,PUT Name: Value\" (repeatable)")
+	certExpiryWarn := flag.Duration("cert-expiry-warn", 0, "Warn when the server certificate expires within this window, such as 720h, and add its expiry to reports, -fail-on-warnings fails the run on it")
+	minimalHeaders := flag.Bool("minimal-headers", false, "Send only the headers in the request plus the ones HTTP requires, without Go's User-Agent and Accept-Encoding")
+	preserveHeaderCase := flag.Bool("preserve-header-case", false, "Send header names with the casing they have in the request instead of canonicalizing them, HTTP/1 only")
+	var genHeaders genHeadersFlag
+	flag.Var(&genHeaders, "gen-headers", "Add this many synthetic X-Generated-N headers of this many bytes to every request, format count,size")
 	output := flag.String("output", "", "Path to output file")
 	retry := flag.Int("retry", 0, "Number of retries")
+	teePath := flag.String("tee", "", "Write the full response body of every request to this file and to stdout")
+	retryLogPath := flag.String("retry-log", "", "Write the status or error of every attempt of requests that fail after their last retry to this file")
+	retriesPerStatus := retriesPerStatusFlag{}
+	flag.Var(retriesPerStatus, "retries-per-status", "Retry counts for responses with these statuses instead of -retry, such as 429:10,500:2, listed statuses below 500 are retried too")
 	sleep := flag.Int("sleep", 0, "Sleep time between retries")
+	retryOnBodyRegex := flag.String("retry-on-body-regex", "", "Retry responses whose body matches this regular expression, such as '\"status\": *\"pending\"'")
+	retryOnEmpty := flag.Bool("retry-on-empty", false, "Retry successful responses with an empty body")
+	idempotentOnly := flag.Bool("idempotent-only", false, "Do not retry POST and PATCH requests unless they send an Idempotency-Key, see -idempotency")
+	retryBudget := flag.Int("retry-budget", 0, "Maximum number of retries across all requests of a run, 0 means no limit")
+	backoff := flag.String("backoff", "fixed", "Delay between retries: fixed waits -sleep, adaptive waits twice the last attempt latency")
+	retryJitter := flag.Float64("retry-jitter", 0, "Add up to this fraction of the sleep time as random delay between retries")
+	retryJitterSeed := flag.Int64("retry-jitter-seed", 0, "Seed for the retry jitter RNG, defaults to a time based seed")
+	waitFor := flag.Bool("wait-for", false, "Poll the request until it returns -wait-status or -wait-timeout passes")
+	waitStatus := flag.Int("wait-status", http.StatusOK, "Status code that ends -wait-for polling")
+	waitInterval := flag.Duration("wait-interval", time.Second, "Interval between -wait-for polls")
+	waitTimeout := flag.Duration("wait-timeout", 30*time.Second, "How long -wait-for and -wait-for-port poll before giving up")
+	splay := flag.Duration("splay", 0, "Before sending anything, wait a random time up to this long, to spread out runs scheduled at the same time")
+	splaySeed := flag.Int64("splay-seed", 0, "Seed for the -splay RNG, defaults to a time based seed")
+	waitForPort := flag.String("wait-for-port", "", "Before sending anything, poll this host:port every -wait-interval until it accepts TCP connections")
+	grpcHexDump := flag.Bool("grpc-hexdump", false, "Hex dump gRPC-Web data frames in the report")
+	hexDump := flag.Bool("hexdump", false, "Write the response body as a hex and ASCII dump in the report")
+	cacheFile := flag.String("cache-file", "", "Remember ETag/Last-Modified in this file and send conditional requests")
+	expectRedirect := flag.Int("expect-redirect", 0, "Fail requests that do not return this 3xx redirect status, redirects are not followed")
+	expectLocation := flag.String("expect-location", "", "Fail requests whose redirect Location is not this URL, redirects are not followed")
+	expectStatus := flag.Int("expect-status", 0, "Fail requests that do not return this status code, # @expect overrides it per request")
+	errorField := flag.String("error-field", "", "JSONPath of the message to show for failed JSON responses, defaults to $.message then $.error")
+	extract := flag.String("extract", "", "JSON Pointer, such as /data/id, of a value to print from each JSON response and add to the -summary-json")
+	expectSHA256 := flag.String("expect-sha256", "", "Fail requests whose response body does not hash to this hex SHA-256 digest")
+	assertContentTypeFlag := flag.String("assert-content-type", "", "Fail requests whose response Content-Type does not start with this media type, ignoring charset")
+	assertExprFlag := flag.String("assert-expr", "", `Fail requests this expression is false for, such as 'status == 200 && headers["Cache-Control"].param("max-age") < 60', over status, latency (ms), headers, body (JSON) and text`)
+	failOnBodyEmpty := flag.Bool("fail-on-body-empty", false, "Fail the run when a successful response has an empty body")
+	preScript := flag.String("pre-script", "", "Shell command to run before each request, a non-zero exit aborts the request")
+	postScript := flag.String("post-script", "", "Shell command to run after each request")
+	bodyTransform := flag.String("body-transform", "", "Shell command the request body is piped through, its output is sent as the body")
+	metricsFile := flag.String("metrics-file", "", "Write request counts and latency of the run to this path in Prometheus text format")
+	minSuccessRate := flag.Float64("min-success-rate", 0, "Pass the batch when at least this fraction of requests pass, such as 0.95, instead of requiring all of them to")
+	summaryJSON := flag.String("summary-json", "", "Write a JSON summary of the whole batch to this path")
+	canaryURL := flag.String("canary-url", "", "Send -canary-percent of the requests to this scheme://host instead and compare its success and latency with the rest")
+	canaryPercent := flag.Float64("canary-percent", 10, "Percentage of requests -canary-url receives, spread evenly over the batch")
+	mirror := flag.String("mirror", "", "Also send a copy of each request to this scheme://host in the background, its status never fails the run")
+	compareBase := flag.String("compare-base", "", "Also send each request to this scheme://host and write a diff of the responses")
+	ignoreJSONPaths := flag.String("ignore-json-paths", "", "Comma-separated JSONPaths, such as $.timestamp, to remove from both bodies before -compare-base diffs them")
+	diffHeadersPath := flag.String("diff-headers", "", "JSONPath where an echo endpoint reports the headers it received, such as $.headers, to diff them against the sent headers")
+	schemaFile := flag.String("schema", "", "Fail the run when the response body does not match this JSON Schema")
+	openAPIFile := flag.String("openapi", "", "Fail the run when the response status or body does not match the operation for the request method and path in this OpenAPI spec, YAML or JSON")
+	maxRequestBytes := flag.Int64("max-request-bytes", 0, "Reject request bodies larger than this many bytes, 0 means no limit")
+	dataJSON := flag.String("data-json", "", `JSON object whose fields are available as {{.field}} variables, such as '{"id":5}' for {{.id}}`)
+	dataRows := flag.String("data-rows", "", "CSV or JSON file of rows, the requests are sent once per row with its fields as {{variables}}")
+	failOnWarnings := flag.Bool("fail-on-warnings", false, "Exit non-zero when any warning was printed, such as a GET with a body or a URL without a scheme")
+	strictParse := flag.Bool("strict-parse", false, "Fail on unknown # @directives in .http files instead of warning")
+	maxResponseBytes := flag.Int64("max-response-bytes", 0, "Truncate response bodies in reports to this many bytes, 0 means no limit")
+	replayDelay := flag.Duration("replay-delay", 0, "Wait this long between requests of a multi-request file")
+	parallel := flag.Int("parallel", 1, "Send up to this many requests at once")
+	maxConcurrent := flag.Int("max-concurrent-per-run", 0, "With -parallel, keep at most this many requests in flight across the run, started in submission order, 0 means no limit")
+	parallelPerHost := flag.Int("parallel-per-host", 0, "With -parallel, send at most this many requests to one host at once, 0 means no limit")
+	eventsLog := flag.String("events-log", "", "Write request lifecycle events as NDJSON to this path")
+	fuzzHeaders := flag.Bool("fuzz-headers", false, "Send edge-case header variants of each request and report the status of each")
+	smuggleCheck := flag.Bool("smuggle-check", false, "Send requests with conflicting Content-Length and Transfer-Encoding headers and report the responses")
+	paginate := flag.Bool("paginate", false, "Follow next page links and write all pages into one report")
+	nextSelector := flag.String("next-selector", linkNextSelector, "Where -paginate finds the next page: \"Link rel=next\" or a JSONPath such as $.next")
+	maxPages := flag.Int("max-pages", 100, "Maximum number of pages -paginate fetches")
+	idempotency := flag.Bool("idempotency", false, "Send an Idempotency-Key header that stays the same across retries of a request")
+	progress := flag.Bool("progress", false, "Print the upload progress of request bodies to stderr")
+	progressInterval := flag.Duration("progress-interval", time.Second, "How often -progress prints the bytes sent")
+	hedgeAfter := flag.Duration("hedge-after", 0, "Send a duplicate of a request that has not answered within this duration and keep the first response")
+	stream := flag.Bool("stream", false, "Append response body chunks with the time they arrived to a -stream.txt report as they are read")
+	streamDuration := flag.Duration("stream-duration", 0, "Stop reading a streamed response after this long without failing the request, implies -stream")
+	sse := flag.Bool("sse", false, "Read responses as text/event-stream and report the server-sent events")
+	sseMaxEvents := flag.Int("sse-max-events", 10, "Close -sse streams after this many events, 0 means read until the stream ends")
+	graph := flag.String("graph", "", "Print the dependencies between requests instead of sending them, dot writes a Graphviz digraph")
+	statusOnly := flag.Bool("status-only", false, "Print a table of the status of every request after the batch instead of writing reports, -output is not needed")
+	keepAliveTimeout := flag.Duration("keep-alive-timeout", 0, "Probe how long the server keeps idle connections open, idling up to this long between requests, and report when it closes them")
+	keepaliveProbe := flag.Bool("keepalive-probe", false, "Report how many responses reused a kept-alive connection, use with -repeat")
+	repeat := flag.Int("repeat", 1, "Send each request this many times")
+	rampFrom := flag.Int("ramp-from", 1, "Concurrency a -ramp-to load run starts at")
+	rampTo := flag.Int("ramp-to", 0, "Raise the concurrency of a -repeat load run from -ramp-from to this over -ramp-duration and report error rate and latency per concurrency")
+	rampDuration := flag.Duration("ramp-duration", 30*time.Second, "How long -ramp-to takes to reach its concurrency")
+	warmup := flag.Int("warmup", 0, "Send each request this many times first and leave those runs out of the summary and latency statistics")
+	measure := flag.Int("measure", 0, "Send each request this many times after the -warmup runs, overriding -repeat")
+	cpuProfile := flag.String("cpuprofile", "", "Write a pprof CPU profile of the run to this path")
+	memProfile := flag.String("memprofile", "", "Write a pprof heap profile to this path when the run ends")
+	expectP95 := flag.Duration("expect-p95", 0, "Fail the run when the p95 latency exceeds this duration")
+	expectP99 := flag.Duration("expect-p99", 0, "Fail the run when the p99 latency exceeds this duration")
+	reportSink := flag.String("report-sink", "file", "Where reports are written: file or stdout")
+	outputAppend := flag.String("output-append", "", "Append every report to this file after a line with its name and time instead of writing a file per report, -output is not needed")
+	reportDir := flag.String("report-dir", "", "Directory the file report sink writes into")
+	traceIDHeader := flag.String("trace-id-header", "", "Response header holding the server trace ID, such as X-Amzn-Trace-Id, shown in status lines, reports and the summary")
+	nameTemplate := flag.String("name-template", "", "Name reports from {method}, {host}, {path}, {status} and {timestamp} instead of the -output name, in the -output directory")
+	jqExpr := flag.String("jq", "", "jq expression, such as '.data[] | .id', whose output replaces JSON response bodies in reports")
+	reportHash := flag.Bool("report-hash", false, "Add a Response Hash, the SHA-256 of the status and normalized body, to reports for detecting changed responses")
+	compactJSON := flag.Bool("compact-json", false, "Write json format reports as a single line of minified JSON, for collecting them as NDJSON")
+	normalizeJSONFlag := flag.Bool("normalize-json", false, "Canonicalize JSON bodies (sorted keys, compact) in reports and diffs")
+	watch := flag.Bool("watch", false, "Rerun the requests every time the -source file changes, until interrupted")
+	watchInterval := flag.Duration("watch-interval", 500*time.Millisecond, "How often -watch checks the source file")
+	interactive := flag.Bool("interactive", false, "List the requests and run the ones picked at a prompt")
+	responseOnly := flag.Bool("response-only", false, "Write only the response status, headers, timing and body in text reports")
+	reportInclude := flag.String("report-include", "", "Comma-separated text report sections to keep: "+strings.Join(reportSections, ", "))
+	reportExclude := flag.String("report-exclude", "", "Comma-separated text report sections to leave out")
+	saveOnFailureOnly := flag.Bool("response-save-on-failure-only", false, "Write full reports only for failed requests, passing requests get a one line Passed: record instead")
+	sortReports := flag.Bool("sort-reports", false, "Write the reports of passing and failing responses into pass/ and fail/ directories")
+	reportEncodingName := flag.String("report-encoding", "utf-8", "Character encoding of report files: utf-8, latin1 or ascii")
+	formats := flag.String("format", "txt", "Comma-separated report formats to write for each response: txt, json")
+	gzipBody := flag.Bool("keep-response-body-gzip", false, "Store each response body gzipped in a .txt.gz file next to its report instead of in the report")
+	dumpRawFlag := flag.Bool("dump-raw", false, "Append the raw request and response to each report")
+	redact := flag.Bool("redact", false, "Write Authorization, cookies and other sensitive header values as *** in reports")
+	redactExtra := flag.String("redact-header", "", "Comma-separated extra header names to redact, implies -redact")
+	traceHeaders := flag.Bool("trace-headers", false, "Generate X-Request-ID and W3C traceparent headers for each request")
+	consolidated := flag.Bool("consolidated", false, "Write a single report listing every attempt")
+	oauthTokenURL := flag.String("oauth-token-url", "", "OAuth2 token endpoint for the client-credentials grant")
+	oauthClientID := flag.String("oauth-client-id", "", "OAuth2 client ID")
+	oauthClientSecret := flag.String("oauth-client-secret", "", "OAuth2 client secret")
+	oauthScope := flag.String("oauth-scope", "", "OAuth2 scope to request")
+	useNetrc := flag.Bool("netrc", false, "Use basic auth credentials for the request host from ~/.netrc when the request has none")
+	netrcFile := flag.String("netrc-file", "", "Read -netrc credentials from this file instead of ~/.netrc, implies -netrc")
+	awsAccessKey := flag.String("aws-access-key", "", "Sign requests with AWS SigV4 using this access key ID")
+	awsSecretKey := flag.String("aws-secret-key", "", "AWS secret access key for -aws-access-key")
+	awsRegion := flag.String("aws-region", "", "AWS region for SigV4 signing, for example us-east-1")
+	awsService := flag.String("aws-service", "", "AWS service for SigV4 signing, for example s3")
+	attemptTimeout := flag.Duration("attempt-timeout", 0, "Timeout for each attempt of a request, retries get a fresh one, 0 means no timeout")
+	maxDuration := flag.Duration("max-duration", 0, "Timeout for all attempts of a request together, including the waits between retries, 0 means no timeout")
+	timeout := flag.Duration("timeout", 0, "Overall timeout for each request including the body read, 0 means no timeout")
+	dialTimeout := flag.Duration("dial-timeout", 30*time.Second, "Timeout for establishing the TCP connection")
+	tlsHandshakeTimeout := flag.Duration("tls-handshake-timeout", 10*time.Second, "Timeout for the TLS handshake")
+	responseHeaderTimeout := flag.Duration("response-header-timeout", 0, "Timeout waiting for response headers after the request is written, 0 means no timeout")
+	expectContinueTimeout := flag.Duration("expect-continue-timeout", time.Second, "How long a request with an Expect: 100-continue header waits for 100 Continue before sending its body")
+	disableKeepAlive := flag.Bool("disable-keepalive", false, "Open a new connection for every request instead of reusing idle connections")
+	maxIdleConns := flag.Int("max-idle-conns", 100, "Maximum idle connections kept across all hosts, 0 means no limit")
+	maxIdleConnsPerHost := flag.Int("max-idle-conns-per-host", 2, "Maximum idle connections kept per host")
+	idleConnTimeout := flag.Duration("idle-conn-timeout", 90*time.Second, "How long an idle connection stays in the pool, 0 means no limit")
+	dnsServer := flag.String("dns-server", "", "Resolve host names with the DNS server at ip:port instead of the system resolver")
+	socks5 := flag.String("socks5", "", "Tunnel connections through the SOCKS5 proxy at [user:password@]host:port")
+	unixSocket := flag.String("unix-socket", "", "Connect to this Unix domain socket instead of the URL host")
+	insecure := flag.Bool("insecure", false, "Skip TLS certificate verification")
+	caBundle := flag.String("ca-bundle", "", "PEM file of CA certificates to trust in addition to the system roots")
+	caBundleOnly := flag.Bool("ca-bundle-only", false, "Trust only the -ca-bundle certificates, ignoring the system roots")
+	sni := flag.String("sni", "", "Send this TLS server name (SNI) instead of the URL host")
+	minTLSVersion := flag.String("min-tls-version", "", "Lowest TLS version to accept: 1.0, 1.1, 1.2 or 1.3")
+	allowInsecureCiphers := flag.Bool("allow-insecure-ciphers", false, "Offer legacy TLS cipher suites and accept TLS 1.0 and 1.1, for testing old servers")
+	verifyHostname := flag.String("verify-hostname", "", "Verify the server certificate against this name instead of the URL host, the SNI is unchanged")
+	forceHTTP2 := flag.Bool("http2", false, "Force HTTP/2 over TLS")
+	h2Priorities := flag.String("h2-priorities", "", "Send each request as concurrent HTTP/2 streams with these comma separated weights, 1 to 256, and report the order they complete in")
+	insecureHTTP2 := flag.Bool("insecure-http2", false, "Force HTTP/2 over cleartext with prior knowledge (h2c)")
+	httpVersion := flag.String("http-version", "", "Force the HTTP/1.x version used on the request line: 1.0 or 1.1")
+	cookies := flag.Bool("cookies", false, "Keep the cookies responses set and send them with the later requests of the run, reports list the cookies each request sent")
+	noAutoDecompress := flag.Bool("no-auto-decompress", false, "Do not send Accept-Encoding: gzip or decompress gzip, br and zstd responses")
+	resolve := resolveFlag{}
+	flag.Var(resolve, "resolve", "Resolve host:port to addr instead of using DNS, format host:port:addr (repeatable)")
+	rewriteHosts := rewriteHostFlag{}
+	flag.Var(rewriteHosts, "rewrite-host", "Send requests for one host to another, keeping the path and query, format from=to (repeatable)")
+	connectTo := connectToFlag{}
+	flag.Var(connectTo, "connect-to", "Connect to another host and port for host:port, format host:port:connecthost:connectport (repeatable)")
+
+	printConfigFlag := flag.Bool("print-config", false, "Print the effective value of every flag and -env-file variable, with secrets as ***, and exit")
+	flag.BoolVar(&quiet, "quiet", false, "Suppress informational output, errors are still written to stderr")
+	colorMode := flag.String("color", "auto", "Color statuses in status lines: auto, always or never")
 
 	flag.Parse()
 
-	if *source == "" || *output == "" {
-		fmt.Println("Usage: httpclient -source <path> -output <path>")
+	if *printConfigFlag {
+		env, err := loadEnv(envFiles)
+		if err != nil {
+			fatal(err)
+		}
+		printConfig(stdout, env)
 		return
 	}
 
+	if *dryValidate == "" && ((*source == "" && *rawURL == "" && *replayReport == "") || (*output == "" && !*statusOnly && *graph == "" && *outputAppend == "")) {
+		fatal("Usage: httpclient -source <path>|-url <url>|-replay-report <path> -output <path>")
+	}
+	if *rawURL != "" && (*source != "" || *replayReport != "") {
+		fatal("-url cannot be combined with -source or -replay-report, which read the requests to send")
+	}
+
+	var err error
+	color, err = colorEnabled(*colorMode)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *expectRedirect != 0 && (*expectRedirect < 300 || *expectRedirect > 399) {
+		fatal("-expect-redirect must be a 3xx status code")
+	}
+	if *pathFlag != "" && !strings.HasPrefix(*pathFlag, "/") {
+		fatal("-path must start with /")
+	}
+	if *watch && *source == "" {
+		fatal("-watch requires -source")
+	}
+	if *watch && strings.Contains(*source, ",") {
+		fatal("-watch requires a single -source file")
+	}
+	if *watch && isRemoteSource(*source) {
+		fatal("-watch cannot watch a remote -source")
+	}
+
+	if *minSuccessRate < 0 || *minSuccessRate > 1 {
+		fatal("-min-success-rate must be between 0 and 1")
+	}
+
+	if *parallel > 1 && *replayDelay > 0 {
+		fatal("-parallel cannot be combined with -replay-delay")
+	}
+
+	if *rampTo > 0 {
+		if *rampFrom < 1 || *rampDuration <= 0 {
+			fatal("-ramp-from must be at least 1 and -ramp-duration positive")
+		}
+		if *parallel > 1 {
+			fatal("-parallel cannot be combined with -ramp-to")
+		}
+	}
+
+	if *warmup < 0 || *measure < 0 {
+		fatal("-warmup and -measure cannot be negative")
+	}
+
+	if *nameTemplate != "" {
+		if err := checkNameTemplate(*nameTemplate); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *graph != "" && *graph != "dot" {
+		fatal(fmt.Sprintf("unknown -graph %q, expected dot", *graph))
+	}
+
+	if *interactive && *watch {
+		fatal("-interactive cannot be combined with -watch")
+	}
+
+	if *bodyFile != "" && *bodyTemplateFile != "" {
+		fatal("-body-file cannot be combined with -body-template-file")
+	}
+
 	if *retry == 0 {
 		retry = &defaultRetry
 	}
 
+	if *backoff != "fixed" && *backoff != "adaptive" {
+		fatal("unsupported -backoff", *backoff)
+	}
+
 	if *sleep == 0 {
 		sleep = &defaultSleep
 	}
 
-	reqData, err := ReadHTTPFile(*source)
+	var cache *ResponseCache
+	if *cacheFile != "" {
+		cache, err = LoadResponseCache(*cacheFile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if *httpVersion != "" && *httpVersion != "1.0" && *httpVersion != "1.1" {
+		fatal("unsupported -http-version", *httpVersion)
+	}
+
+	// The pool must not drop a probed connection before the server does
+	if *keepAliveTimeout > 0 {
+		if *disableKeepAlive {
+			fatal("-keep-alive-timeout cannot be used with -disable-keepalive")
+		}
+		if *idleConnTimeout != 0 {
+			*idleConnTimeout = max(*idleConnTimeout, *keepAliveTimeout+time.Second)
+		}
+	}
+
+	client, err := NewClient(ClientOptions{
+		Resolve:            resolve,
+		ConnectTo:          connectTo,
+		DNSServer:          *dnsServer,
+		SOCKS5:             *socks5,
+		UnixSocket:         *unixSocket,
+		DisableCompression: *noAutoDecompress || *minimalHeaders,
+		DisableKeepAlives:  *disableKeepAlive,
+		Insecure:           *insecure,
+		CABundle:           *caBundle,
+		CABundleOnly:       *caBundleOnly,
+		ServerName:         *sni,
+		VerifyHostname:     *verifyHostname,
+		HTTP2:              *forceHTTP2,
+		H2C:                *insecureHTTP2,
+		HTTP10:             *httpVersion == "1.0",
+
+		NoRedirects:          *expectRedirect != 0 || *expectLocation != "",
+		Cookies:              *cookies,
+		AllowInsecureCiphers: *allowInsecureCiphers,
+		MinTLSVersion:        *minTLSVersion,
 
+		MaxIdleConns:        *maxIdleConns,
+		MaxIdleConnsPerHost: *maxIdleConnsPerHost,
+		IdleConnTimeout:     *idleConnTimeout,
+
+		Timeout:               *timeout,
+		DialTimeout:           *dialTimeout,
+		TLSHandshakeTimeout:   *tlsHandshakeTimeout,
+		ResponseHeaderTimeout: *responseHeaderTimeout,
+		ExpectContinueTimeout: *expectContinueTimeout,
+	})
 	if err != nil {
-		fmt.Println(err)
+		fatal(err)
+	}
+
+	var tokens *TokenSource
+	if *oauthTokenURL != "" {
+		tokens = NewTokenSource(client, OAuthConfig{
+			TokenURL:     *oauthTokenURL,
+			ClientID:     *oauthClientID,
+			ClientSecret: *oauthClientSecret,
+			Scope:        *oauthScope,
+		})
+	}
+
+	var reportFormats []string
+	for _, format := range strings.Split(*formats, ",") {
+		format = strings.TrimSpace(format)
+		if _, ok := reportRenderers[format]; !ok {
+			fatal("unsupported -format", format)
+		}
+		reportFormats = append(reportFormats, format)
+	}
+	if _, err := reportEncoding(*reportEncodingName); err != nil {
+		fatal(err)
+	}
+
+	includeSections, err := parseReportSections(*reportInclude)
+	if err != nil {
+		fatal(err)
+	}
+	excludeSections, err := parseReportSections(*reportExclude)
+	if err != nil {
+		fatal(err)
+	}
+
+	var redactHeaders []string
+	if *redact || *redactExtra != "" {
+		redactHeaders = redactHeaderNames(*redactExtra)
+	}
+
+	if err := checkJSONPointer(*extract); err != nil {
+		fatal(err)
+	}
+
+	errorFields := defaultErrorFields
+	if *errorField != "" {
+		if _, err := parseJSONPath(*errorField); err != nil {
+			fatal(err)
+		}
+		errorFields = []string{*errorField}
+	}
+
+	var netrc *Netrc
+	if *useNetrc || *netrcFile != "" {
+		path := *netrcFile
+		if path == "" {
+			path = defaultNetrcPath()
+		}
+		netrc, err = LoadNetrc(path)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	var sigV4 *SigV4Config
+	if *awsAccessKey != "" {
+		if *awsSecretKey == "" || *awsRegion == "" || *awsService == "" {
+			fatal("-aws-access-key requires -aws-secret-key, -aws-region and -aws-service")
+		}
+		sigV4 = &SigV4Config{
+			AccessKey: *awsAccessKey,
+			SecretKey: *awsSecretKey,
+			Region:    *awsRegion,
+			Service:   *awsService,
+		}
+	}
+
+	env, err := loadEnv(envFiles)
+	if err != nil {
+		fatal(err)
+	}
+
+	var secrets map[string]string
+	if *secretsFile != "" {
+		secrets, err = ReadSecretsFile(*secretsFile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	var assertions []Assertion
+	// An empty body that is still there after the last retry fails the request
+	if *failOnBodyEmpty || *retryOnEmpty {
+		assertions = append(assertions, assertBodyNotEmpty)
+	}
+
+	// As with empty bodies, a response that still matches after the last retry fails the request
+	var retryOnBody *regexp.Regexp
+	if *retryOnBodyRegex != "" {
+		retryOnBody, err = regexp.Compile(*retryOnBodyRegex)
+		if err != nil {
+			fatal(fmt.Errorf("invalid -retry-on-body-regex: %w", err))
+		}
+		assertions = append(assertions, assertBodyNotMatching(retryOnBody))
+	}
+
+	if *expectRedirect != 0 || *expectLocation != "" {
+		assertions = append(assertions, assertRedirect(*expectRedirect, *expectLocation))
+	}
+
+	if *assertContentTypeFlag != "" {
+		assertions = append(assertions, assertContentType(*assertContentTypeFlag))
+	}
+
+	if *assertExprFlag != "" {
+		assert, err := assertExpr(*assertExprFlag)
+		if err != nil {
+			fatal(fmt.Errorf("invalid -assert-expr: %w", err))
+		}
+		assertions = append(assertions, assert)
+	}
+
+	if *expectSHA256 != "" {
+		assertions = append(assertions, assertSHA256(*expectSHA256))
+	}
+
+	if *schemaFile != "" {
+		schema, err := LoadSchema(*schemaFile)
+		if err != nil {
+			fatal(err)
+		}
+		assertions = append(assertions, assertSchema(schema))
+	}
+
+	if *openAPIFile != "" {
+		spec, err := LoadOpenAPISpec(*openAPIFile)
+		if err != nil {
+			fatal(err)
+		}
+		assertions = append(assertions, assertOpenAPI(spec))
+	}
+
+	if *dryValidate != "" {
+		result, err := ReadReportResponse(*dryValidate)
+		if err != nil {
+			fatal(err)
+		}
+		if *expectStatus != 0 {
+			assertions = append(assertions, assertStatus(*expectStatus))
+		}
+		runAssertions(result, assertions)
+
+		for _, failure := range result.Failures {
+			printError("assertion failed:", failure)
+		}
+		if result.Failed() {
+			fatal(fmt.Sprintf("%s: %d assertion failures", *dryValidate, len(result.Failures)))
+		}
+		printInfo(fmt.Sprintf("%s: %d assertions passed", *dryValidate, len(assertions)))
 		return
 	}
 
-	for i := 0; i < *retry; i++ {
-		response, err := SendRequest(reqData, *retry, *sleep)
+	sink, err := newReportSink(*reportSink, *reportDir)
+	if err != nil {
+		fatal(err)
+	}
+	if *statusOnly {
+		sink = DiscardSink{}
+	}
+	if *outputAppend != "" {
+		sink = &AppendSink{Path: *outputAppend}
+		// Reports are still named after -output in the appended file, or after the file itself
+		if *output == "" {
+			*output = strings.TrimSuffix(filepath.Base(*outputAppend), filepath.Ext(*outputAppend))
+		}
+	}
+
+	var events *EventLog
+	if *eventsLog != "" {
+		events, err = NewEventLog(*eventsLog)
+		if err != nil {
+			fatal(err)
+		}
+	}
 
+	var retryLog *RetryLog
+	if *retryLogPath != "" {
+		retryLog, err = NewRetryLog(*retryLogPath)
 		if err != nil {
-			fmt.Println(err)
-			return
+			fatal(err)
 		}
+	}
 
-		err = GenerateReport(*output, reqData, response)
+	var tee *Tee
+	if *teePath != "" {
+		tee, err = NewTee(*teePath)
+		if err != nil {
+			fatal(err)
+		}
+	}
 
+	var jq *jqFilter
+	if *jqExpr != "" {
+		jq, err = parseJQ(*jqExpr)
 		if err != nil {
-			fmt.Println(err)
-			return
+			fatal(err)
 		}
-		time.Sleep(time.Duration(*sleep) * time.Second)
+	}
 
+	runner := &Runner{
+		Client:       client,
+		Retry:        *retry,
+		RetryBudget:  *retryBudget,
+		Sleep:        time.Duration(*sleep) * time.Second,
+		Backoff:      *backoff,
+		RetryOnEmpty: *retryOnEmpty,
+		RetryOnBody:  retryOnBody,
+		Jitter:       *retryJitter,
+		JitterRand:   newJitterRand(*retryJitterSeed),
+		Tokens:       tokens,
+		SigV4:        sigV4,
+		Netrc:        netrc,
+		Idempotency:  *idempotency,
+		TraceHeaders: *traceHeaders,
+		Cache:        cache,
+		Variables:    &VariableScopes{Shared: *sharedVars},
+		Assertions:   assertions,
+		ExpectStatus: *expectStatus,
+		ErrorFields:  errorFields,
+		Extract:      *extract,
+		Consolidated: *consolidated,
+		Events:       events,
+		Report: ReportOptions{
+			GRPCHexDump:      *grpcHexDump,
+			HexDump:          *hexDump,
+			MaxResponseBytes: *maxResponseBytes,
+			NormalizeJSON:    *normalizeJSONFlag,
+			CompactJSON:      *compactJSON,
+			JQ:               jq,
+			NameTemplate:     *nameTemplate,
+			TraceIDHeader:    *traceIDHeader,
+			SHA256:           *expectSHA256 != "",
+			ResponseHash:     *reportHash,
+			CertExpiry:       *certExpiryWarn > 0,
+			CookiesSent:      *cookies,
+			GzipBody:         *gzipBody,
+			DumpRaw:          *dumpRawFlag,
+			Formats:          reportFormats,
+			Encoding:         *reportEncodingName,
+			SortReports:      *sortReports,
+			FailuresOnly:     *saveOnFailureOnly,
+			GeneratedHeaders: genHeaders.Count,
+			Secrets:          secretValues(secrets),
+			ResponseOnly:     *responseOnly,
+			Include:          includeSections,
+			Exclude:          excludeSections,
+			Redact:           redactHeaders,
+			Sink:             sink,
+		},
+		Paginate:     *paginate,
+		FuzzHeaders:  *fuzzHeaders,
+		SmuggleCheck: *smuggleCheck,
+		NextSelector: *nextSelector,
+		MaxPages:     *maxPages,
+		SSE:          *sse,
+		SSEMaxEvents: *sseMaxEvents,
+		CompareBase:  *compareBase,
+		Mirror:       *mirror,
+		DiffHeaders:  *diffHeadersPath,
+		PreScript:    *preScript,
+		PostScript:   *postScript,
+		WaitFor:      *waitFor,
+		WaitStatus:   *waitStatus,
+		WaitInterval: *waitInterval,
+		WaitTimeout:  *waitTimeout,
+
+		BodyTransform:  *bodyTransform,
+		IdempotentOnly: *idempotentOnly,
+		CaptureAll:     *captureAll,
+		Secrets:        secrets,
+		Env:            env,
+		Resume:         *resume,
+
+		PreserveHeaderCase: *preserveHeaderCase,
+		MinimalHeaders:     *minimalHeaders,
+		KeepEncoded:        *noAutoDecompress,
+	}
+	if *progress {
+		runner.Progress = *progressInterval
+	}
+	runner.HedgeAfter = *hedgeAfter
+	runner.Stream = *stream || *streamDuration > 0
+	runner.StreamDuration = *streamDuration
+	runner.KeepAliveTimeout = *keepAliveTimeout
+	runner.RetriesPerStatus = retriesPerStatus
+	runner.AttemptTimeout, runner.MaxDuration = *attemptTimeout, *maxDuration
+	runner.RetryLog = retryLog
+	runner.Tee = tee
+	// Nothing but the hash looks at the body when it is the only assertion and no report or sidecar shows it
+	runner.HashOnly = *expectSHA256 != "" && len(assertions) == 1 && !runner.Report.includes("response-body") &&
+		!slices.Contains(reportFormats, "json") && tee == nil && *extract == "" && !*gzipBody
+	runner.RewriteHosts = rewriteHosts
+	runner.CertExpiryWarn = *certExpiryWarn
+	if *dataJSON != "" {
+		runner.Data, err = ParseDataJSON(*dataJSON)
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if *canaryURL != "" {
+		if *canaryPercent < 0 || *canaryPercent > 100 {
+			fatal(fmt.Sprintf("invalid -canary-percent %v, expected 0 to 100", *canaryPercent))
+		}
+		runner.Canary, runner.CanaryPercent = *canaryURL, *canaryPercent
+	}
+	if *ignoreJSONPaths != "" {
+		if *compareBase == "" {
+			fatal("-ignore-json-paths requires -compare-base")
+		}
+		for _, path := range strings.Split(*ignoreJSONPaths, ",") {
+			segments, err := parseJSONPath(strings.TrimSpace(path))
+			if err != nil {
+				fatal(err)
+			}
+			runner.IgnoreJSONPaths = append(runner.IgnoreJSONPaths, segments)
+		}
+	}
+	if *h2Priorities != "" {
+		for _, field := range strings.Split(*h2Priorities, ",") {
+			weight, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || weight < 1 || weight > 256 {
+				fatal(fmt.Sprintf("invalid -h2-priorities weight %q, expected 1 to 256", field))
+			}
+			runner.H2Priorities = append(runner.H2Priorities, weight)
+		}
 	}
 
+	// loadRequests reads the requests to send and applies the command line overrides to them
+	loadRequests := func() ([]RequestData, error) {
+		parseOpts := ParseOptions{
+			MaxBodyBytes: *maxRequestBytes,
+			Strict:       *strictParse,
+		}
+
+		var requests []RequestData
+		var err error
+
+		switch {
+		case *replayReport != "":
+			reqData, err := ReadReportFile(*replayReport)
+			if err != nil {
+				return nil, err
+			}
+			requests = []RequestData{reqData}
+		case *source == "":
+			requests = []RequestData{NewURLRequest(*rawURL)}
+		}
+		for _, path := range strings.Split(*source, ",") {
+			if path == "" {
+				continue
+			}
+
+			var fileRequests []RequestData
+			switch *formatIn {
+			case "har":
+				fileRequests, err = ReadHARFile(path)
+			case "http":
+				fileRequests, err = ReadHTTPFile(path, parseOpts)
+			default:
+				err = fmt.Errorf("unknown input format %q", *formatIn)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range fileRequests {
+				fileRequests[i].Source = path
+			}
+			requests = append(requests, fileRequests...)
+		}
+
+		if *dataRows != "" {
+			rows, err := ReadDataRows(*dataRows)
+			if err != nil {
+				return nil, err
+			}
+			requests, err = expandDataRows(requests, rows)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var sharedHeaders map[string]string
+		if *headersFile != "" {
+			sharedHeaders, err = ReadHeadersFile(*headersFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var hostHeaders []HostHeaders
+		if *hostHeadersFile != "" {
+			hostHeaders, err = ReadHostHeadersFile(*hostHeadersFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		runner.HostHeaders, runner.SharedHeaders = hostHeaders, sharedHeaders
+
+		var body []byte
+		if *bodyFile != "" {
+			body, err = os.ReadFile(*bodyFile)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkBodySize(string(body), *maxRequestBytes); err != nil {
+				return nil, err
+			}
+		} else if *bodyTemplateFile != "" {
+			template, err := ReadBodyTemplate(*bodyTemplateFile)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkBodySize(template, *maxRequestBytes); err != nil {
+				return nil, err
+			}
+			body = []byte(template)
+		}
+
+		for i := range requests {
+			reqData := &requests[i]
+
+			if *method != "" {
+				reqData.Method = strings.ToUpper(*method)
+			}
+
+			if *pathFlag != "" {
+				reqData.URL = replacePath(reqData.URL, *pathFlag)
+			}
+
+			if *bodyOut != "" && reqData.SaveBody == "" {
+				reqData.SaveBody = *bodyOut
+			}
+
+			for k, v := range headers {
+				reqData.Headers[k] = v
+			}
+
+			methodHeaders.apply(reqData)
+			genHeaders.apply(reqData)
+
+			if body != nil {
+				// Strings hold arbitrary bytes, so the body is sent exactly as it is on disk
+				reqData.Body = string(body)
+			}
+
+			warnRequest(*reqData)
+		}
+
+		return requests, nil
+	}
+
+	// printOutcome prints the status line of a finished request, -status-only prints a table after the batch instead
+	printOutcome := func(outcome Outcome) {
+		if !*statusOnly {
+			printInfo(statusLine(outcome))
+		}
+	}
+
+	// runs is how many measured times each request is sent, after its -warmup runs
+	runs := max(*repeat, 1)
+	if *measure > 0 {
+		runs = *measure
+	}
+
+	// printWarmup prints the status line of a -warmup run, which is left out of the batch outcomes
+	printWarmup := func(outcome Outcome) {
+		if !*statusOnly {
+			printInfo("warmup:", statusLine(outcome))
+		}
+	}
+
+	// runBatch sends every request and reports whether they all passed
+	runBatch := func(requests []RequestData) bool {
+		var outcomes []Outcome
+		failed := false
+		// requestFailed is set when a request did not pass, which -min-success-rate may tolerate
+		requestFailed := false
+
+		flow, err := newChain(requests)
+		if err != nil {
+			printError(err)
+			return false
+		}
+
+		// reportPath names the reports of run n of request i
+		reportPath := func(i, n int) string {
+			path := *output
+			if requests[i].Output != "" {
+				path = filepath.Join(filepath.Dir(*output), requests[i].Output)
+			} else if len(requests) > 1 {
+				path = fmt.Sprintf("%s-%d", path, i+1)
+			}
+			if n < *warmup {
+				path = fmt.Sprintf("%s-warmup%d", path, n+1)
+			} else if runs > 1 {
+				path = fmt.Sprintf("%s-r%d", path, n-*warmup+1)
+			}
+			return path
+		}
+
+		if *rampTo > 0 {
+			if flow.hasBranches() {
+				printError("-ramp-to cannot be combined with @on-success or @on-failure")
+				return false
+			}
+
+			var warmups, jobs []job
+			for i, reqData := range requests {
+				for n := 0; n < *warmup; n++ {
+					warmups = append(warmups, job{Request: reqData, OutputPath: reportPath(i, n)})
+				}
+				for n := *warmup; n < *warmup+runs; n++ {
+					jobs = append(jobs, job{Request: reqData, OutputPath: reportPath(i, n)})
+				}
+			}
+
+			runPool(warmups, *rampFrom, 0, 0, func(j job) Outcome {
+				outcome := runner.Run(j.Request, j.OutputPath)
+				printWarmup(outcome)
+				return outcome
+			})
+			outcomes = runRamp(jobs, *rampFrom, *rampTo, *rampDuration, func(j job) Outcome {
+				if j.Request.Delay > 0 {
+					time.Sleep(j.Request.Delay)
+				}
+				outcome := runner.Run(j.Request, j.OutputPath)
+				printOutcome(outcome)
+				return outcome
+			})
+			for _, outcome := range outcomes {
+				if !outcome.Passed {
+					requestFailed = true
+				}
+			}
+		} else if *parallel > 1 {
+			if flow.hasBranches() {
+				printError("-parallel cannot be combined with @on-success or @on-failure")
+				return false
+			}
+
+			// The warmup runs all finish before the measured ones start, so they do not overlap
+			var warmups, jobs []job
+			for i, reqData := range requests {
+				for n := 0; n < *warmup; n++ {
+					warmups = append(warmups, job{Request: reqData, OutputPath: reportPath(i, n)})
+				}
+				for n := *warmup; n < *warmup+runs; n++ {
+					jobs = append(jobs, job{Request: reqData, OutputPath: reportPath(i, n)})
+				}
+			}
+
+			runPool(warmups, *parallel, *parallelPerHost, *maxConcurrent, func(j job) Outcome {
+				outcome := runner.Run(j.Request, j.OutputPath)
+				printWarmup(outcome)
+				return outcome
+			})
+			outcomes = runPool(jobs, *parallel, *parallelPerHost, *maxConcurrent, func(j job) Outcome {
+				if j.Request.Delay > 0 {
+					time.Sleep(j.Request.Delay)
+				}
+				outcome := runner.Run(j.Request, j.OutputPath)
+				printOutcome(outcome)
+				return outcome
+			})
+			for _, outcome := range outcomes {
+				if !outcome.Passed {
+					requestFailed = true
+				}
+			}
+		} else {
+			for i := flow.first(); i >= 0; {
+				reqData := requests[i]
+
+				// A @delay directive overrides the global pacing, which only applies between requests
+				if reqData.Delay > 0 {
+					time.Sleep(reqData.Delay)
+				} else if len(outcomes) > 0 && *replayDelay > 0 {
+					time.Sleep(*replayDelay)
+				}
+
+				for n := 0; n < *warmup; n++ {
+					printWarmup(runner.Run(reqData, reportPath(i, n)))
+				}
+				for n := *warmup; n < *warmup+runs; n++ {
+					outcome := runner.Run(reqData, reportPath(i, n))
+					outcomes = append(outcomes, outcome)
+					printOutcome(outcome)
+					if !outcome.Passed {
+						requestFailed = true
+					}
+				}
+
+				i, err = flow.next(i, outcomes[len(outcomes)-1].Passed)
+				if err != nil {
+					printError(err)
+					failed = true
+				}
+			}
+		}
+
+		if *statusOnly && !quiet {
+			if err := writeStatusTable(stdout, outcomes); err != nil {
+				printError(err)
+				failed = true
+			}
+		}
+
+		summary := NewSummary(outcomes)
+		if *minSuccessRate > 0 && summary.Total > 0 {
+			rate := float64(summary.Passed) / float64(summary.Total)
+			if rate < *minSuccessRate {
+				summary.Fail(fmt.Sprintf("success rate %.1f%% is below -min-success-rate %.1f%%", rate*100, *minSuccessRate*100))
+			} else {
+				summary.Pass = true
+				if requestFailed {
+					printInfo(fmt.Sprintf("success rate %.1f%% meets -min-success-rate %.1f%%", rate*100, *minSuccessRate*100))
+				}
+			}
+		} else if requestFailed {
+			failed = true
+		}
+		if summary.Latency != nil && runs > 1 {
+			printInfo(summary.Latency.String())
+		}
+		if *keepaliveProbe {
+			reuse := NewConnReuse(outcomes)
+			summary.ConnReuse = &reuse
+			printInfo(reuse.String())
+		}
+		if *canaryURL != "" {
+			comparison := NewCanaryComparison(outcomes)
+			summary.Canary = &comparison
+			if !quiet {
+				if err := writeCanaryComparison(stdout, comparison); err != nil {
+					printError(err)
+					failed = true
+				}
+			}
+		}
+
+		if *rampTo > 0 {
+			summary.Ramp = NewRampLevels(outcomes)
+			if !quiet {
+				if err := writeRampLevels(stdout, summary.Ramp); err != nil {
+					printError(err)
+					failed = true
+				}
+			}
+		}
+
+		if *expectP95 > 0 || *expectP99 > 0 {
+			latencies := outcomeLatencies(outcomes)
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+			if p95 := Percentile(latencies, 95); *expectP95 > 0 && p95 > *expectP95 {
+				summary.Fail(fmt.Sprintf("p95 latency %s exceeds -expect-p95 %s", p95, *expectP95))
+			}
+			if p99 := Percentile(latencies, 99); *expectP99 > 0 && p99 > *expectP99 {
+				summary.Fail(fmt.Sprintf("p99 latency %s exceeds -expect-p99 %s", p99, *expectP99))
+			}
+		}
+
+		for _, failure := range summary.Failures {
+			printError(failure)
+			failed = true
+		}
+
+		if *summaryJSON != "" {
+			err := WriteSummaryJSON(*summaryJSON, summary)
+
+			if err != nil {
+				printError(err)
+				failed = true
+			}
+		}
+
+		if *metricsFile != "" {
+			err := WriteMetricsFile(*metricsFile, summary, time.Now())
+
+			if err != nil {
+				printError(err)
+				failed = true
+			}
+		}
+
+		if cache != nil {
+			err := cache.Save()
+
+			if err != nil {
+				printError(err)
+				failed = true
+			}
+		}
+
+		return !failed
+	}
+
+	requests, err := loadRequests()
+	if err != nil {
+		fatal(err)
+	}
+
+	if *graph != "" {
+		if err := WriteDOT(stdout, requests); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if *waitForPort != "" {
+		printInfo("waiting for port", *waitForPort)
+		if err := WaitForPort(*waitForPort, *waitInterval, *waitTimeout); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *splay > 0 {
+		delay := SplayDelay(*splay, newJitterRand(*splaySeed))
+		printInfo("splay: waiting", delay.Round(time.Millisecond), "before the first request")
+		time.Sleep(delay)
+	}
+
+	if *interactive {
+		session := &Session{Requests: requests, Runner: runner, Output: *output}
+		err := session.Interact(os.Stdin, stdout)
+		runner.WaitMirrors()
+		if events != nil {
+			events.Close()
+		}
+		if retryLog != nil {
+			retryLog.Close()
+		}
+		if tee != nil {
+			tee.Close()
+		}
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	var stopCPUProfile func() error
+	if *cpuProfile != "" {
+		stopCPUProfile, err = startCPUProfile(*cpuProfile)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	ok := runBatch(requests)
+
+	if *watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		printInfo("watching", *source, "for changes, press Ctrl+C to stop")
+		err = watchFile(ctx, *source, *watchInterval, func() {
+			requests, err := loadRequests()
+			if err != nil {
+				printError(err)
+				return
+			}
+			runBatch(requests)
+		})
+		if err != nil {
+			printError(err)
+			ok = false
+		}
+	}
+
+	runner.WaitMirrors()
+
+	if stopCPUProfile != nil {
+		if err := stopCPUProfile(); err != nil {
+			printError(err)
+			ok = false
+		}
+	}
+
+	if *memProfile != "" {
+		if err := writeHeapProfile(*memProfile); err != nil {
+			printError(err)
+			ok = false
+		}
+	}
+
+	if events != nil {
+		events.Close()
+	}
+	if retryLog != nil {
+		retryLog.Close()
+	}
+	if tee != nil {
+		tee.Close()
+	}
+
+	if *failOnWarnings && warnings.Load() > 0 {
+		printError("-fail-on-warnings:", warnings.Load(), "warning(s) printed")
+		ok = false
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runMainEnv makes the test binary run main instead of the tests, so runMain can test flags end to end
+const runMainEnv = "HTTP2TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the command in dir with args and returns its stdout, stderr and exit code
+func runMain(t *testing.T, dir string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// readReport returns the only file matching pattern
+func readReport(t *testing.T, pattern string) string {
+	t.Helper()
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("%s matched %d files, want 1: %v", pattern, len(matches), matches)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestURLShorthandWithMethodAndHeader(t *testing.T) {
+	var gotMethod, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotHeader = r.Method, r.Header.Get("X-Test")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	_, stderr, code := runMain(t, dir, "-url", srv.URL+"/items", "-method", "post", "-header", "X-Test: yes", "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if gotMethod != http.MethodPost || gotHeader != "yes" {
+		t.Errorf("server got %s with X-Test %q, want POST with yes", gotMethod, gotHeader)
+	}
+
+	report := readReport(t, filepath.Join(dir, "out|*.txt"))
+	for _, want := range []string{"Request Method: POST", "Request URL: " + srv.URL + "/items", "X-Test: yes", "Response Status: 200 OK", "hello"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestURLRejectedWithSourceOrReplayReport(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for flag, path := range map[string]string{
+		"-source":        writeFile(t, dir, "users.http", "GET "+srv.URL+"/users\n"),
+		"-replay-report": writeFile(t, dir, "recorded.txt", "Request Method: GET\nRequest URL: "+srv.URL+"/users\n"),
+	} {
+		_, stderr, code := runMain(t, dir, flag, path, "-url", srv.URL+"/other", "-output", "out")
+		if code == 0 || !strings.Contains(stderr, "-url cannot be combined with -source or -replay-report") {
+			t.Errorf("%s with -url: exit code %d, want a failure naming the conflict: %s", flag, code, stderr)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("server got %d requests, want none for the rejected flags", calls)
+	}
+}
+
+func TestBodyFileSendsBinaryBody(t *testing.T) {
+	body := make([]byte, 256)
+	for i := range body {
+		body[i] = byte(i)
+	}
+
+	var got []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	bodyPath := writeFile(t, dir, "body.bin", string(body))
+	_, stderr, code := runMain(t, dir, "-url", srv.URL, "-method", "PUT", "-body-file", bodyPath, "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("server got %d bytes %x, want %x", len(got), got, body)
+	}
+}
+
+func TestReportListsResponseTrailers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Write([]byte("body"))
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, ".txt")
+	if !strings.Contains(report, "Response Body:\nbody\n\nResponse Trailers:\nX-Checksum: abc\n") {
+		t.Errorf("report does not list the trailer after the body:\n%s", report)
+	}
+}
+
+func TestMaxRequestAndResponseBytes(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	big := writeFile(t, dir, "big.http", "POST "+srv.URL+"\nContent-Type: text/plain\n\n0123456789\n")
+	_, stderr, code := runMain(t, dir, "-source", big, "-max-request-bytes", "5", "-output", "big")
+	if code != 1 || !strings.Contains(stderr, "request body is 10 bytes, exceeds -max-request-bytes 5") {
+		t.Errorf("oversized request: exit code %d, want 1 with a size error: %s", code, stderr)
+	}
+	if called {
+		t.Error("oversized request was sent")
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL, "-max-response-bytes", "4", "-output", "small")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	report := readReport(t, filepath.Join(dir, "small|*.txt"))
+	if !strings.Contains(report, "Response Body:\n0123\n[response body truncated: showing 4 of 10 bytes]\n") {
+		t.Errorf("report does not truncate the body to 4 bytes:\n%s", report)
+	}
+}
+
+func TestReplayDelayPacesRequests(t *testing.T) {
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "paced.http", "GET "+srv.URL+"/one\n\n###\nGET "+srv.URL+"/two\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-replay-delay", "200ms", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if len(times) != 2 {
+		t.Fatalf("server got %d requests, want 2", len(times))
+	}
+	if gap := times[1].Sub(times[0]); gap < 200*time.Millisecond {
+		t.Errorf("requests were %s apart, want at least -replay-delay 200ms", gap)
+	}
+}
+
+func TestReportIncludeAndExclude(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "test")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-report-exclude", "response-body,request-headers", "-output", "excluded"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	report := readReport(t, filepath.Join(dir, "excluded|*.txt"))
+	if strings.Contains(report, "Response Body:") || strings.Contains(report, "Request Headers:") {
+		t.Errorf("report has an excluded section:\n%s", report)
+	}
+	if !strings.Contains(report, "Request Method: GET") || !strings.Contains(report, "Response Status: 200 OK") {
+		t.Errorf("report dropped a section that was not excluded:\n%s", report)
+	}
+
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-report-include", "status,response-headers", "-output", "included"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	report = readReport(t, filepath.Join(dir, "included|*.txt"))
+	if !strings.Contains(report, "Response Status: 200 OK") || !strings.Contains(report, "X-Served-By: test") {
+		t.Errorf("report is missing an included section:\n%s", report)
+	}
+	for _, section := range []string{"Request Method:", "Request Headers:", "Request Body:", "Response Body:"} {
+		if strings.Contains(report, section) {
+			t.Errorf("report has %s outside the included sections:\n%s", section, report)
+		}
+	}
+
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-report-include", "footer", "-output", "unknown"); code != 1 || !strings.Contains(stderr, `unknown report section "footer"`) {
+		t.Errorf("unknown section: exit code %d, want 1 with an error: %s", code, stderr)
+	}
+}
+
+func TestResponseOnlyReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "test")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-header", "X-Secret-Request: 1", "-response-only", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	report := readReport(t, filepath.Join(dir, "out|*.txt"))
+	for _, unwanted := range []string{"Request Method:", "Request URL:", "Request Headers:", "X-Secret-Request", "Request Body:"} {
+		if strings.Contains(report, unwanted) {
+			t.Errorf("response-only report has %q:\n%s", unwanted, report)
+		}
+	}
+	for _, want := range []string{"Response Status: 200 OK", "Response Headers:", "X-Served-By: test", "Latency: ", "Response Body:\nhello"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("response-only report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRedirectKeepsMethodAndBody(t *testing.T) {
+	var gotMethod, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/307":
+			http.Redirect(w, r, "/final", http.StatusTemporaryRedirect)
+		case "/308":
+			http.Redirect(w, r, "/final", http.StatusPermanentRedirect)
+		case "/final":
+			body, _ := io.ReadAll(r.Body)
+			gotMethod, gotBody = r.Method, string(body)
+		}
+	}))
+	defer srv.Close()
+
+	for _, status := range []string{"307", "308"} {
+		// -progress wraps the body, the redirected request is sent from GetBody instead
+		for _, progress := range []time.Duration{0, time.Millisecond} {
+			gotMethod, gotBody = "", ""
+			runner := &Runner{Client: srv.Client(), Retry: 1, Progress: progress, Report: ReportOptions{Sink: DiscardSink{}}}
+			reqData := NewURLRequest(srv.URL + "/" + status)
+			reqData.Method = http.MethodPost
+			reqData.Body = `{"order":42}`
+			if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+				t.Fatalf("%s with progress %s: request failed: %v", status, progress, outcome.Err)
+			}
+			if gotMethod != http.MethodPost || gotBody != `{"order":42}` {
+				t.Errorf("%s with progress %s: final endpoint got %s with body %q, want the POST body", status, progress, gotMethod, gotBody)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metricsPrefix namespaces the metrics written by -metrics-file
+const metricsPrefix = "http2test_"
+
+// WriteMetricsFile writes the summary in the Prometheus text exposition format for the node_exporter textfile collector.
+// The file is replaced atomically so the collector never reads a partial file.
+func WriteMetricsFile(path string, summary Summary, now time.Time) error {
+	var b strings.Builder
+
+	gauge := func(name, help string, samples ...string) {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n# TYPE %s%s gauge\n", metricsPrefix, name, help, metricsPrefix, name)
+		for _, sample := range samples {
+			fmt.Fprintf(&b, "%s%s%s\n", metricsPrefix, name, sample)
+		}
+	}
+
+	success := 0
+	if summary.Pass {
+		success = 1
+	}
+
+	gauge("requests_total", "Requests sent in the last run.", fmt.Sprintf(" %d", summary.Total))
+	gauge("requests_passed", "Requests of the last run that passed.", fmt.Sprintf(" %d", summary.Passed))
+	gauge("requests_failed", "Requests of the last run that failed.", fmt.Sprintf(" %d", summary.Failed))
+	gauge("run_success", "Whether the last run passed, 1 or 0.", fmt.Sprintf(" %d", success))
+	gauge("last_run_timestamp_seconds", "Unix time the last run finished.", fmt.Sprintf(" %d", now.Unix()))
+
+	if latency := summary.Latency; latency != nil {
+		stats := []struct {
+			name string
+			ms   float64
+		}{
+			{"min", latency.MinMS},
+			{"mean", latency.MeanMS},
+			{"p50", latency.P50MS},
+			{"p95", latency.P95MS},
+			{"p99", latency.P99MS},
+			{"max", latency.MaxMS},
+		}
+
+		var samples []string
+		for _, stat := range stats {
+			samples = append(samples, fmt.Sprintf("{stat=%q} %.6f", stat.name, stat.ms/1000))
+		}
+		gauge("request_latency_seconds", "Request latency of the last run.", samples...)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsFileFromSummary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "http2test.prom")
+	now := time.Unix(1700000000, 0)
+	summary := Summary{Total: 3, Passed: 2, Failed: 1, Latency: &LatencyStats{MinMS: 10, MeanMS: 20, P50MS: 15, P95MS: 40, P99MS: 45, MaxMS: 50}}
+	if err := WriteMetricsFile(path, summary, now); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE http2test_requests_total gauge\nhttp2test_requests_total 3\n",
+		"http2test_requests_passed 2\n",
+		"http2test_requests_failed 1\n",
+		"http2test_run_success 0\n",
+		"http2test_last_run_timestamp_seconds 1700000000\n",
+		`http2test_request_latency_seconds{stat="p95"} 0.040000` + "\n",
+		`http2test_request_latency_seconds{stat="max"} 0.050000` + "\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("metrics file is missing %q:\n%s", want, data)
+		}
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftovers) > 0 {
+		t.Errorf("temporary files were left behind: %v", leftovers)
+	}
+}
+
+func TestMetricsFileFlag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.prom")
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-repeat", "2", "-metrics-file", path, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range []string{`(?m)^http2test_requests_total 2$`, `(?m)^http2test_run_success 1$`, `(?m)^http2test_request_latency_seconds\{stat="p50"\} \d+\.\d{6}$`} {
+		if !regexp.MustCompile(pattern).Match(data) {
+			t.Errorf("metrics file does not match %s:\n%s", pattern, data)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+)
+
+// mirror sends a copy of the request to Mirror in the background. Its outcome is printed and written
+// to a -mirror report but never affects whether the request passed.
+func (r *Runner) mirror(reqData RequestData, outputPath string) {
+	mirroredURL, err := rebaseURL(reqData.URL, r.Mirror)
+	if err != nil {
+		printError("mirror:", err)
+		return
+	}
+
+	mirrored := reqData
+	mirrored.URL = mirroredURL
+	mirrored.Headers = maps.Clone(reqData.Headers)
+	if err := r.authorize(&mirrored); err != nil {
+		printError("mirror:", err)
+		return
+	}
+	shown := maskRequestSecrets(mirrored, secretValues(r.Secrets))
+
+	r.mirrors.Add(1)
+	go func() {
+		defer r.mirrors.Done()
+
+		result, err := Execute(context.Background(), r.Client, mirrored)
+		if err != nil {
+			printError("mirror:", shown.Method, shown.URL, "->", err)
+			return
+		}
+		printInfo(fmt.Sprintf("mirror: %s %s -> %s (%.1fms)", shown.Method, shown.URL, result.Response.Status, milliseconds(result.Latency)))
+
+		if err := GenerateReport(outputPath+"-mirror", mirrored, result, r.Report); err != nil {
+			printError("mirror:", err)
+		}
+	}()
+}
+
+// WaitMirrors blocks until every mirrored request has finished
+func (r *Runner) WaitMirrors() {
+	r.mirrors.Wait()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMirrorGetsCopyWithoutAffectingExitCode(t *testing.T) {
+	var primaryStatus atomic.Int64
+	var primaryGot, mirrorGot atomic.Value
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		primaryGot.Store(r.Method + " " + r.URL.RequestURI() + " " + string(body))
+		w.WriteHeader(int(primaryStatus.Load()))
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mirrorGot.Store(r.Method + " " + r.URL.RequestURI() + " " + string(body))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer mirror.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "order.http", "POST "+primary.URL+"/orders?dry=1\n\n{\"item\":\"book\"}\n")
+	want := `POST /orders?dry=1 {"item":"book"}`
+
+	primaryStatus.Store(http.StatusOK)
+	_, stderr, code := runMain(t, dir, "-source", source, "-mirror", mirror.URL, "-output", "ok")
+	if code != 0 {
+		t.Errorf("failing mirror: exit code %d, want 0 since only the primary counts: %s", code, stderr)
+	}
+	if primaryGot.Load() != want || mirrorGot.Load() != want {
+		t.Errorf("primary got %v and mirror got %v, want both to get %s", primaryGot.Load(), mirrorGot.Load(), want)
+	}
+	if report := readReport(t, filepath.Join(dir, "ok-mirror|*.txt")); !strings.Contains(report, "Response Status: 500 Internal Server Error") {
+		t.Errorf("mirror report does not record its 500:\n%s", report)
+	}
+
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	if _, stderr, code := runMain(t, dir, "-source", source, "-mirror", mirror.URL, "-output", "down"); code != 1 {
+		t.Errorf("failing primary: exit code %d, want 1: %s", code, stderr)
+	}
+}
+
+func TestMirrorGetsBearerToken(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"abc123","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer primary.Close()
+	var mirrorAuth atomic.Value
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorAuth.Store(r.Header.Get("Authorization"))
+	}))
+	defer mirror.Close()
+
+	runner := &Runner{
+		Client: primary.Client(),
+		Retry:  1,
+		Mirror: mirror.URL,
+		Tokens: NewTokenSource(tokenSrv.Client(), OAuthConfig{TokenURL: tokenSrv.URL, ClientID: "id", ClientSecret: "secret"}),
+		Report: ReportOptions{Sink: DiscardSink{}},
+	}
+	if outcome := runner.Run(NewURLRequest(primary.URL+"/orders"), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	runner.WaitMirrors()
+
+	if got := mirrorAuth.Load(); got != "Bearer abc123" {
+		t.Errorf("mirror got Authorization %v, want the bearer token", got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// namePlaceholderPattern matches the {field} placeholders of a -name-template
+var namePlaceholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// unsafeNameChars are replaced in the request values put into report names, keeping them to one path element
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// nameFields are the -name-template placeholders
+var nameFields = []string{"method", "host", "path", "status", "timestamp"}
+
+// checkNameTemplate rejects a -name-template with placeholders other than nameFields
+func checkNameTemplate(template string) error {
+	for _, m := range namePlaceholderPattern.FindAllStringSubmatch(template, -1) {
+		if !slices.Contains(nameFields, m[1]) {
+			return fmt.Errorf("invalid -name-template %q: unknown placeholder {%s}, expected one of {%s}", template, m[1], strings.Join(nameFields, "}, {"))
+		}
+	}
+	if strings.ContainsAny(namePlaceholderPattern.ReplaceAllString(template, ""), `/\`) {
+		return fmt.Errorf("invalid -name-template %q: must not contain path separators, -output sets the directory", template)
+	}
+	return nil
+}
+
+// reportName renders a -name-template for the request and response. A .txt or .json ending is dropped
+// because every -format adds its own extension.
+func reportName(template string, reqData RequestData, statusCode int, now time.Time) string {
+	host, path := reqData.URL, ""
+	if u, err := url.Parse(reqData.URL); err == nil {
+		host, path = u.Host, strings.TrimPrefix(u.Path, "/")
+	}
+
+	fields := map[string]string{
+		"method":    reqData.Method,
+		"host":      host,
+		"path":      path,
+		"status":    strconv.Itoa(statusCode),
+		"timestamp": strconv.FormatInt(now.Unix(), 10),
+	}
+	name := namePlaceholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		value := strings.Trim(unsafeNameChars.ReplaceAllString(fields[match[1:len(match)-1]], "_"), "_")
+		if value == "" {
+			value = "_"
+		}
+		return value
+	})
+
+	for format := range reportRenderers {
+		name = strings.TrimSuffix(name, "."+format)
+	}
+	return name
+}
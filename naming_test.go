@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportNameSubstitutesRequestFields(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	reqData := NewURLRequest("https://api.example.com:8443/v1/users/42?full=1")
+	reqData.Method = http.MethodDelete
+
+	tests := []struct{ template, want string }{
+		{"{method}-{host}-{status}.txt", "DELETE-api.example.com_8443-404"},
+		{"{path}_{timestamp}", "v1_users_42_1700000000"},
+		{"run-{status}.json", "run-404"},
+	}
+	for _, tt := range tests {
+		if got := reportName(tt.template, reqData, http.StatusNotFound, now); got != tt.want {
+			t.Errorf("reportName(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+
+	root := NewURLRequest("http://localhost/")
+	if got := reportName("{host}-{path}", root, http.StatusOK, now); got != "localhost-_" {
+		t.Errorf("empty path renders as %q, want localhost-_", got)
+	}
+
+	for _, template := range []string{"{query}", "reports/{status}"} {
+		if err := checkNameTemplate(template); err == nil {
+			t.Errorf("checkNameTemplate(%q) accepted an invalid template", template)
+		}
+	}
+}
+
+func TestNameTemplateNamesReportFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/orders/new", "-method", "POST", "-name-template", "{method}-{host}-{path}-{status}.txt", "-output", filepath.Join(dir, "reports", "out")); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	host := strings.ReplaceAll(strings.TrimPrefix(srv.URL, "http://"), ":", "_")
+	want := filepath.Join(dir, "reports", "POST-"+host+"-orders_new-201.txt")
+	if _, err := os.Stat(want); err != nil {
+		entries, _ := filepath.Glob(filepath.Join(dir, "reports", "*"))
+		t.Errorf("report %s was not written, got %v", want, entries)
+	}
+}
+
+func TestOutputDirectiveNamesReports(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("served " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "flow.http", "# @output login-report\nPOST "+srv.URL+"/login\n\n###\n# @output orders-report\nGET "+srv.URL+"/orders\n\n###\nGET "+srv.URL+"/logout\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", filepath.Join(dir, "run")); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	for pattern, body := range map[string]string{
+		"login-report|*.txt":  "served /login",
+		"orders-report|*.txt": "served /orders",
+		"run-3|*.txt":         "served /logout",
+	} {
+		if report := readReport(t, filepath.Join(dir, pattern)); !strings.Contains(report, body) {
+			t.Errorf("report %s is not for the request it names:\n%s", pattern, report)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetrcEntry holds the credentials of a machine entry, or of the default entry when Machine is empty
+type NetrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+// Netrc is a parsed .netrc file
+type Netrc struct {
+	Entries []NetrcEntry
+}
+
+// defaultNetrcPath returns ~/.netrc
+func defaultNetrcPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".netrc"
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// LoadNetrc reads and parses a .netrc file
+func LoadNetrc(path string) (*Netrc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseNetrc(string(data)), nil
+}
+
+// parseNetrc reads the machine, default, login and password tokens, skipping macdef bodies
+func parseNetrc(data string) *Netrc {
+	netrc := &Netrc{}
+	var entry *NetrcEntry
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			next := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+
+			switch fields[j] {
+			case "machine":
+				netrc.Entries = append(netrc.Entries, NetrcEntry{Machine: next()})
+				entry = &netrc.Entries[len(netrc.Entries)-1]
+			case "default":
+				netrc.Entries = append(netrc.Entries, NetrcEntry{})
+				entry = &netrc.Entries[len(netrc.Entries)-1]
+			case "login":
+				if entry != nil {
+					entry.Login = next()
+				}
+			case "password":
+				if entry != nil {
+					entry.Password = next()
+				}
+			case "account":
+				next()
+			case "macdef":
+				// A macro runs until the next blank line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+
+	return netrc
+}
+
+// Lookup returns the entry for the host, falling back to the default entry
+func (n *Netrc) Lookup(host string) (NetrcEntry, bool) {
+	var fallback *NetrcEntry
+	for i, entry := range n.Entries {
+		if entry.Machine == "" {
+			if fallback == nil {
+				fallback = &n.Entries[i]
+			}
+			continue
+		}
+		if strings.EqualFold(entry.Machine, host) {
+			return entry, true
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return NetrcEntry{}, false
+}
+
+// applyNetrc adds basic auth from the netrc entry of the request host unless the request already carries credentials
+func applyNetrc(reqData *RequestData, netrc *Netrc) {
+	if hasHeader(reqData.Headers, "Authorization") {
+		return
+	}
+
+	u, err := url.Parse(reqData.URL)
+	if err != nil || u.User != nil {
+		return
+	}
+
+	entry, ok := netrc.Lookup(u.Hostname())
+	if !ok || entry.Login == "" {
+		return
+	}
+
+	credentials := base64.StdEncoding.EncodeToString([]byte(entry.Login + ":" + entry.Password))
+	reqData.Headers["Authorization"] = "Basic " + credentials
+}
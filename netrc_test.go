@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNetrcAppliesMatchingCredentials(t *testing.T) {
+	var gotAuth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	home := t.TempDir()
+	writeFile(t, home, ".netrc", "machine other.example.com login bob password nope\n\nmachine 127.0.0.1\n  login alice\n  password s3cret\n")
+	t.Setenv("HOME", home)
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "auth.http", "GET "+srv.URL+"/netrc\n\n###\nGET "+srv.URL+"/explicit\nAuthorization: Bearer mine\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-netrc", "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	// alice:s3cret
+	want := []string{"Basic YWxpY2U6czNjcmV0", "Bearer mine"}
+	if len(gotAuth) != 2 || gotAuth[0] != want[0] || gotAuth[1] != want[1] {
+		t.Errorf("server got Authorization %q, want %q", gotAuth, want)
+	}
+}
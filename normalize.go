@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// normalizeJSON canonicalizes a JSON document with sorted keys and no insignificant whitespace.
+// Bodies that are not JSON are returned unchanged.
+func normalizeJSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return body
+	}
+
+	// encoding/json writes map keys in sorted order
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// stripJSONPaths removes the values the JSONPaths match from a JSON body and returns it normalized,
+// see normalizeJSON. Bodies that are not JSON are returned unchanged.
+func stripJSONPaths(body []byte, paths [][]jsonPathSegment) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return body
+	}
+	for _, segments := range paths {
+		doc = deleteJSONPath(doc, segments)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// responseHash is the hex SHA-256 of the status code and the normalized body, so the same response
+// hashes the same across runs whatever its JSON key order, whitespace or line endings
+func responseHash(result *Result) string {
+	body := bytes.ReplaceAll(result.Body, []byte("\r\n"), []byte("\n"))
+	body = bytes.TrimSpace(normalizeJSON(body))
+
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(result.Response.StatusCode) + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
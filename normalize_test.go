@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestNormalizeJSON(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"sorted keys", `{"b": 1, "a": {"d": [1, 2], "c": true}}`, `{"a":{"c":true,"d":[1,2]},"b":1}`},
+		{"big numbers keep digits", `{"id": 12345678901234567890, "f": 1.50}`, `{"f":1.50,"id":12345678901234567890}`},
+		{"html not escaped", `{"html": "<a>&</a>"}`, `{"html":"<a>&</a>"}`},
+		{"not JSON", "plain text", "plain text"},
+		{"trailing data", `{"a":1} {"b":2}`, `{"a":1} {"b":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeJSON([]byte(tt.body))); got != tt.want {
+				t.Errorf("normalizeJSON(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeJSONMakesEqualBodiesCompareEqual(t *testing.T) {
+	a := normalizeJSON([]byte("{\n  \"name\": \"x\",\n  \"id\": 1\n}"))
+	b := normalizeJSON([]byte(`{"id":1,"name":"x"}`))
+	if string(a) != string(b) {
+		t.Errorf("%s and %s differ after normalizing", a, b)
+	}
+}
+
+func TestReportHashDetectsChangedBody(t *testing.T) {
+	body := `{"id": 1, "name": "x"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	hashPattern := regexp.MustCompile(`Response Hash: ([0-9a-f]{64})\n`)
+	hash := func() string {
+		t.Helper()
+		sink := &memorySink{}
+		runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, ResponseHash: true}}
+		if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+			t.Fatalf("request failed: %v", outcome.Err)
+		}
+		report := sink.report(t, ".txt")
+		m := hashPattern.FindStringSubmatch(report)
+		if m == nil {
+			t.Fatalf("report has no Response Hash:\n%s", report)
+		}
+		return m[1]
+	}
+
+	first, second := hash(), hash()
+	if first != second {
+		t.Errorf("identical responses hash to %s and %s, want the same", first, second)
+	}
+	body = "{\n  \"name\": \"x\",\n  \"id\": 1\n}\n"
+	if reordered := hash(); reordered != first {
+		t.Errorf("the same JSON with other key order and whitespace hashes to %s, want %s", reordered, first)
+	}
+	body = `{"id": 2, "name": "x"}`
+	if changed := hash(); changed == first {
+		t.Errorf("a changed body hashes to %s like the original, want a different hash", changed)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuthConfig holds the settings for the OAuth2 client-credentials grant
+type OAuthConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+}
+
+// TokenSource fetches an OAuth2 access token and caches it until it expires
+type TokenSource struct {
+	config OAuthConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// NewTokenSource creates a TokenSource that requests tokens with the given client
+func NewTokenSource(client *http.Client, config OAuthConfig) *TokenSource {
+	return &TokenSource{config: config, client: client}
+}
+
+// Token returns the cached access token, fetching a new one when it is missing or expired
+func (ts *TokenSource) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && (ts.expiry.IsZero() || time.Now().Before(ts.expiry)) {
+		return ts.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if ts.config.Scope != "" {
+		form.Set("scope", ts.config.Scope)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, ts.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(ts.config.ClientID), url.QueryEscape(ts.config.ClientSecret))
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("oauth token request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oauth token request: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("oauth token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("oauth token response: missing access_token")
+	}
+
+	ts.token = tokenResp.AccessToken
+	ts.expiry = time.Time{}
+	if tokenResp.ExpiresIn > 0 {
+		// Refresh slightly early so the token does not expire mid-request
+		ts.expiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn)*time.Second - 10*time.Second)
+	}
+
+	return ts.token, nil
+}
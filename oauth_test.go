@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTokenSourceFetchesAndCachesToken(t *testing.T) {
+	var tokenRequests atomic.Int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		user, password, _ := r.BasicAuth()
+		if r.FormValue("grant_type") != "client_credentials" || user != "id" || password != "secret" || r.FormValue("scope") != "read" {
+			http.Error(w, "bad client", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"abc123","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+
+	var authorizations []string
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+	}))
+	defer apiSrv.Close()
+
+	runner := &Runner{
+		Client: apiSrv.Client(),
+		Retry:  1,
+		Tokens: NewTokenSource(tokenSrv.Client(), OAuthConfig{TokenURL: tokenSrv.URL, ClientID: "id", ClientSecret: "secret", Scope: "read"}),
+		Report: ReportOptions{Sink: DiscardSink{}},
+	}
+	for range 2 {
+		if outcome := runner.Run(NewURLRequest(apiSrv.URL), "out"); !outcome.Passed {
+			t.Fatalf("request failed: %v", outcome.Err)
+		}
+	}
+
+	for _, got := range authorizations {
+		if got != "Bearer abc123" {
+			t.Errorf("Authorization = %q, want Bearer abc123", got)
+		}
+	}
+	if n := tokenRequests.Load(); n != 1 {
+		t.Errorf("token endpoint called %d times, want 1", n)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPISpec is an OpenAPI 3 or Swagger 2 document, in YAML or JSON, that responses are validated against
+type OpenAPISpec struct {
+	root map[string]any
+	// basePaths are the path prefixes of the servers, or the Swagger basePath, stripped before matching
+	basePaths []string
+}
+
+// LoadOpenAPISpec reads an OpenAPI document from a YAML or JSON file
+func LoadOpenAPISpec(path string) (*OpenAPISpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec %s: %w", path, err)
+	}
+	root, ok := yamlToJSON(doc).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid OpenAPI spec %s: expected an object", path)
+	}
+	if _, ok := root["paths"].(map[string]any); !ok {
+		return nil, fmt.Errorf("invalid OpenAPI spec %s: no paths", path)
+	}
+
+	spec := &OpenAPISpec{root: root}
+	if basePath, ok := root["basePath"].(string); ok {
+		spec.basePaths = append(spec.basePaths, basePath)
+	}
+	servers, _ := root["servers"].([]any)
+	for _, server := range servers {
+		server, _ := server.(map[string]any)
+		if rawURL, ok := server["url"].(string); ok {
+			if u, err := url.Parse(rawURL); err == nil && u.Path != "" {
+				spec.basePaths = append(spec.basePaths, u.Path)
+			}
+		}
+	}
+	return spec, nil
+}
+
+// yamlToJSON converts a decoded YAML document to the types encoding/json decodes to,
+// so the schema validator handles both: string keyed maps and float64 numbers
+func yamlToJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = yamlToJSON(child)
+		}
+		return v
+	case map[any]any:
+		obj := make(map[string]any, len(v))
+		for k, child := range v {
+			obj[fmt.Sprint(k)] = yamlToJSON(child)
+		}
+		return obj
+	case []any:
+		for i, child := range v {
+			v[i] = yamlToJSON(child)
+		}
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+	return v
+}
+
+// operation finds the operation for the method and path, preferring the path template with the fewest
+// {parameters}. It returns the template, an empty one when no path matches.
+func (s *OpenAPISpec) operation(method, path string) (map[string]any, string) {
+	paths := s.root["paths"].(map[string]any)
+
+	candidates := []string{path}
+	for _, base := range s.basePaths {
+		base = strings.TrimSuffix(base, "/")
+		if rest, ok := strings.CutPrefix(path, base); ok && base != "" && strings.HasPrefix(rest, "/") {
+			candidates = append(candidates, rest)
+		}
+	}
+
+	var best map[string]any
+	bestTemplate, bestParams := "", -1
+	for _, template := range sortedKeys(paths) {
+		item, ok := paths[template].(map[string]any)
+		if !ok {
+			continue
+		}
+		op, ok := item[strings.ToLower(method)].(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, candidate := range candidates {
+			params, ok := matchPathTemplate(template, candidate)
+			if ok && (bestParams < 0 || params < bestParams) {
+				best, bestTemplate, bestParams = op, template, params
+			}
+		}
+	}
+	return best, bestTemplate
+}
+
+// matchPathTemplate reports whether path matches an OpenAPI path template such as /users/{id},
+// and how many {parameters} it took
+func matchPathTemplate(template, path string) (int, bool) {
+	want := strings.Split(strings.Trim(template, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return 0, false
+	}
+
+	params := 0
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if got[i] == "" {
+				return 0, false
+			}
+			params++
+		} else if segment != got[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// responseSpec finds the documented response for a status: the exact code, then its range such as 2XX, then default
+func responseSpec(op map[string]any, status int) (map[string]any, bool) {
+	responses, _ := op["responses"].(map[string]any)
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if response, ok := responses[key].(map[string]any); ok {
+			return response, true
+		}
+	}
+	return nil, false
+}
+
+// responseSchema returns the schema of the documented response for the content type, nil when it documents none.
+// OpenAPI 3 keys schemas by media type, Swagger 2 has a single schema.
+func responseSchema(response map[string]any, contentType string) map[string]any {
+	if schema, ok := response["schema"].(map[string]any); ok {
+		return schema
+	}
+
+	content, _ := response["content"].(map[string]any)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	keys := []string{mediaType}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		keys = append(keys, major+"/*")
+	}
+	keys = append(keys, "application/json", "*/*")
+	for _, key := range keys {
+		if media, ok := content[key].(map[string]any); ok {
+			schema, _ := media["schema"].(map[string]any)
+			return schema
+		}
+	}
+	return nil
+}
+
+// assertOpenAPI checks that the response status is documented for the request's operation and the body matches its schema
+func assertOpenAPI(spec *OpenAPISpec) Assertion {
+	return func(result *Result) error {
+		req := result.Response.Request
+		if req == nil {
+			return fmt.Errorf("openapi: no request to match an operation against")
+		}
+
+		op, template := spec.operation(req.Method, req.URL.Path)
+		if op == nil {
+			return fmt.Errorf("openapi: no operation for %s %s", req.Method, req.URL.Path)
+		}
+		operation := req.Method + " " + template
+
+		response, ok := responseSpec(op, result.Response.StatusCode)
+		if !ok {
+			return fmt.Errorf("openapi: %s does not document status %d", operation, result.Response.StatusCode)
+		}
+		schema := responseSchema(response, result.Response.Header.Get("Content-Type"))
+		if schema == nil {
+			return nil
+		}
+
+		var body any
+		if err := json.Unmarshal(result.Body, &body); err != nil {
+			return fmt.Errorf("openapi: %s: response body is not valid JSON: %v", operation, err)
+		}
+
+		var errs []error
+		for _, violation := range validateSchemaIn(spec.root, schema, body) {
+			errs = append(errs, fmt.Errorf("openapi: %s: %s", operation, violation))
+		}
+		return errors.Join(errs...)
+	}
+}
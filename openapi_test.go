@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const userSpec = `openapi: 3.0.0
+servers:
+  - url: https://api.example.com/api/v1
+paths:
+  /users/{id}:
+    get:
+      responses:
+        "200":
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+components:
+  schemas:
+    User:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+`
+
+func TestOpenAPIValidatesResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/users/1":
+			w.Write([]byte(`{"id": 1, "name": "alice"}`))
+		case "/api/v1/users/2":
+			w.Write([]byte(`{"id": "2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	spec := writeFile(t, dir, "spec.yaml", userSpec)
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL+"/api/v1/users/1", "-openapi", spec, "-output", "conforms"); code != 0 {
+		t.Errorf("conforming response: exit code %d, want 0: %s", code, stderr)
+	}
+
+	_, stderr, code := runMain(t, dir, "-url", srv.URL+"/api/v1/users/2", "-openapi", spec, "-output", "violates")
+	if code != 1 || !strings.Contains(stderr, `openapi: GET /users/{id}: $: missing required property "name"`) || !strings.Contains(stderr, "openapi: GET /users/{id}: $.id: expected integer, got string") {
+		t.Errorf("violating response: exit code %d, want 1 naming the wrong id and missing name: %s", code, stderr)
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL+"/api/v1/users/3", "-openapi", spec, "-expect-status", "404", "-output", "undocumented")
+	if code != 1 || !strings.Contains(stderr, "openapi: GET /users/{id} does not document status 404") {
+		t.Errorf("undocumented status: exit code %d, want 1 with the status violation: %s", code, stderr)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+var (
+	// stdout receives informational output, stderr receives errors
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+
+	// quiet suppresses informational output
+	quiet bool
+
+	// warnings counts the warnings printed, -fail-on-warnings exits non-zero when there are any
+	warnings atomic.Int64
+
+	// color wraps the status in status lines with ANSI color codes
+	color bool
+)
+
+// ANSI escape codes used to color statuses
+const (
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+	ansiReset  = "\x1b[0m"
+)
+
+// colorEnabled resolves a -color mode, auto colors only when stdout is a terminal and NO_COLOR is unset
+func colorEnabled(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid -color %q, expected auto, always or never", mode)
+}
+
+// colorize wraps text in the color for the status code: green 2xx, yellow 3xx, red 4xx and 5xx
+func colorize(text string, statusCode int) string {
+	if !color {
+		return text
+	}
+	switch {
+	case statusCode >= 400:
+		return ansiRed + text + ansiReset
+	case statusCode >= 300:
+		return ansiYellow + text + ansiReset
+	case statusCode >= 200:
+		return ansiGreen + text + ansiReset
+	}
+	return text
+}
+
+// printInfo writes an informational line to stdout unless running quietly
+func printInfo(a ...any) {
+	if quiet {
+		return
+	}
+	fmt.Fprintln(stdout, a...)
+}
+
+// printError writes an error line to stderr
+func printError(a ...any) {
+	fmt.Fprintln(stderr, a...)
+}
+
+// printWarning writes a warning line to stderr and counts it
+func printWarning(a ...any) {
+	warnings.Add(1)
+	printError(append([]any{"warning:"}, a...)...)
+}
+
+// fatal prints the error and exits with a non-zero status
+func fatal(a ...any) {
+	printError(a...)
+	os.Exit(1)
+}
+
+// statusLine summarizes the outcome of a request in one line
+func statusLine(o Outcome) string {
+	line := fmt.Sprintf("%s %s", o.Request.Method, o.Request.URL)
+	if o.Result != nil {
+		line += fmt.Sprintf(" -> %s (%.1fms)", colorize(o.Result.Response.Status, o.Result.Response.StatusCode), milliseconds(o.Latency()))
+	}
+	if o.TraceID != "" {
+		line += " [trace " + o.TraceID + "]"
+	}
+	if o.Err != nil {
+		line += " -> " + colorize(fmt.Sprintf("error: %v", o.Err), 500)
+	} else if !o.Passed {
+		line += " -> " + colorize("assertion failed", 500)
+	}
+	return line
+}
+
+// writeStatusTable writes the status of every outcome as an aligned table, -status-only prints it instead of status lines
+func writeStatusTable(w io.Writer, outcomes []Outcome) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REQUEST\tSTATUS")
+	for _, o := range outcomes {
+		status := "error"
+		if o.Result != nil {
+			status = fmt.Sprint(o.Result.Response.StatusCode)
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\n", o.Request.Method, o.Request.URL, status)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestQuietLeavesStdoutEmpty(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "-quiet", "-url", srv.URL+"/ok", "-output", "ok")
+	if code != 0 || stdout != "" || stderr != "" {
+		t.Errorf("passing request: exit code %d, stdout %q, stderr %q, want 0 and no output", code, stdout, stderr)
+	}
+	if report := readReport(t, filepath.Join(dir, "ok|*.txt")); !strings.Contains(report, "Response Status: 200 OK") {
+		t.Errorf("quiet run wrote no report:\n%s", report)
+	}
+
+	stdout, _, code = runMain(t, dir, "-quiet", "-url", srv.URL+"/fail", "-output", "fail")
+	if code != 1 || stdout != "" {
+		t.Errorf("failing request: exit code %d, stdout %q, want 1 and no output", code, stdout)
+	}
+}
+
+func TestColorModes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "-color", "always", "-url", srv.URL, "-output", "always")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, ansiGreen+"200 OK"+ansiReset) {
+		t.Errorf("-color always did not color the 200 status green:\n%q", stdout)
+	}
+
+	stdout, _, _ = runMain(t, dir, "-color", "never", "-url", srv.URL, "-output", "never")
+	if strings.Contains(stdout, "\x1b[") || !strings.Contains(stdout, "200 OK") {
+		t.Errorf("-color never printed color codes or no status:\n%q", stdout)
+	}
+}
+
+func TestHexDumpOfBinaryBody(t *testing.T) {
+	body := []byte{0x00, 0x01, 0x02, 'h', 'i', 0xff, 0x7f, 0x80, 0x10, 0x20, 'A', 'B', 'C', 'D', 'E', 'F', 'G', '\n'}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, HexDump: true}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	want := "00000000  00 01 02 68 69 ff 7f 80  10 20 41 42 43 44 45 46  |...hi.... ABCDEF|\n" +
+		"00000010  47 0a                                             |G.|\n"
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "Response Body:\n"+want) {
+		t.Errorf("report does not hold the canonical dump\n%s\nof the body:\n%s", want, report)
+	}
+}
+
+func TestFailOnWarningsExitCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// A GET with a body is sent as written but warns that servers may ignore it
+	dir := t.TempDir()
+	source := writeFile(t, dir, "get.http", "GET "+srv.URL+"\nContent-Type: text/plain\n\nignored body\n")
+	_, stderr, code := runMain(t, dir, "-source", source, "-output", "plain")
+	if code != 0 || !strings.Contains(stderr, "warning: GET "+srv.URL+" has a body") {
+		t.Errorf("without the flag: exit code %d, want 0 with the warning printed: %s", code, stderr)
+	}
+
+	_, stderr, code = runMain(t, dir, "-source", source, "-fail-on-warnings", "-output", "strict")
+	if code == 0 || !strings.Contains(stderr, "-fail-on-warnings: 1 warning(s) printed") {
+		t.Errorf("with -fail-on-warnings: exit code %d, want non-zero with the warning count: %s", code, stderr)
+	}
+}
+
+func TestStatusOnlyTable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "health.http", "GET "+srv.URL+"/ok\n\n###\nGET "+srv.URL+"/missing\n\n###\nGET "+srv.URL+"/down\n")
+	stdout, stderr, _ := runMain(t, dir, "-source", source, "-status-only", "-retry", "1")
+
+	for _, want := range []string{
+		`(?m)^REQUEST\s+STATUS$`,
+		`(?m)^GET ` + regexp.QuoteMeta(srv.URL) + `/ok\s+200$`,
+		`(?m)^GET ` + regexp.QuoteMeta(srv.URL) + `/missing\s+404$`,
+		`(?m)^GET ` + regexp.QuoteMeta(srv.URL) + `/down\s+503$`,
+	} {
+		if !regexp.MustCompile(want).MatchString(stdout) {
+			t.Errorf("status table does not match %s:\n%s%s", want, stdout, stderr)
+		}
+	}
+	if reports, _ := filepath.Glob(filepath.Join(dir, "*.txt")); len(reports) > 0 {
+		t.Errorf("-status-only wrote reports %v", reports)
+	}
+}
+
+func TestSaveOnFailureOnlyKeepsFullReportsForFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte("body of " + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: sink, FailuresOnly: true, Formats: []string{"txt", "json"}}}
+	runner.Run(NewURLRequest(srv.URL+"/ok"), "ok")
+	runner.Run(NewURLRequest(srv.URL+"/fail"), "fail")
+
+	var passed, failed []string
+	for _, name := range sink.names() {
+		if strings.HasPrefix(name, "ok|") {
+			passed = append(passed, name)
+		} else {
+			failed = append(failed, name)
+		}
+	}
+	if len(passed) != 1 || !strings.HasSuffix(passed[0], ".txt") {
+		t.Fatalf("passing request wrote %v, want a single txt record", passed)
+	}
+	if record := sink.report(t, passed[0]); !regexp.MustCompile(`^Passed: GET ` + regexp.QuoteMeta(srv.URL) + `/ok -> 200 OK \([0-9.]+ms\)\n$`).MatchString(record) {
+		t.Errorf("passing request record is not one summary line:\n%s", record)
+	}
+
+	if len(failed) != 2 {
+		t.Fatalf("failing request wrote %v, want its txt and json reports", failed)
+	}
+	for _, name := range failed {
+		if report := sink.report(t, name); !strings.Contains(report, "body of /fail") {
+			t.Errorf("failing request report %s does not keep the body:\n%s", name, report)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// linkNextSelector selects the next page from the Link response header
+const linkNextSelector = "Link rel=next"
+
+var linkPattern = regexp.MustCompile(`<([^>]*)>\s*((?:;\s*[^,;]+)*)`)
+
+// nextPageURL finds the next page link of a response, returning "" when there is none.
+// The selector is either "Link rel=next" or a JSONPath into the JSON body, such as $.links.next.
+func nextPageURL(result *Result, currentURL, selector string) (string, error) {
+	var next string
+
+	if selector == "" || strings.EqualFold(selector, linkNextSelector) {
+		next = linkRelNext(result.Response.Header.Values("Link"))
+	} else {
+		var body any
+		if err := json.Unmarshal(result.Body, &body); err != nil {
+			return "", fmt.Errorf("paginate: response body is not valid JSON: %w", err)
+		}
+		value, ok, err := lookupJSONPath(body, selector)
+		if err != nil {
+			return "", err
+		}
+		if ok && value != nil {
+			next = jsonValueString(value)
+		}
+	}
+
+	if next == "" {
+		return "", nil
+	}
+
+	// Next links are often relative to the page they came from
+	base, err := url.Parse(currentURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("paginate: invalid next link %q: %w", next, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// linkRelNext returns the target of the rel="next" entry of Link header values
+func linkRelNext(values []string) string {
+	for _, value := range values {
+		for _, m := range linkPattern.FindAllStringSubmatch(value, -1) {
+			for _, param := range strings.Split(m[2], ";") {
+				name, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return m[1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// paginate follows next links from the first page and writes every page into one report
+func (r *Runner) paginate(reqData RequestData, outputPath string) Outcome {
+	outcome := Outcome{Request: reqData}
+	seen := map[string]bool{}
+	var pages []*Result
+	var pageURLs []string
+
+	page := reqData
+	for len(pages) < max(r.MaxPages, 1) {
+		seen[page.URL] = true
+
+		start := time.Now()
+		result, err := Execute(context.Background(), r.Client, page)
+		attempt := NewAttempt(len(pages)+1, result, time.Since(start), err)
+		outcome.Attempts = append(outcome.Attempts, attempt)
+		if err != nil {
+			printError(err)
+			outcome.Err = err
+			break
+		}
+
+		runAssertions(result, r.Assertions)
+		outcome.Result = result
+		pages = append(pages, result)
+		pageURLs = append(pageURLs, page.URL)
+
+		if attempt.Failed() || result.Failed() {
+			break
+		}
+
+		next, err := nextPageURL(result, page.URL, r.NextSelector)
+		if err != nil {
+			printError(err)
+			outcome.Err = err
+			break
+		}
+		if next == "" || seen[next] {
+			break
+		}
+		page.URL = next
+	}
+
+	if len(pages) > 0 {
+		if err := GeneratePagesReport(r.Report.sink(), outputPath, reqData, pageURLs, pages); err != nil {
+			printError(err)
+			outcome.Err = err
+			return outcome
+		}
+	}
+
+	if outcome.Err == nil && !outcome.Attempts[len(outcome.Attempts)-1].Failed() && !outcome.Result.Failed() {
+		outcome.Passed = true
+	}
+	return outcome
+}
+
+// GeneratePagesReport writes every fetched page of a paginated request into one report
+func GeneratePagesReport(sink ReportSink, outputPath string, reqData RequestData, urls []string, pages []*Result) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-pages.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nRequest URL: %s\nPages: %d\n", reqData.Method, reqData.URL, len(pages)))
+	if err != nil {
+		return err
+	}
+
+	for i, page := range pages {
+		_, err = io.WriteString(file, fmt.Sprintf("\nPage %d: %s\nResponse Status: %s\nResponse Body:\n%s\n", i+1, urls[i], page.Response.Status, page.Body))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
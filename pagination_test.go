@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPaginateFollowsNextLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"items":[3]}`))
+			return
+		}
+		w.Header().Set("Link", `</items?page=2>; rel="next", </items?page=1>; rel="first"`)
+		w.Write([]byte(`{"items":[1,2]}`))
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Paginate: true, NextSelector: linkNextSelector, MaxPages: 10, Report: ReportOptions{Sink: sink}}
+	outcome := runner.Run(NewURLRequest(srv.URL+"/items"), "out")
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, "-pages.txt")
+	for _, want := range []string{
+		"Pages: 2",
+		"Page 1: " + srv.URL + "/items\nResponse Status: 200 OK\nResponse Body:\n{\"items\":[1,2]}",
+		"Page 2: " + srv.URL + "/items?page=2\nResponse Status: 200 OK\nResponse Body:\n{\"items\":[3]}",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("pages report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestPaginateWithJSONPathSelector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page2" {
+			w.Write([]byte(`{"next":null}`))
+			return
+		}
+		w.Write([]byte(`{"next":"/page2"}`))
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 1, Paginate: true, NextSelector: "$.next", MaxPages: 10, Report: ReportOptions{Sink: DiscardSink{}}}
+	outcome := runner.Run(NewURLRequest(srv.URL+"/page1"), "out")
+	if !outcome.Passed || len(outcome.Attempts) != 2 {
+		t.Errorf("passed %t after %d pages, want 2 pages", outcome.Passed, len(outcome.Attempts))
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseOptions controls how ReadHTTPFile parses a .http file
+type ParseOptions struct {
+	// MaxBodyBytes rejects request bodies larger than this, 0 means no limit
+	MaxBodyBytes int64
+	// Strict rejects unknown # @directives instead of ignoring them with a warning
+	Strict bool
+}
+
+// directivePattern matches "# @name value" and "// @name value" comment lines
+var directivePattern = regexp.MustCompile(`^(?:#|//)\s*@([\w-]+)\s*(.*)$`)
+
+// directives applies each supported "# @name value" directive to the request that follows it
+var directives = map[string]func(reqData *RequestData, value string) error{
+	"delay": func(reqData *RequestData, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid @delay %q: %w", value, err)
+		}
+		reqData.Delay = d
+		return nil
+	},
+	"expect": func(reqData *RequestData, value string) error {
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 100 || status > 999 {
+			return fmt.Errorf("invalid @expect %q, expected a status code", value)
+		}
+		reqData.ExpectStatus = status
+		return nil
+	},
+	"save-body": func(reqData *RequestData, value string) error {
+		if value == "" {
+			return fmt.Errorf("@save-body requires a path")
+		}
+		reqData.SaveBody = value
+		return nil
+	},
+	"output": func(reqData *RequestData, value string) error {
+		if value == "" {
+			return fmt.Errorf("@output requires a name")
+		}
+		reqData.Output = value
+		return nil
+	},
+	"name": func(reqData *RequestData, value string) error {
+		if value == "" {
+			return fmt.Errorf("@name requires a value")
+		}
+		reqData.Name = value
+		return nil
+	},
+	"on-success": func(reqData *RequestData, value string) error {
+		return parseBranch(&reqData.OnSuccess, "on-success", value)
+	},
+	"on-failure": func(reqData *RequestData, value string) error {
+		return parseBranch(&reqData.OnFailure, "on-failure", value)
+	},
+	"capture": func(reqData *RequestData, value string) error {
+		fields := strings.Fields(value)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid @capture %q, expected <name> <jsonpath>", value)
+		}
+		if _, err := parseJSONPath(fields[1]); err != nil {
+			return err
+		}
+		reqData.Captures = append(reqData.Captures, Capture{Name: fields[0], Path: fields[1]})
+		return nil
+	},
+	"capture-header": func(reqData *RequestData, value string) error {
+		name, header, ok := strings.Cut(value, "=")
+		name, header = strings.TrimSpace(name), strings.TrimSpace(header)
+		if !ok || name == "" || header == "" {
+			return fmt.Errorf("invalid @capture-header %q, expected <name> = <header>", value)
+		}
+		reqData.Captures = append(reqData.Captures, Capture{Name: name, Header: header})
+		return nil
+	},
+	"trailer": func(reqData *RequestData, value string) error {
+		name, trailer, ok := strings.Cut(value, ":")
+		name, trailer = strings.TrimSpace(name), strings.TrimSpace(trailer)
+		if !ok || name == "" {
+			return fmt.Errorf("invalid @trailer %q, expected <name>: <value>", value)
+		}
+		if reqData.Trailers == nil {
+			reqData.Trailers = make(map[string]string)
+		}
+		reqData.Trailers[name] = trailer
+		return nil
+	},
+}
+
+// parseBranch reads the "next=name" value of an @on-success or @on-failure directive
+func parseBranch(target *string, directive, value string) error {
+	name, ok := strings.CutPrefix(value, "next=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid @%s %q, expected next=<name>", directive, value)
+	}
+	*target = name
+	return nil
+}
+
+// ReadHTTPFile parses the .HTTP file and returns the RequestData of every request in it.
+// Requests are separated by lines starting with ###, gzipped files are decompressed first.
+// An http(s):// path is fetched instead of read from disk.
+func ReadHTTPFile(filePath string, opts ParseOptions) ([]RequestData, error) {
+	file, err := openSource(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Gzipped files are recognized by their magic bytes, whatever their extension
+	r := bufio.NewReader(file)
+	if magic, _ := r.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		return parseHTTPRequests(gz, opts)
+	}
+
+	return parseHTTPRequests(r, opts)
+}
+
+// rawRequestLinePattern matches the request line of a raw HTTP/1.x dump, whose target is a path instead of a URL
+var rawRequestLinePattern = regexp.MustCompile(`^[A-Z]+ /\S* HTTP/1\.[01]\r?$`)
+
+// parseHTTPRequests splits the input into request blocks and parses each one.
+// Raw HTTP/1.x request dumps are recognized by their request line and parsed as such.
+func parseHTTPRequests(r io.Reader, opts ParseOptions) ([]RequestData, error) {
+	br := bufio.NewReader(r)
+	if isRawHTTPDump(br) {
+		return parseRawHTTPRequests(br, opts)
+	}
+
+	// Long body lines, such as inline base64 blobs, grow the buffer instead of
+	// stopping the scan at bufio's 64KB token limit
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 0, 64*1024), math.MaxInt)
+
+	var blocks [][]string
+	var block []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "###") {
+			blocks = append(blocks, block)
+			block = nil
+			continue
+		}
+		block = append(block, line)
+	}
+	blocks = append(blocks, block)
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var requests []RequestData
+	for _, lines := range blocks {
+		if isEmptyBlock(lines) {
+			continue
+		}
+
+		reqData, err := parseHTTPRequest(lines, opts)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", len(requests)+1, err)
+		}
+		requests = append(requests, reqData)
+	}
+
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests found")
+	}
+
+	return requests, nil
+}
+
+// isRawHTTPDump reports whether the first line of the input, after blank lines, is a raw HTTP/1.x request line
+func isRawHTTPDump(br *bufio.Reader) bool {
+	peek, _ := br.Peek(br.Size())
+	for _, line := range strings.Split(string(peek), "\n") {
+		if strings.TrimSpace(line) != "" {
+			return rawRequestLinePattern.MatchString(line)
+		}
+	}
+	return false
+}
+
+// parseRawHTTPRequests reads raw HTTP/1.x requests, one after the other, as they were sent over the wire.
+// The URL is built from the Host header, over https when its port is 443.
+func parseRawHTTPRequests(br *bufio.Reader, opts ParseOptions) ([]RequestData, error) {
+	var requests []RequestData
+	for {
+		// Pipelined requests may be separated by blank lines
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				break
+			}
+			if c != '\r' && c != '\n' {
+				br.UnreadByte()
+				break
+			}
+		}
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return nil, fmt.Errorf("raw request %d: %w", len(requests)+1, err)
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("raw request %d: %w", len(requests)+1, err)
+		}
+		if req.Host == "" {
+			return nil, fmt.Errorf("raw request %d: no Host header", len(requests)+1)
+		}
+
+		scheme, host := "http", req.Host
+		if h, ok := strings.CutSuffix(host, ":443"); ok {
+			scheme, host = "https", h
+		}
+		reqData := RequestData{
+			Method:  req.Method,
+			URL:     scheme + "://" + host + req.RequestURI,
+			Headers: make(map[string]string, len(req.Header)),
+			Body:    string(body),
+		}
+		// The length is set again for the body when the request is sent
+		for k, v := range req.Header {
+			if k != "Content-Length" {
+				reqData.Headers[k] = strings.Join(v, ", ")
+			}
+		}
+
+		if err := checkBodySize(reqData.Body, opts.MaxBodyBytes); err != nil {
+			return nil, fmt.Errorf("raw request %d: %w", len(requests)+1, err)
+		}
+		requests = append(requests, reqData)
+	}
+
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests found")
+	}
+	return requests, nil
+}
+
+// parseHTTPRequest parses a single request block: directives and comments, the request line, headers and body
+func parseHTTPRequest(lines []string, opts ParseOptions) (RequestData, error) {
+	reqData := RequestData{
+		Headers: make(map[string]string),
+	}
+
+	i := 0
+
+	// Skip blank lines and comments before the request line, applying directives
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if !isComment(line) {
+			break
+		}
+
+		if m := directivePattern.FindStringSubmatch(line); m != nil {
+			apply, ok := directives[m[1]]
+			if !ok {
+				if opts.Strict {
+					return RequestData{}, fmt.Errorf("unknown directive @%s", m[1])
+				}
+				printWarning("ignoring unknown directive @" + m[1])
+				continue
+			}
+			if err := apply(&reqData, strings.TrimSpace(m[2])); err != nil {
+				return RequestData{}, err
+			}
+		}
+	}
+
+	// Read the request line for method and URL
+	if i < len(lines) {
+		parts := strings.SplitN(lines[i], " ", 2)
+		if len(parts) != 2 {
+			return RequestData{}, fmt.Errorf("invalid request line")
+		}
+		reqData.Method, reqData.URL = parts[0], parts[1]
+		i++
+	}
+
+	// Read headers
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			i++
+			break
+		}
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) != 2 {
+			continue // Skip invalid header
+		}
+		reqData.Headers[parts[0]] = parts[1]
+	}
+
+	// Read body (if any), dropping the blank lines left before the next separator
+	bodyLines := lines[min(i, len(lines)):]
+	for len(bodyLines) > 0 && strings.TrimSpace(bodyLines[len(bodyLines)-1]) == "" {
+		bodyLines = bodyLines[:len(bodyLines)-1]
+	}
+	reqData.Body = strings.Join(bodyLines, "\n")
+
+	// A JSON request body may be written as "key=value" and "key:=raw" shorthand items
+	if isJSONContentType(reqData.Headers) {
+		body, ok, err := expandJSONShorthand(reqData.Body)
+		if err != nil {
+			return RequestData{}, err
+		}
+		if ok {
+			reqData.Body = body
+		}
+	}
+
+	if err := checkBodySize(reqData.Body, opts.MaxBodyBytes); err != nil {
+		return RequestData{}, err
+	}
+
+	return reqData, nil
+}
+
+// isJSONContentType reports whether the Content-Type header names a JSON media type
+func isJSONContentType(headers map[string]string) bool {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") && strings.Contains(strings.ToLower(v), "json") {
+			return true
+		}
+	}
+	return false
+}
+
+// isComment reports whether a trimmed line is a # or // comment
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
+
+// isEmptyBlock reports whether a block holds nothing but blank lines and comments
+func isEmptyBlock(lines []string) bool {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !isComment(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkBodySize rejects a request body larger than max bytes, 0 means no limit
+func checkBodySize(body string, max int64) error {
+	if max > 0 && int64(len(body)) > max {
+		return fmt.Errorf("request body is %d bytes, exceeds -max-request-bytes %d", len(body), max)
+	}
+	return nil
+}
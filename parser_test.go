@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStrictParseRejectsUnknownDirective(t *testing.T) {
+	known := "# @name login\nGET http://example.com\n"
+	requests, err := parseHTTPRequests(strings.NewReader(known), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("known directive: %v", err)
+	}
+	if len(requests) != 1 || requests[0].Name != "login" {
+		t.Errorf("parsed %+v, want one request named login", requests)
+	}
+
+	typo := "# @expcet 200\nGET http://example.com\n"
+	if _, err := parseHTTPRequests(strings.NewReader(typo), ParseOptions{Strict: true}); err == nil || !strings.Contains(err.Error(), "unknown directive @expcet") {
+		t.Errorf("strict parse of a typo returned %v, want an unknown directive error", err)
+	}
+	if _, err := parseHTTPRequests(strings.NewReader(typo), ParseOptions{}); err != nil {
+		t.Errorf("lenient parse of a typo returned %v, want a warning only", err)
+	}
+}
+
+func TestReadGzippedHTTPFile(t *testing.T) {
+	const source = "# @name create\nPOST http://example.com/items\nContent-Type: application/json\n\n{\"a\":1}\n\n###\nGET http://example.com/items\n"
+
+	dir := t.TempDir()
+	plain := writeFile(t, dir, "plain.http", source)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(source))
+	gz.Close()
+	gzipped := writeFile(t, dir, "requests.http.gz", compressed.String())
+
+	want, err := ReadHTTPFile(plain, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadHTTPFile(gzipped, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("gzipped file parsed to %+v, want %+v", got, want)
+	}
+}
+
+func TestBodyLineLongerThanScannerDefault(t *testing.T) {
+	blob := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xde, 0xad, 0xbe, 0xef}, 50000))
+	if len(blob) <= bufio.MaxScanTokenSize {
+		t.Fatalf("blob is %d bytes, want more than %d", len(blob), bufio.MaxScanTokenSize)
+	}
+	source := "POST http://example.com/upload\nContent-Type: text/plain\n\n" + blob + "\n\n###\nGET http://example.com/done\n"
+
+	path := writeFile(t, t.TempDir(), "upload.http", source)
+	requests, err := ReadHTTPFile(path, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("parsed %d requests, want 2", len(requests))
+	}
+	if requests[0].Body != blob {
+		t.Errorf("body is %d bytes, want the %d byte line intact", len(requests[0].Body), len(blob))
+	}
+	if requests[1].URL != "http://example.com/done" {
+		t.Errorf("second request URL = %s, want parsing to continue after the long line", requests[1].URL)
+	}
+}
+
+func TestHTTPRestAndRawDumpParseAlike(t *testing.T) {
+	const lines = "POST http://example.com/items?page=2\nContent-Type: application/json\nAccept: */*\n\n{\"a\":1}\n\n###\nGET https://example.com/health\n"
+	const raw = "POST /items?page=2 HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nAccept: */*\r\nContent-Length: 7\r\n\r\n{\"a\":1}" +
+		"GET /health HTTP/1.1\r\nHost: example.com:443\r\n\r\n"
+
+	dir := t.TempDir()
+	want, err := ReadHTTPFile(writeFile(t, dir, "api.http", lines), ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2 {
+		t.Fatalf(".http file parsed to %d requests, want 2", len(want))
+	}
+	for name, content := range map[string]string{"api.rest": lines, "dump.txt": raw} {
+		got, err := ReadHTTPFile(writeFile(t, dir, name, content), ParseOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s parsed to %+v, want %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/url"
+	"sync"
+)
+
+// job is one run of a request in a batch
+type job struct {
+	Request    RequestData
+	OutputPath string
+}
+
+// hostLimiter caps how many requests are in flight to each host at once
+type hostLimiter struct {
+	limit int
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+// newHostLimiter returns a limiter allowing limit requests per host, 0 means no limit
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, sems: make(map[string]chan struct{})}
+}
+
+// acquire blocks until the host of the URL has a free slot and returns the func that releases it
+func (l *hostLimiter) acquire(rawURL string) func() {
+	if l.limit <= 0 {
+		return func() {}
+	}
+
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+
+	l.mu.Lock()
+	sem, ok := l.sems[host]
+	if !ok {
+		sem = make(chan struct{}, l.limit)
+		l.sems[host] = sem
+	}
+	l.mu.Unlock()
+
+	sem <- struct{}{}
+	return func() { <-sem }
+}
+
+// fairLimiter caps how many requests of a run are in flight at once. Workers contend for its slots
+// with the index of their job as a ticket and are admitted strictly in ticket order, where a plain
+// channel semaphore wakes blocked senders in any order and a later job could overtake an earlier one.
+type fairLimiter struct {
+	limit int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	// next is the ticket admitted next
+	next int
+}
+
+// newFairLimiter returns a limiter allowing limit requests at once, 0 means no limit
+func newFairLimiter(limit int) *fairLimiter {
+	l := &fairLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until every lower ticket has been admitted and a slot is free.
+// Tickets must be handed out without gaps, starting at 0.
+func (l *fairLimiter) acquire(ticket int) {
+	if l.limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	for ticket != l.next || l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.next++
+	l.active++
+	l.mu.Unlock()
+	// The holder of the next ticket may be waiting for its turn rather than for a slot
+	l.cond.Broadcast()
+}
+
+// release frees a slot for the holder of the next ticket
+func (l *fairLimiter) release() {
+	if l.limit <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// runPool runs the jobs on up to workers goroutines, at most perHost of them sending to the same host
+// and at most maxConcurrent in flight across the run, and returns the outcomes in job order
+func runPool(jobs []job, workers, perHost, maxConcurrent int, run func(job) Outcome) []Outcome {
+	outcomes := make([]Outcome, len(jobs))
+	limiter := newHostLimiter(perHost)
+	global := newFairLimiter(maxConcurrent)
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Jobs are numbered in submission order, so they take the global slots in that order
+				global.acquire(i)
+				release := limiter.acquire(jobs[i].Request.URL)
+				outcomes[i] = run(jobs[i])
+				release()
+				global.release()
+			}
+		}()
+	}
+
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return outcomes
+}
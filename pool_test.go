@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrencyTracker records the most requests in flight at once per host and overall
+type concurrencyTracker struct {
+	mu                 sync.Mutex
+	active, peak       map[string]int
+	total, peakOverall int
+}
+
+func newConcurrencyTracker() *concurrencyTracker {
+	return &concurrencyTracker{active: map[string]int{}, peak: map[string]int{}}
+}
+
+// handler holds each request for a moment so concurrent ones overlap
+func (c *concurrencyTracker) handler(host string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.active[host]++
+		c.total++
+		c.peak[host] = max(c.peak[host], c.active[host])
+		c.peakOverall = max(c.peakOverall, c.total)
+		c.mu.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+
+		c.mu.Lock()
+		c.active[host]--
+		c.total--
+		c.mu.Unlock()
+	})
+}
+
+func TestParallelPerHostLimit(t *testing.T) {
+	tracker := newConcurrencyTracker()
+	a := httptest.NewServer(tracker.handler("a"))
+	defer a.Close()
+	b := httptest.NewServer(tracker.handler("b"))
+	defer b.Close()
+
+	var jobs []job
+	for range 6 {
+		jobs = append(jobs, job{Request: NewURLRequest(a.URL)}, job{Request: NewURLRequest(b.URL)})
+	}
+	runner := &Runner{Client: a.Client(), Retry: 1, Report: ReportOptions{Sink: DiscardSink{}}}
+	outcomes := runPool(jobs, 8, 2, 0, func(j job) Outcome { return runner.Run(j.Request, "out") })
+
+	for i, outcome := range outcomes {
+		if !outcome.Passed || outcome.Request.URL != jobs[i].Request.URL {
+			t.Errorf("outcome %d is for %s with error %v, want a passing %s", i, outcome.Request.URL, outcome.Err, jobs[i].Request.URL)
+		}
+	}
+	for _, host := range []string{"a", "b"} {
+		if tracker.peak[host] > 2 {
+			t.Errorf("host %s had %d requests in flight, want at most 2", host, tracker.peak[host])
+		}
+	}
+	if tracker.peakOverall <= 2 {
+		t.Errorf("at most %d requests were in flight overall, want the hosts to run in parallel", tracker.peakOverall)
+	}
+}
+
+func TestMaxConcurrentStartsJobsInSubmissionOrder(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		started = append(started, r.URL.Query().Get("job"))
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	// Sixteen workers contend for a single slot, each handing its job index as the ticket
+	var want []string
+	var batch []job
+	for i := range 30 {
+		id := fmt.Sprint(i)
+		want = append(want, id)
+		batch = append(batch, job{Request: NewURLRequest(srv.URL + "/?job=" + id)})
+	}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{Sink: DiscardSink{}}}
+	for _, outcome := range runPool(batch, 16, 0, 1, func(j job) Outcome { return runner.Run(j.Request, "out") }) {
+		if !outcome.Passed {
+			t.Fatalf("%s failed: %v", outcome.Request.URL, outcome.Err)
+		}
+	}
+
+	if !slices.Equal(started, want) {
+		t.Errorf("jobs started in order %v, want submission order %v", started, want)
+	}
+}
+
+func TestFairLimiterAdmitsTicketsInOrder(t *testing.T) {
+	limiter := newFairLimiter(2)
+	var mu sync.Mutex
+	var admitted []int
+
+	// Later tickets ask first, so a limiter admitting in arrival order would let them overtake
+	var wg sync.WaitGroup
+	for ticket := 9; ticket >= 0; ticket-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.acquire(ticket)
+			mu.Lock()
+			admitted = append(admitted, ticket)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			limiter.release()
+		}()
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+
+	for i := 1; i < len(admitted); i++ {
+		// Two slots let a ticket record itself just before the one admitted right ahead of it
+		if admitted[i] < admitted[i-1]-1 {
+			t.Fatalf("tickets admitted in order %v, want ascending", admitted)
+		}
+	}
+	if len(admitted) != 10 {
+		t.Errorf("%d tickets admitted, want 10", len(admitted))
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// priorityTimeout bounds the streams of -h2-priorities when no -timeout is set
+const priorityTimeout = 30 * time.Second
+
+// priorityMaxBody is the largest body -h2-priorities sends, the frame size every HTTP/2 server accepts
+const priorityMaxBody = 16384
+
+// priorityStream is one stream of the -h2-priorities test and how it completed
+type priorityStream struct {
+	StreamID uint32
+	Weight   int
+	Status   string
+	Bytes    int
+	Latency  time.Duration
+	// Order is the position the stream completed in, starting at 1, 0 when it did not complete
+	Order int
+	Err   error
+}
+
+// priorityTest sends the request as concurrent HTTP/2 streams on one connection, one per weight of
+// H2Priorities, and reports the order they completed in. Servers are free to ignore priorities, so the
+// test only fails when a stream does not complete.
+func (r *Runner) priorityTest(reqData RequestData, outputPath string) Outcome {
+	outcome := Outcome{Request: reqData}
+
+	timeout := r.Client.Timeout
+	if timeout <= 0 {
+		timeout = priorityTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	streams, err := sendPrioritizedStreams(ctx, r, reqData)
+	for i, stream := range streams {
+		attempt := Attempt{Number: i + 1, Latency: stream.Latency, Err: stream.Err}
+		if code, err := strconv.Atoi(stream.Status); err == nil {
+			attempt.StatusCode, attempt.Status = code, stream.Status+" "+http.StatusText(code)
+		}
+		outcome.Attempts = append(outcome.Attempts, attempt)
+
+		if stream.Order == 0 {
+			printInfo("priority", "weight", stream.Weight, "did not complete:", stream.Err)
+		} else {
+			printInfo("priority", "weight", stream.Weight, "completed", ordinal(stream.Order), fmt.Sprintf("(%d bytes, %.1fms)", stream.Bytes, milliseconds(stream.Latency)))
+		}
+	}
+	if err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	if err := GeneratePriorityReport(r.Report.sink(), outputPath, reqData, streams); err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	outcome.Passed = true
+	return outcome
+}
+
+// sendPrioritizedStreams opens an HTTP/2 connection and sends every stream before reading any response,
+// so the server has all of them queued when it schedules the responses
+func sendPrioritizedStreams(ctx context.Context, r *Runner, reqData RequestData) ([]priorityStream, error) {
+	u, err := url.Parse(reqData.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(reqData.Body) > priorityMaxBody {
+		return nil, fmt.Errorf("-h2-priorities sends bodies in a single frame, %d bytes is more than %d", len(reqData.Body), priorityMaxBody)
+	}
+
+	conn, err := dialRaw(ctx, r.Client, u, "h2")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		return nil, err
+	}
+	framer := http2.NewFramer(conn, conn)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+
+	// Flow control is opened wide so only the server's scheduling decides the completion order
+	const window = 1 << 30
+	err = framer.WriteSettings(
+		http2.Setting{ID: http2.SettingEnablePush, Val: 0},
+		http2.Setting{ID: http2.SettingInitialWindowSize, Val: window},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := framer.WriteWindowUpdate(0, window-65535); err != nil {
+		return nil, err
+	}
+
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	encoder.WriteField(hpack.HeaderField{Name: ":method", Value: reqData.Method})
+	encoder.WriteField(hpack.HeaderField{Name: ":scheme", Value: u.Scheme})
+	encoder.WriteField(hpack.HeaderField{Name: ":authority", Value: u.Host})
+	encoder.WriteField(hpack.HeaderField{Name: ":path", Value: u.RequestURI()})
+	names := make([]string, 0, len(reqData.Headers))
+	for k := range reqData.Headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		// Connection specific headers are not allowed in HTTP/2, Host becomes :authority
+		switch strings.ToLower(k) {
+		case "host", "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade":
+			continue
+		}
+		encoder.WriteField(hpack.HeaderField{Name: strings.ToLower(k), Value: reqData.Headers[k]})
+	}
+
+	streams := make([]priorityStream, len(r.H2Priorities))
+	index := make(map[uint32]int)
+	start := time.Now()
+	for i, weight := range r.H2Priorities {
+		id := uint32(2*i + 1)
+		streams[i] = priorityStream{StreamID: id, Weight: weight}
+		index[id] = i
+
+		// The wire weight is one less than the weight, 0 to 255 for 1 to 256. The framer leaves a zero
+		// priority off the HEADERS frame, so weight 1 goes in a PRIORITY frame sent ahead of it.
+		priority := http2.PriorityParam{Weight: uint8(weight - 1)}
+		if priority.IsZero() {
+			if err := framer.WritePriority(id, priority); err != nil {
+				return streams, err
+			}
+		}
+		err := framer.WriteHeaders(http2.HeadersFrameParam{
+			StreamID:      id,
+			BlockFragment: block.Bytes(),
+			EndStream:     reqData.Body == "",
+			EndHeaders:    true,
+			Priority:      priority,
+		})
+		if err != nil {
+			return streams, err
+		}
+		if reqData.Body != "" {
+			if err := framer.WriteData(id, true, []byte(reqData.Body)); err != nil {
+				return streams, err
+			}
+		}
+	}
+
+	completed := 0
+	end := func(id uint32, err error) {
+		i, ok := index[id]
+		if !ok || streams[i].Order != 0 || streams[i].Err != nil {
+			return
+		}
+		streams[i].Latency = time.Since(start)
+		if err != nil {
+			streams[i].Err = err
+		} else {
+			streams[i].Order = completed + 1
+		}
+		completed++
+	}
+
+	for completed < len(streams) {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return streams, err
+		}
+
+		switch f := frame.(type) {
+		case *http2.MetaHeadersFrame:
+			if i, ok := index[f.StreamID]; ok && streams[i].Status == "" {
+				streams[i].Status = f.PseudoValue("status")
+			}
+			if f.StreamEnded() {
+				end(f.StreamID, nil)
+			}
+		case *http2.DataFrame:
+			if i, ok := index[f.StreamID]; ok {
+				streams[i].Bytes += len(f.Data())
+			}
+			if f.StreamEnded() {
+				end(f.StreamID, nil)
+			}
+		case *http2.RSTStreamFrame:
+			end(f.StreamID, fmt.Errorf("stream reset: %v", f.ErrCode))
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				if err := framer.WriteSettingsAck(); err != nil {
+					return streams, err
+				}
+			}
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				if err := framer.WritePing(true, f.Data); err != nil {
+					return streams, err
+				}
+			}
+		case *http2.GoAwayFrame:
+			return streams, fmt.Errorf("server sent GOAWAY: %v", f.ErrCode)
+		}
+	}
+
+	return streams, nil
+}
+
+// ordinal renders 1 as "1st", 2 as "2nd" and so on
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// GeneratePriorityReport writes every stream with its weight and the order it completed in
+func GeneratePriorityReport(sink ReportSink, outputPath string, reqData RequestData, streams []priorityStream) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-priority.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Streams that did not complete go last
+	byOrder := slices.Clone(streams)
+	slices.SortStableFunc(byOrder, func(a, b priorityStream) int {
+		if a.Order == 0 || b.Order == 0 {
+			return b.Order - a.Order
+		}
+		return a.Order - b.Order
+	})
+	var weights []string
+	for _, stream := range byOrder {
+		weights = append(weights, fmt.Sprint(stream.Weight))
+	}
+	followed := slices.IsSortedFunc(byOrder, func(a, b priorityStream) int { return b.Weight - a.Weight })
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request URL: %s\nStreams: %d\nCompletion Order: weights %s\nFollows Weights: %t\n", reqData.URL, len(streams), strings.Join(weights, ", "), followed))
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		state := ordinal(stream.Order)
+		if stream.Err != nil {
+			state = "error: " + stream.Err.Error()
+		}
+		_, err = io.WriteString(file, fmt.Sprintf("\nStream: %d\nWeight: %d\nStatus: %s\nCompleted: %s (%d bytes, %.1fms)\n", stream.StreamID, stream.Weight, stream.Status, state, stream.Bytes, milliseconds(stream.Latency)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// startPriorityServer starts a cleartext HTTP/2 server that reads every stream before answering and
+// then completes them heaviest first. It sends the weights it read from the wire, by stream, on the channel.
+func startPriorityServer(t *testing.T, streams int) (string, chan map[uint32]int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan map[uint32]int, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			preface := make([]byte, len(http2.ClientPreface))
+			if _, err := io.ReadFull(conn, preface); err != nil {
+				conn.Close()
+				continue
+			}
+			framer := http2.NewFramer(conn, conn)
+			framer.WriteSettings()
+
+			// Streams without a priority get the default weight of 16
+			weights := make(map[uint32]int)
+			var ids []uint32
+			for len(ids) < streams {
+				frame, err := framer.ReadFrame()
+				if err != nil {
+					break
+				}
+				switch f := frame.(type) {
+				case *http2.PriorityFrame:
+					weights[f.StreamID] = int(f.Weight) + 1
+				case *http2.HeadersFrame:
+					if f.HasPriority() {
+						weights[f.StreamID] = int(f.Priority.Weight) + 1
+					} else if _, ok := weights[f.StreamID]; !ok {
+						weights[f.StreamID] = 16
+					}
+					ids = append(ids, f.StreamID)
+				}
+			}
+			received <- weights
+
+			sort.SliceStable(ids, func(i, j int) bool { return weights[ids[i]] > weights[ids[j]] })
+			var block bytes.Buffer
+			hpack.NewEncoder(&block).WriteField(hpack.HeaderField{Name: ":status", Value: "200"})
+			for _, id := range ids {
+				framer.WriteHeaders(http2.HeadersFrameParam{StreamID: id, BlockFragment: block.Bytes(), EndHeaders: true})
+				framer.WriteData(id, true, []byte("ok"))
+			}
+			io.Copy(io.Discard, conn)
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestH2PrioritiesHeavierStreamCompletesFirst(t *testing.T) {
+	addr, received := startPriorityServer(t, 3)
+
+	sink := &memorySink{}
+	runner := &Runner{Client: http.DefaultClient, Retry: 1, H2Priorities: []int{1, 256, 16}, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest("http://"+addr+"/"), "out"); !outcome.Passed {
+		t.Fatalf("priority test failed: %v", outcome.Err)
+	}
+
+	// Weight 1 is a zero priority on the wire, so it only arrives when sent on its own
+	weights := <-received
+	if weights[1] != 1 || weights[3] != 256 || weights[5] != 16 {
+		t.Errorf("server read weights %v by stream, want 1, 256 and 16 on streams 1, 3 and 5", weights)
+	}
+	report := sink.report(t, "-priority.txt")
+	if !strings.Contains(report, "Completion Order: weights 256, 16, 1\nFollows Weights: true\n") {
+		t.Errorf("report does not show the streams completing heaviest first:\n%s", report)
+	}
+	if !strings.Contains(report, "Stream: 3\nWeight: 256\nStatus: 200\nCompleted: 1st (2 bytes") {
+		t.Errorf("report does not show the weight 256 stream completing 1st:\n%s", report)
+	}
+}
+
+func TestH2PrioritiesAgainstGoServer(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 64<<10)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The Go server schedules streams as it likes, so only completion is checked, not the order
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, H2Priorities: []int{1, 256, 16}, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("priority test failed: %v", outcome.Err)
+	}
+	report := sink.report(t, "-priority.txt")
+	if !strings.Contains(report, "Streams: 3\nCompletion Order: weights ") || strings.Count(report, "Status: 200\n") != 3 || strings.Count(report, "(65536 bytes") != 3 {
+		t.Errorf("report does not show three complete streams:\n%s", report)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkPprof fails unless path is a gzipped pprof protocol buffer whose string table holds the sample type
+func checkPprof(t *testing.T, path, sampleType string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s is not gzipped: %v", path, err)
+	}
+	profile, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if !bytes.Contains(profile, []byte(sampleType)) {
+		t.Errorf("%s has no %s sample type", path, sampleType)
+	}
+}
+
+func TestProfilesAreWritten(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "mem.pprof")
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-repeat", "20", "-cpuprofile", cpu, "-memprofile", mem, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	checkPprof(t, cpu, "nanoseconds")
+	checkPprof(t, mem, "inuse_space")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// progressKey is the context key under which -progress stores its reporting interval
+type progressKey struct{}
+
+// withProgress returns a context that makes SendRequest report upload progress every interval
+func withProgress(ctx context.Context, interval time.Duration) context.Context {
+	return context.WithValue(ctx, progressKey{}, interval)
+}
+
+// progressReader counts the bytes read from a request body and reports them at most once per interval
+type progressReader struct {
+	body     io.ReadCloser
+	total    int64
+	sent     int64
+	interval time.Duration
+	last     time.Time
+	report   func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.body.Read(b)
+	p.sent += int64(n)
+
+	// The final count is always reported so the last line shows the full body
+	if now := time.Now(); now.Sub(p.last) >= p.interval || (n > 0 && p.sent == p.total) {
+		p.last = now
+		p.report(p.sent, p.total)
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	return p.body.Close()
+}
+
+// printProgress writes an upload progress line to stderr
+func printProgress(url string) func(sent, total int64) {
+	return func(sent, total int64) {
+		percent := 100.0
+		if total > 0 {
+			percent = float64(sent) / float64(total) * 100
+		}
+		fmt.Fprintf(stderr, "upload %s: %d/%d bytes (%.0f%%)\n", url, sent, total, percent)
+	}
+}
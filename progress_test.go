@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProgressReaderReportsUpToTotal(t *testing.T) {
+	body := strings.Repeat("x", 1<<20)
+	var reports []int64
+	reader := &progressReader{
+		body:   io.NopCloser(strings.NewReader(body)),
+		total:  int64(len(body)),
+		report: func(sent, total int64) { reports = append(reports, sent) },
+	}
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("progress was reported %d times, want several for a 1MB body", len(reports))
+	}
+	if last := reports[len(reports)-1]; last != int64(len(body)) || n != int64(len(body)) {
+		t.Errorf("last report was %d bytes with %d read, want the %d byte body", last, n, len(body))
+	}
+}
+
+func TestProgressFlagPrintsUploadTotal(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	size := 3 << 20
+	bodyPath := writeFile(t, dir, "upload.bin", strings.Repeat("y", size))
+	_, stderr, code := runMain(t, dir, "-url", srv.URL, "-method", "PUT", "-body-file", bodyPath, "-progress", "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if received != size {
+		t.Errorf("server got %d bytes, want %d", received, size)
+	}
+	if want := fmt.Sprintf("upload %s: %d/%d bytes (100%%)\n", srv.URL, size, size); !strings.HasSuffix(stderr, want) {
+		t.Errorf("stderr does not end with the full upload %q:\n%s", want, stderr)
+	}
+}
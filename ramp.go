@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// rampTick is how often a ramp checks whether its concurrency rose while every worker is busy
+const rampTick = 10 * time.Millisecond
+
+// rampLevel is the concurrency a ramp from from to to over duration has reached after elapsed
+func rampLevel(from, to int, duration, elapsed time.Duration) int {
+	if elapsed >= duration {
+		return to
+	}
+	return from + int(float64(to-from)*float64(elapsed)/float64(duration))
+}
+
+// runRamp runs the jobs in order with a concurrency that moves from from to to over duration and
+// stays at to after it. Each outcome records the concurrency it was started at.
+func runRamp(jobs []job, from, to int, duration time.Duration, run func(job) Outcome) []Outcome {
+	outcomes := make([]Outcome, len(jobs))
+
+	var mu sync.Mutex
+	freed := sync.NewCond(&mu)
+	active := 0
+
+	// The workers only signal when they finish, the ticker wakes the loop up when the level rises
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(rampTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				freed.Broadcast()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	defer close(stop)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, j := range jobs {
+		mu.Lock()
+		level := rampLevel(from, to, duration, time.Since(start))
+		for active >= level {
+			freed.Wait()
+			level = rampLevel(from, to, duration, time.Since(start))
+		}
+		active++
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome := run(j)
+			outcome.Concurrency = level
+			outcomes[i] = outcome
+
+			mu.Lock()
+			active--
+			mu.Unlock()
+			freed.Broadcast()
+		}()
+	}
+	wg.Wait()
+
+	return outcomes
+}
+
+// RampLevel is the success and latency of the requests a ramp started at one concurrency
+type RampLevel struct {
+	Concurrency int           `json:"concurrency"`
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"`
+	ErrorRate   float64       `json:"error_rate"`
+	Latency     *LatencyStats `json:"latency,omitempty"`
+}
+
+// NewRampLevels groups the outcomes of a ramp by the concurrency they were started at, lowest first
+func NewRampLevels(outcomes []Outcome) []RampLevel {
+	byLevel := make(map[int][]Outcome)
+	for _, o := range outcomes {
+		byLevel[o.Concurrency] = append(byLevel[o.Concurrency], o)
+	}
+
+	var levels []RampLevel
+	for concurrency, group := range byLevel {
+		level := RampLevel{Concurrency: concurrency, Requests: len(group)}
+		for _, o := range group {
+			if !o.Passed {
+				level.Errors++
+			}
+		}
+		level.ErrorRate = float64(level.Errors) / float64(level.Requests)
+		if latencies := outcomeLatencies(group); len(latencies) > 0 {
+			stats := NewLatencyStats(latencies)
+			level.Latency = &stats
+		}
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Concurrency < levels[j].Concurrency })
+	return levels
+}
+
+// writeRampLevels prints the requests, error rate and latency of every concurrency level as a table
+func writeRampLevels(w io.Writer, levels []RampLevel) error {
+	latency := func(l RampLevel, value func(LatencyStats) float64) string {
+		if l.Latency == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.1fms", value(*l.Latency))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONCURRENCY\tREQUESTS\tERROR RATE\tP50\tP95\tP99")
+	for _, l := range levels {
+		fmt.Fprintf(tw, "%d\t%d\t%.1f%%\t%s\t%s\t%s\n", l.Concurrency, l.Requests, l.ErrorRate*100,
+			latency(l, func(s LatencyStats) float64 { return s.P50MS }),
+			latency(l, func(s LatencyStats) float64 { return s.P95MS }),
+			latency(l, func(s LatencyStats) float64 { return s.P99MS }))
+	}
+	return tw.Flush()
+}
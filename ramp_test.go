@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRampRaisesConcurrencyOverTime(t *testing.T) {
+	jobs := make([]job, 60)
+	for i := range jobs {
+		jobs[i].OutputPath = fmt.Sprint(i)
+	}
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	running := make(map[string]int)
+	outcomes := runRamp(jobs, 1, 4, 200*time.Millisecond, func(j job) Outcome {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		running[j.OutputPath] = inFlight
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return Outcome{Passed: true}
+	})
+
+	previous := 0
+	for i, outcome := range outcomes {
+		if outcome.Concurrency < previous {
+			t.Fatalf("job %d started at concurrency %d after %d, want the ramp to only rise", i, outcome.Concurrency, previous)
+		}
+		if n := running[fmt.Sprint(i)]; n > outcome.Concurrency {
+			t.Errorf("job %d started with %d jobs running, more than its level %d", i, n, outcome.Concurrency)
+		}
+		previous = outcome.Concurrency
+	}
+	if first, last := outcomes[0].Concurrency, outcomes[len(outcomes)-1].Concurrency; first != 1 || last != 4 {
+		t.Errorf("ramp went from concurrency %d to %d, want 1 to 4", first, last)
+	}
+	if peak != 4 {
+		t.Errorf("at most %d jobs ran at once, want 4 once the ramp is up", peak)
+	}
+}
+
+func TestRampReportsStatsPerLevel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	summaryPath := filepath.Join(dir, "summary.json")
+	stdout, stderr, code := runMain(t, dir, "-url", srv.URL, "-repeat", "30", "-ramp-from", "1", "-ramp-to", "3", "-ramp-duration", "150ms", "-summary-json", summaryPath, "-output", "ramp")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !regexp.MustCompile(`CONCURRENCY\s+REQUESTS\s+ERROR RATE\s+P50\s+P95\s+P99\n1\s+\d+\s+0\.0%\s+[0-9.]+ms`).MatchString(stdout) {
+		t.Errorf("output has no per-level table starting at concurrency 1:\n%s", stdout)
+	}
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Ramp) != 3 {
+		t.Fatalf("summary has ramp levels %+v, want concurrency 1, 2 and 3", summary.Ramp)
+	}
+	requests := 0
+	for i, level := range summary.Ramp {
+		if level.Concurrency != i+1 || level.Latency == nil || level.Latency.Count != level.Requests || level.ErrorRate != 0 {
+			t.Errorf("ramp level %d is %+v, want concurrency %d with latency stats for its requests and no errors", i, level, i+1)
+		}
+		requests += level.Requests
+	}
+	if requests != 30 {
+		t.Errorf("ramp levels hold %d requests, want all 30", requests)
+	}
+}
+
+func TestRampHonoursDelayDirective(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "delayed.http", "# @delay 100ms\nGET "+srv.URL+"\n")
+	start := time.Now()
+	if _, stderr, code := runMain(t, dir, "-source", source, "-repeat", "3", "-ramp-from", "1", "-ramp-to", "1", "-ramp-duration", "10ms", "-output", "delayed"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	// At concurrency 1 the three delays add up
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("ramp of three delayed requests took %s, want at least 300ms", elapsed)
+	}
+}
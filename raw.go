@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// buildRawRequest renders an HTTP/1.1 request with the headers in order and exactly as given,
+// followed by Connection: close and the body
+func buildRawRequest(method string, u *url.URL, headers [][2]string, body string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", method, u.RequestURI())
+	for _, h := range headers {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
+	b.WriteString("Connection: close\r\n\r\n")
+	b.WriteString(body)
+	return b.Bytes()
+}
+
+// exchangeRaw writes raw request bytes over a new connection and reads the response, bypassing net/http's
+// header normalization. The connection is bounded by the context deadline when it has one.
+func exchangeRaw(ctx context.Context, client *http.Client, u *url.URL, method string, raw []byte) (*http.Response, error) {
+	conn, err := dialRaw(ctx, client, u, "http/1.1")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(raw); err != nil {
+		return nil, err
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: method})
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	return response, err
+}
+
+// dialRaw opens a connection to the URL host with the dialer and TLS settings of the client transport,
+// negotiating protocol through ALPN over TLS
+func dialRaw(ctx context.Context, client *http.Client, u *url.URL, protocol string) (net.Conn, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dial := (&net.Dialer{}).DialContext
+	var tlsConfig *tls.Config
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		if transport.DialContext != nil {
+			dial = transport.DialContext
+		}
+		tlsConfig = transport.TLSClientConfig
+	}
+
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil || u.Scheme != "https" {
+		return conn, err
+	}
+
+	config := &tls.Config{}
+	if tlsConfig != nil {
+		config = tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = u.Hostname()
+	}
+	config.NextProtos = []string{protocol}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if negotiated := tlsConn.ConnectionState().NegotiatedProtocol; protocol == "h2" && negotiated != "h2" {
+		conn.Close()
+		return nil, fmt.Errorf("server did not negotiate HTTP/2, got %q", negotiated)
+	}
+	return tlsConn, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultRedactHeaders are the header names -redact scrubs from reports
+var defaultRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Amz-Security-Token",
+}
+
+// sensitiveVariableWords mark variable names whose values -capture-all writes as ***
+var sensitiveVariableWords = []string{"token", "secret", "password", "passwd", "auth", "key", "cookie", "session", "credential"}
+
+// redactVariable returns the value to report for a variable, *** when its name looks sensitive
+func redactVariable(name, value string) string {
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveVariableWords {
+		if strings.Contains(lower, word) {
+			return redactedValue
+		}
+	}
+	return value
+}
+
+// redactedValue replaces the value of a redacted header in reports
+const redactedValue = "***"
+
+// redactHeaderNames returns the default sensitive headers plus the comma-separated extra names
+func redactHeaderNames(extra string) []string {
+	names := append([]string(nil), defaultRedactHeaders...)
+	for _, name := range strings.Split(extra, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// redactHeader returns the value to report for a header, *** when its name is in names
+func redactHeader(name, value string, names []string) string {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return redactedValue
+		}
+	}
+	return value
+}
+
+// redactHeaderValues returns a copy of the header with the values of redacted names replaced
+func redactHeaderValues(header http.Header, names []string) map[string][]string {
+	redacted := make(map[string][]string, len(header))
+	for name, values := range header {
+		redacted[name] = make([]string, len(values))
+		for i, v := range values {
+			redacted[name][i] = redactHeader(name, v, names)
+		}
+	}
+	return redacted
+}
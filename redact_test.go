@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactScrubsReportButSendsToken(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("X-Internal", "hidden")
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{
+		Sink:    sink,
+		Redact:  redactHeaderNames("x-internal"),
+		Include: []string{"request-headers", "response-headers"},
+		Formats: []string{"txt", "json"},
+	}}
+	reqData := NewURLRequest(srv.URL)
+	reqData.Headers["Authorization"] = "Bearer real-token"
+	if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	if gotAuth != "Bearer real-token" {
+		t.Errorf("server got Authorization %q, want the real token", gotAuth)
+	}
+	for _, format := range []string{".txt", ".json"} {
+		report := sink.report(t, format)
+		if strings.Contains(report, "real-token") || strings.Contains(report, "session=abc") || strings.Contains(report, "hidden") {
+			t.Errorf("%s report leaks a redacted value:\n%s", format, report)
+		}
+		if !strings.Contains(report, "***") {
+			t.Errorf("%s report has no *** for the redacted values:\n%s", format, report)
+		}
+	}
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "Authorization: ***") {
+		t.Errorf("txt report does not show Authorization: ***:\n%s", report)
+	}
+}
+
+func TestCaptureAllRecordsVariablesWithRedaction(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{
+		Client:     srv.Client(),
+		Retry:      1,
+		CaptureAll: true,
+		Env:        map[string]string{"userId": "42", "apiToken": "t0ps3cret"},
+		Report:     ReportOptions{Sink: sink, Redact: redactHeaderNames(""), Formats: []string{"txt", "json"}},
+	}
+	reqData := NewURLRequest(srv.URL + "/users/{{userId}}")
+	reqData.Headers["Authorization"] = "Bearer {{apiToken}}"
+	if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	if gotPath != "/users/42" || gotAuth != "Bearer t0ps3cret" {
+		t.Errorf("server got %s with Authorization %q, want the substituted values", gotPath, gotAuth)
+	}
+	if report := sink.report(t, ".txt"); !strings.Contains(report, "Variables:\napiToken = ***\nuserId = 42\n") {
+		t.Errorf("txt report does not list the variables with the token redacted:\n%s", report)
+	}
+	if report := sink.report(t, ".json"); !strings.Contains(report, `"userId": "42"`) || strings.Contains(report, "t0ps3cret") {
+		t.Errorf("json report does not record the variables with the token redacted:\n%s", report)
+	}
+}
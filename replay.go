@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// reportSectionsAfterBody are the headings that can follow the request body in a text report
+var reportSectionsAfterBody = []string{"Variables:\n", "Response Status: ", "Response Headers:\n", "Latency: ", "Response Body:\n"}
+
+// reportSectionsAfterResponseBody are the text that can follow the response body in a text report
+var reportSectionsAfterResponseBody = []string{"\n[response body ", "\n\nServer-Sent Events: ", "\n\nResponse Trailers:\n", "\n\nAssertion Failures:\n", "\n\nRaw Exchange:\n"}
+
+// ReadReportFile reads the request back out of a report written by GenerateReport, in its txt or json format
+func ReadReportFile(path string) (RequestData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RequestData{}, err
+	}
+
+	var reqData RequestData
+	if filepath.Ext(path) == ".json" {
+		reqData, err = parseJSONReport(data)
+	} else {
+		reqData, err = parseTextReport(string(data))
+	}
+	if err != nil {
+		return RequestData{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, value := range reqData.Headers {
+		if value == redactedValue {
+			printWarning("header", name, "was redacted in", path, "and is replayed as ***")
+		}
+	}
+	return reqData, nil
+}
+
+// parseJSONReport reads the request of a -format=json report
+func parseJSONReport(data []byte) (RequestData, error) {
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return RequestData{}, err
+	}
+	if report.Request.Method == "" || report.Request.URL == "" {
+		return RequestData{}, fmt.Errorf("report has no request method and URL")
+	}
+
+	headers := report.Request.Headers
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	return RequestData{
+		Method:  report.Request.Method,
+		URL:     report.Request.URL,
+		Headers: headers,
+		Body:    report.Request.Body,
+	}, nil
+}
+
+// parseTextReport reads the request of a text report, which must include its request sections
+func parseTextReport(text string) (RequestData, error) {
+	reqData := RequestData{Headers: make(map[string]string)}
+
+	rest, ok := strings.CutPrefix(text, "Request Method: ")
+	if !ok {
+		return RequestData{}, fmt.Errorf("report does not start with the request, was it written with -response-only?")
+	}
+	reqData.Method, rest, _ = strings.Cut(rest, "\n")
+
+	rest, ok = strings.CutPrefix(rest, "Request URL: ")
+	if !ok {
+		return RequestData{}, fmt.Errorf("report has no Request URL line")
+	}
+	reqData.URL, rest, _ = strings.Cut(rest, "\n")
+
+	if _, headers, ok := strings.Cut(rest, "\nRequest Headers:\n"); ok {
+		headers, _, _ = strings.Cut(headers, "\nRequest Body:\n")
+		for _, line := range strings.Split(headers, "\n") {
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				reqData.Headers[name] = value
+			}
+		}
+	}
+
+	// The body is followed by a blank line and the next section, whichever comes first
+	if _, body, ok := strings.Cut(rest, "\nRequest Body:\n"); ok {
+		end := len(body)
+		for _, heading := range reportSectionsAfterBody {
+			if i := strings.Index(body, "\n\n"+heading); i >= 0 && i < end {
+				end = i
+			}
+		}
+		reqData.Body = strings.TrimSuffix(body[:end], "\n\n")
+	}
+
+	return reqData, nil
+}
+
+// ReadReportResponse reads the response recorded in a txt or json report back into a Result, as -dry-validate
+// checks it. The body is the one the report shows, after any -jq, -normalize-json or truncation.
+func ReadReportResponse(path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *Result
+	if filepath.Ext(path) == ".json" {
+		result, err = parseJSONReportResponse(data)
+	} else {
+		result, err = parseTextReportResponse(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(result.Body)
+	result.SHA256 = hex.EncodeToString(sum[:])
+	return result, nil
+}
+
+// parseJSONReportResponse reads the response of a -format=json report
+func parseJSONReportResponse(data []byte) (*Result, error) {
+	var report jsonReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	if report.Response.StatusCode == 0 {
+		return nil, fmt.Errorf("report has no response status")
+	}
+
+	response := &http.Response{
+		Status:     report.Response.Status,
+		StatusCode: report.Response.StatusCode,
+		Proto:      report.Response.Protocol,
+		Header:     http.Header(report.Response.Headers),
+	}
+	if response.Header == nil {
+		response.Header = make(http.Header)
+	}
+	return &Result{Response: response, Body: []byte(report.Response.Body)}, nil
+}
+
+// parseTextReportResponse reads the response of a text report, which must include its status and body sections
+func parseTextReportResponse(text string) (*Result, error) {
+	_, rest, ok := strings.Cut("\n"+text, "\nResponse Status: ")
+	if !ok {
+		return nil, fmt.Errorf("report has no Response Status line")
+	}
+	status, rest, _ := strings.Cut(rest, "\n")
+	code, _, _ := strings.Cut(status, " ")
+	statusCode, err := strconv.Atoi(code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Response Status %q", status)
+	}
+	response := &http.Response{Status: status, StatusCode: statusCode, Header: make(http.Header)}
+
+	// Header lines run until the timing line or the body, whichever comes first
+	if _, headers, ok := strings.Cut(rest, "Response Headers:\n"); ok {
+		end := len(headers)
+		for _, heading := range []string{"Latency: ", "Response Body:\n"} {
+			if i := strings.Index("\n"+headers, "\n"+heading); i >= 0 && i < end {
+				end = i
+			}
+		}
+		for _, line := range strings.Split(headers[:end], "\n") {
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				response.Header.Add(name, value)
+			}
+		}
+	}
+
+	_, body, ok := strings.Cut(rest, "Response Body:\n")
+	if !ok {
+		return nil, fmt.Errorf("report has no Response Body section")
+	}
+	end := len(body)
+	for _, heading := range reportSectionsAfterResponseBody {
+		if i := strings.Index(body, heading); i >= 0 && i < end {
+			end = i
+		}
+	}
+	return &Result{Response: response, Body: []byte(strings.TrimSuffix(body[:end], "\n"))}, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestReportRoundTripsRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer srv.Close()
+
+	original := RequestData{
+		Method:  http.MethodPost,
+		URL:     srv.URL + "/orders?dry=1",
+		Headers: map[string]string{"Content-Type": "application/json", "X-Request-Id": "abc-123"},
+		Body:    "{\n  \"item\": \"book\",\n\n  \"count\": 2\n}",
+	}
+	for _, format := range []string{"txt", "json"} {
+		t.Run(format, func(t *testing.T) {
+			sink := &memorySink{}
+			runner := &Runner{Client: srv.Client(), Retry: 1, Report: ReportOptions{
+				Sink:    sink,
+				Redact:  redactHeaderNames(""),
+				Include: []string{"request", "request-headers", "request-body", "status", "response-headers", "timing", "response-body"},
+				Formats: []string{format},
+			}}
+			if outcome := runner.Run(original, "out"); !outcome.Passed {
+				t.Fatalf("request failed: %v", outcome.Err)
+			}
+
+			report := sink.report(t, "."+format)
+			var got RequestData
+			var err error
+			if format == "json" {
+				got, err = parseJSONReport([]byte(report))
+			} else {
+				got, err = parseTextReport(report)
+			}
+			if err != nil {
+				t.Fatalf("parsing the report: %v\n%s", err, report)
+			}
+			if !reflect.DeepEqual(got, original) {
+				t.Errorf("report parsed back to %+v, want %+v\n%s", got, original, report)
+			}
+		})
+	}
+}
+
+func TestReplayReportResendsRequest(t *testing.T) {
+	var gotMethod, gotHeader, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotHeader, gotBody = r.Method, r.Header.Get("X-Request-Id"), string(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	report := writeFile(t, dir, "past.txt", "Request Method: PUT\nRequest URL: "+srv.URL+"/items/1\n\nRequest Headers:\nX-Request-Id: abc-123\n\nRequest Body:\n{\"name\":\"pen\"}\n\nResponse Status: 500 Internal Server Error\nResponse Body:\n")
+	if _, stderr, code := runMain(t, dir, "-replay-report", report, "-output", "replayed"); code != 0 {
+		t.Fatalf("exit code %d, want 0: %s", code, stderr)
+	}
+	if gotMethod != http.MethodPut || gotHeader != "abc-123" || gotBody != `{"name":"pen"}` {
+		t.Errorf("server got %s with X-Request-Id %q and body %q, want the recorded request", gotMethod, gotHeader, gotBody)
+	}
+}
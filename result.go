@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Result holds a response with its body read and the assertions that failed against it
+type Result struct {
+	Response *http.Response
+	Body     []byte
+	Latency  time.Duration
+	Failures []string
+	// ReadErr is set when the body read timed out, Body then holds what arrived before it
+	ReadErr error
+	// SHA256 is the hex digest of the body, hashed as it was read
+	SHA256 string
+	// Events are the server-sent events read in -sse mode
+	Events []SSEEvent
+	// Got100Continue is set when the server answered an Expect: 100-continue request with 100 Continue
+	Got100Continue bool
+	// ConnReused is set when the response came over a kept-alive connection instead of a new one
+	ConnReused bool
+	// Hedge names the request that won when the attempt was hedged: "first request" or "hedged request"
+	Hedge string
+	// SentHeaders are the request headers as the transport wrote them, including the ones it added
+	SentHeaders http.Header
+}
+
+// Failed reports whether any assertion failed for the result
+func (r *Result) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+// Execute sends the request and reads the full response body
+func Execute(ctx context.Context, client *http.Client, reqData RequestData) (*Result, error) {
+	return execute(ctx, client, reqData, io.ReadAll)
+}
+
+// discardBody reads the body to the end without keeping it, so it is only hashed
+func discardBody(r io.Reader) ([]byte, error) {
+	_, err := io.Copy(io.Discard, r)
+	return nil, err
+}
+
+// execute sends the request and reads the response body with read
+func execute(ctx context.Context, client *http.Client, reqData RequestData, read func(io.Reader) ([]byte, error)) (*Result, error) {
+	start := time.Now()
+
+	// The transport holds the body back until the server sends 100 Continue or the ExpectContinueTimeout passes
+	var got100Continue atomic.Bool
+	var connReused atomic.Bool
+	var sentMu sync.Mutex
+	sent := make(http.Header)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got100Continue: func() { got100Continue.Store(true) },
+		GotConn:        func(info httptrace.GotConnInfo) { connReused.Store(info.Reused) },
+		// A redirected request writes its headers again, only the last request's are kept
+		GetConn: func(string) {
+			sentMu.Lock()
+			defer sentMu.Unlock()
+			sent = make(http.Header)
+		},
+		WroteHeaderField: func(key string, values []string) {
+			sentMu.Lock()
+			defer sentMu.Unlock()
+			sent[key] = append(sent[key], values...)
+		},
+	})
+
+	response, err := SendRequest(ctx, client, reqData)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if keep, _ := ctx.Value(keepEncodedKey{}).(bool); !keep {
+		if err := decodeContentEncoding(response); err != nil {
+			return nil, err
+		}
+	}
+
+	// A server that sends headers and then stalls is cut off by the client timeout,
+	// keep the partial body so the report is still written
+	hash := sha256.New()
+	body, err := read(io.TeeReader(response.Body, hash))
+	if err != nil && !isTimeout(err) {
+		return nil, err
+	}
+
+	result := &Result{Response: response, Body: body, Latency: time.Since(start), ReadErr: err}
+	result.Got100Continue = got100Continue.Load()
+	result.ConnReused = connReused.Load()
+	sentMu.Lock()
+	result.SentHeaders = sent
+	sentMu.Unlock()
+	result.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if err != nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("response body read aborted after %d bytes: %v", len(body), err))
+	}
+	return result, nil
+}
+
+// isTimeout reports whether err is a deadline or network timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
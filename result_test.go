@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStalledBodyKeepsPartialBody(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client, err := NewClient(ClientOptions{Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &memorySink{}
+	runner := &Runner{Client: client, Retry: 1, Report: ReportOptions{Sink: sink}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+
+	if outcome.Passed || outcome.Result == nil {
+		t.Fatalf("passed %t with result %v, want a failed request with the partial response", outcome.Passed, outcome.Result)
+	}
+	if string(outcome.Result.Body) != "partial" || outcome.Result.ReadErr == nil {
+		t.Errorf("body %q, read error %v, want the partial body and the timeout", outcome.Result.Body, outcome.Result.ReadErr)
+	}
+
+	report := sink.report(t, ".txt")
+	for _, want := range []string{"Response Body:\npartial\n", "[response body incomplete: read timed out after 7 bytes", "response body read aborted after 7 bytes"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestExpectSHA256(t *testing.T) {
+	const content = "artifact contents"
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-expect-sha256", digest, "-output", "good"); code != 0 {
+		t.Errorf("correct hash: exit code %d, want 0: %s", code, stderr)
+	}
+	if report := readReport(t, filepath.Join(dir, "good|*.txt")); !strings.Contains(report, "Response SHA-256: "+digest) {
+		t.Errorf("report does not record the computed hash:\n%s", report)
+	}
+
+	wrong := strings.Repeat("0", 64)
+	_, stderr, code := runMain(t, dir, "-url", srv.URL, "-expect-sha256", wrong, "-output", "bad")
+	if code != 1 || !strings.Contains(stderr, "response body sha256 "+digest+" does not match expected "+wrong) {
+		t.Errorf("wrong hash: exit code %d, want 1 with a hash mismatch: %s", code, stderr)
+	}
+}
+
+func TestHashOnlyDiscardsBody(t *testing.T) {
+	const content = "artifact contents"
+	sum := sha256.Sum256([]byte(content))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	runner := &Runner{
+		Client:     srv.Client(),
+		Retry:      1,
+		HashOnly:   true,
+		Assertions: []Assertion{assertSHA256(hex.EncodeToString(sum[:]))},
+		Report:     ReportOptions{Sink: DiscardSink{}},
+	}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v %v", outcome.Err, outcome.Result.Failures)
+	}
+	if outcome.Result.Body != nil {
+		t.Errorf("body %q was kept, want it only hashed", outcome.Result.Body)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// applyResume asks only for the bytes missing from a partial # @save-body file with a Range header
+// and returns how many bytes the file already has, 0 when there is nothing to resume
+func applyResume(reqData *RequestData) int64 {
+	if reqData.SaveBody == "" || hasHeader(reqData.Headers, "Range") {
+		return 0
+	}
+	info, err := os.Stat(reqData.SaveBody)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 {
+		return 0
+	}
+	reqData.Headers["Range"] = fmt.Sprintf("bytes=%d-", info.Size())
+	return info.Size()
+}
+
+// saveResumed appends a 206 Partial Content body that continues at offset to path. A server that
+// ignores the Range header sends the whole body with a 200, which replaces the file, and 416 means
+// it is complete. Any other status, such as a 5xx error page, leaves the partial file alone.
+func saveResumed(path string, offset int64, result *Result) error {
+	switch result.Response.StatusCode {
+	case http.StatusPartialContent:
+		var start, end int64
+		contentRange := result.Response.Header.Get("Content-Range")
+		if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/", &start, &end); err != nil || start != offset {
+			return fmt.Errorf("cannot resume %s at byte %d, server sent Content-Range %q", path, offset, contentRange)
+		}
+
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(result.Body); err != nil {
+			file.Close()
+			return err
+		}
+		printInfo("resumed:", path, "from byte", offset)
+		return file.Close()
+	case http.StatusRequestedRangeNotSatisfiable:
+		printInfo("already complete:", path)
+		return nil
+	case http.StatusOK:
+		return saveBody(path, result.Body)
+	}
+	return fmt.Errorf("cannot resume %s at byte %d, server answered %s", path, offset, result.Response.Status)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResumeCompletesPartialDownload(t *testing.T) {
+	artifact := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	var gotRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRange = r.Header.Get("Range")
+		switch r.URL.Path {
+		case "/no-range":
+			w.Write(artifact)
+		case "/error":
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+		default:
+			http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(artifact))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		partial int
+		want    []byte
+		wantErr string
+	}{
+		{"partial file", "/artifact", 10000, artifact, ""},
+		{"complete file", "/artifact", len(artifact), artifact, ""},
+		{"range ignored", "/no-range", 10000, artifact, ""},
+		{"server error", "/error", 10000, artifact[:10000], "server answered 503 Service Unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saveTo := filepath.Join(t.TempDir(), "artifact.bin")
+			if err := os.WriteFile(saveTo, artifact[:tt.partial], 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			runner := &Runner{Client: srv.Client(), Retry: 1, Resume: true, Report: ReportOptions{Sink: DiscardSink{}}}
+			reqData := NewURLRequest(srv.URL + tt.path)
+			reqData.SaveBody = saveTo
+			outcome := runner.Run(reqData, "out")
+
+			if want := "bytes=10000-"; tt.partial == 10000 && gotRange != want {
+				t.Errorf("server got Range %q, want %q", gotRange, want)
+			}
+			if tt.wantErr == "" && outcome.Err != nil {
+				t.Errorf("resume failed: %v", outcome.Err)
+			}
+			if tt.wantErr != "" && (outcome.Err == nil || !strings.Contains(outcome.Err.Error(), tt.wantErr)) {
+				t.Errorf("error %v, want %q", outcome.Err, tt.wantErr)
+			}
+			got, err := os.ReadFile(saveTo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("file has %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,675 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Runner sends requests with the configured retries and writes their reports
+type Runner struct {
+	Client *http.Client
+	Retry  int
+	// RetryLog records the attempts of requests that failed after their last retry
+	RetryLog *RetryLog
+	// Tee writes the response body of every request to a file and stdout
+	Tee *Tee
+	// HashOnly hashes response bodies without keeping them, for -expect-sha256 runs whose reports leave the body out
+	HashOnly bool
+	// RetriesPerStatus overrides Retry for responses with these status codes, which are retried even below 500
+	RetriesPerStatus map[int]int
+	// AttemptTimeout bounds each attempt, MaxDuration all attempts of a request and the waits between them
+	AttemptTimeout time.Duration
+	MaxDuration    time.Duration
+	// RetryBudget caps the retries used across the whole batch, 0 means no cap
+	RetryBudget  int
+	Sleep        time.Duration
+	Jitter       float64
+	JitterRand   *rand.Rand
+	Tokens       *TokenSource
+	SigV4        *SigV4Config
+	Netrc        *Netrc
+	Idempotency  bool
+	TraceHeaders bool
+	Cache        *ResponseCache
+	Variables    *VariableScopes
+	CaptureAll   bool
+	Secrets      map[string]string
+	Assertions   []Assertion
+
+	// HostHeaders are the defaults for the hosts matching their pattern, SharedHeaders the defaults for every host
+	HostHeaders   []HostHeaders
+	SharedHeaders map[string]string
+
+	// ExpectStatus fails requests that return another status, a # @expect directive overrides it
+	ExpectStatus int
+	// Extract is a JSON Pointer whose value in each response is printed and added to the summary
+	Extract string
+	// ErrorFields are the JSONPaths tried to pull an error message out of failed JSON responses
+	ErrorFields  []string
+	Consolidated bool
+	Report       ReportOptions
+	Events       *EventLog
+
+	// Backoff is "fixed" to wait Sleep between retries or "adaptive" to scale the wait from the last latency
+	Backoff string
+	// RetryOnEmpty retries successful responses that came back with an empty body
+	RetryOnEmpty bool
+	// RetryOnBody retries responses whose body matches it, such as a {"status":"pending"} job status
+	RetryOnBody *regexp.Regexp
+	// IdempotentOnly refuses to retry POST and PATCH requests unless they carry an Idempotency-Key
+	IdempotentOnly bool
+	// PreserveHeaderCase sends header names as written in the request instead of canonicalized
+	PreserveHeaderCase bool
+	// MinimalHeaders leaves out the User-Agent net/http adds, compression is turned off on the client
+	MinimalHeaders bool
+	// KeepEncoded writes br and zstd response bodies as the server sent them instead of decompressed
+	KeepEncoded bool
+	// Resume continues partial # @save-body and -body-out files with a Range request for the missing bytes
+	Resume bool
+
+	// Paginate follows next page links found with NextSelector, up to MaxPages pages
+	Paginate     bool
+	NextSelector string
+	MaxPages     int
+
+	// FuzzHeaders sends edge-case header variants of each request instead of the request itself
+	FuzzHeaders bool
+	// SmuggleCheck sends requests with conflicting Content-Length and Transfer-Encoding framing instead
+	SmuggleCheck bool
+	// KeepAliveTimeout probes how long the server keeps idle connections open, idling up to this long
+	KeepAliveTimeout time.Duration
+	// H2Priorities sends the request as concurrent HTTP/2 streams with these weights and reports their completion order
+	H2Priorities []int
+
+	// Progress prints upload progress of request bodies at this interval when set
+	Progress time.Duration
+
+	// HedgeAfter sends a duplicate of a request that has not answered within it and keeps the first response
+	HedgeAfter time.Duration
+
+	// Stream writes response body chunks to a -stream.txt report as they arrive, for up to StreamDuration when set
+	Stream         bool
+	StreamDuration time.Duration
+
+	// SSE reads responses as server-sent event streams, up to SSEMaxEvents events
+	SSE          bool
+	SSEMaxEvents int
+
+	// CompareBase also sends each request to this scheme://host and reports the differences
+	CompareBase string
+	// IgnoreJSONPaths are removed from both JSON bodies before CompareBase compares them
+	IgnoreJSONPaths [][]jsonPathSegment
+	// Mirror also sends each request to this scheme://host in the background, without affecting the outcome
+	Mirror string
+	// CertExpiryWarn warns when a server certificate expires within this window
+	CertExpiryWarn time.Duration
+	// RewriteHosts maps request hosts to the hosts they are sent to instead, see rewriteHost
+	RewriteHosts map[string]string
+	// Canary sends CanaryPercent of the requests to this scheme://host instead of their own host
+	Canary        string
+	CanaryPercent float64
+	// DiffHeaders is the JSONPath where an echo endpoint reports the headers it received, which are diffed against the sent ones
+	DiffHeaders string
+
+	// PreScript and PostScript are shell commands run before and after each request
+	PreScript  string
+	PostScript string
+	// BodyTransform is a shell command the request body is piped through before sending
+	BodyTransform string
+
+	// WaitFor switches from retrying failures to polling until WaitStatus is returned
+	WaitFor      bool
+	WaitStatus   int
+	WaitInterval time.Duration
+	WaitTimeout  time.Duration
+
+	// Env holds the -env-file variables, with values from the process environment taking precedence
+	Env map[string]string
+	// Data holds the -data-json fields as {{.field}} variables
+	Data map[string]string
+
+	retriesUsed atomic.Int64
+	certWarned  sync.Map
+	canaryCount atomic.Int64
+	// mirrors tracks the mirrored requests still in flight
+	mirrors sync.WaitGroup
+	// jitterMu guards JitterRand, which parallel requests share
+	jitterMu sync.Mutex
+}
+
+// Outcome is the final state of a request after all of its attempts
+type Outcome struct {
+	Request  RequestData
+	Result   *Result
+	Attempts []Attempt
+	// Err is set when the request failed for a reason other than an assertion
+	Err    error
+	Passed bool
+	// ErrorMessage is the error field pulled from a failed JSON response
+	ErrorMessage string
+	// TraceID is the value of the -trace-id-header response header
+	TraceID string
+	// Extracted is the -extract value of the response, ExtractMissing says why there is none
+	Extracted      string
+	ExtractMissing string
+	// Canary is set when the request was sent to the -canary-url
+	Canary bool
+	// Concurrency is the -ramp-to concurrency the request was started at
+	Concurrency int
+}
+
+// Latency returns the latency of the last attempt
+func (o Outcome) Latency() time.Duration {
+	if o.Result != nil {
+		return o.Result.Latency
+	}
+	if len(o.Attempts) > 0 {
+		return o.Attempts[len(o.Attempts)-1].Latency
+	}
+	return 0
+}
+
+// Run sends a single request, retrying failed attempts, and returns its outcome
+func (r *Runner) Run(reqData RequestData, outputPath string) Outcome {
+	var vars map[string]string
+	if r.Variables != nil {
+		vars = r.Variables.resolveReferences(reqData, r.Variables.Vars(reqData.Source))
+	}
+	// Variables captured from responses override the ones from the environment file
+	if len(r.Env) > 0 {
+		merged := maps.Clone(r.Env)
+		maps.Copy(merged, vars)
+		vars = merged
+	}
+	if len(r.Data) > 0 {
+		vars = maps.Clone(vars)
+		if vars == nil {
+			vars = make(map[string]string, len(r.Data))
+		}
+		maps.Copy(vars, r.Data)
+	}
+	if len(reqData.Row) > 0 {
+		vars = maps.Clone(vars)
+		if vars == nil {
+			vars = make(map[string]string, len(reqData.Row))
+		}
+		maps.Copy(vars, reqData.Row)
+	}
+	if len(r.Secrets) > 0 {
+		vars = maps.Clone(vars)
+		if vars == nil {
+			vars = make(map[string]string, len(r.Secrets))
+		}
+		for name, value := range r.Secrets {
+			vars[secretPrefix+name] = value
+		}
+	}
+	if r.CaptureAll {
+		reqData.Variables = vars
+		if reqData.Variables == nil {
+			reqData.Variables = map[string]string{}
+		}
+	}
+	// Host patterns are matched once the variables in the URL are substituted, so {{baseUrl}} picks its host's headers.
+	// Host specific defaults take precedence over the ones shared by every host.
+	if len(r.HostHeaders) > 0 || len(r.SharedHeaders) > 0 {
+		headers := make(map[string]string, len(reqData.Headers))
+		maps.Copy(headers, reqData.Headers)
+		applyHostHeaders(headers, Substitute(reqData.URL, vars), r.HostHeaders)
+		mergeHeaders(headers, r.SharedHeaders)
+		reqData.Headers = headers
+	}
+	reqData = SubstituteRequest(reqData, vars)
+
+	if len(r.RewriteHosts) > 0 {
+		if target, ok := rewriteHost(reqData.URL, r.RewriteHosts); ok {
+			printInfo("rewrite-host:", reqData.URL, "->", target)
+			reqData.URL = target
+		}
+	}
+
+	// OAuth and SigV4 set their own Authorization header on every attempt
+	if r.Netrc != nil && r.Tokens == nil && r.SigV4 == nil {
+		applyNetrc(&reqData, r.Netrc)
+	}
+
+	// Trace headers are added to the request itself so they show up in its report
+	if r.TraceHeaders {
+		addTraceHeaders(&reqData)
+	}
+
+	// The key is chosen once per request so every retry of it carries the same value
+	if r.Idempotency && !hasHeader(reqData.Headers, "Idempotency-Key") {
+		reqData.Headers["Idempotency-Key"] = newUUID()
+	}
+
+	if r.PreScript != "" {
+		if err := runScript(r.PreScript, reqData, nil); err != nil {
+			printError("pre-script:", err)
+			return Outcome{Request: reqData, Err: fmt.Errorf("pre-script: %w", err)}
+		}
+	}
+
+	if r.BodyTransform != "" {
+		body, err := transformBody(r.BodyTransform, reqData)
+		if err != nil {
+			printError("body-transform:", err)
+			return Outcome{Request: reqData, Err: fmt.Errorf("body-transform: %w", err)}
+		}
+		reqData.Body = body
+	}
+
+	canary := r.Canary != "" && r.takeCanary()
+	if canary {
+		target, err := rebaseURL(reqData.URL, r.Canary)
+		if err != nil {
+			printError("canary:", err)
+			return Outcome{Request: reqData, Err: fmt.Errorf("canary: %w", err), Canary: true}
+		}
+		reqData.URL = target
+	}
+
+	if r.Mirror != "" {
+		r.mirror(reqData, outputPath)
+	}
+
+	outcome := r.send(reqData, outputPath)
+	outcome.Canary = canary
+
+	if outcome.Passed && r.Variables != nil {
+		if err := r.Variables.capture(reqData, outcome.Result); err != nil {
+			printError(err)
+			outcome.Err = err
+			outcome.Passed = false
+		}
+	}
+
+	if r.CertExpiryWarn > 0 && outcome.Result != nil {
+		r.checkCertExpiry(outcome.Result)
+	}
+
+	if r.Report.TraceIDHeader != "" && outcome.Result != nil {
+		outcome.TraceID = outcome.Result.Response.Header.Get(r.Report.TraceIDHeader)
+	}
+
+	if r.Extract != "" && outcome.Result != nil {
+		outcome.Extracted, outcome.ExtractMissing = extractValue(outcome.Result, r.Extract)
+		if outcome.ExtractMissing != "" {
+			printWarning("extract", r.Extract, "from", reqData.URL+":", outcome.ExtractMissing)
+		} else {
+			printInfo("extract", r.Extract+":", outcome.Extracted)
+		}
+	}
+
+	if !outcome.Passed && outcome.Result != nil {
+		if message := extractErrorMessage(outcome.Result, r.ErrorFields); message != "" {
+			printError("error message:", message)
+			outcome.ErrorMessage = message
+		}
+	}
+
+	if r.CompareBase != "" && outcome.Result != nil {
+		r.compare(reqData, outcome.Result, outputPath)
+	}
+
+	if r.DiffHeaders != "" && outcome.Result != nil {
+		r.diffHeaders(reqData, outcome.Result, outputPath)
+	}
+
+	if r.PostScript != "" {
+		if err := runScript(r.PostScript, reqData, outcome.Result); err != nil {
+			printError("post-script:", err)
+			outcome.Err = fmt.Errorf("post-script: %w", err)
+			outcome.Passed = false
+		}
+	}
+
+	// Status lines and summaries show the request without its secrets
+	outcome.Request = maskRequestSecrets(outcome.Request, secretValues(r.Secrets))
+
+	if r.Tee != nil && outcome.Result != nil {
+		r.Tee.Write(outcome.Result.Body)
+	}
+
+	if r.RetryLog != nil && !outcome.Passed && len(outcome.Attempts) > 0 {
+		r.RetryLog.Record(outcome)
+	}
+	return outcome
+}
+
+// send runs the attempts of a request and records the last response received
+func (r *Runner) send(reqData RequestData, outputPath string) Outcome {
+	if r.Cache != nil {
+		r.Cache.Apply(&reqData)
+	}
+
+	if r.WaitFor {
+		return r.wait(reqData, outputPath)
+	}
+
+	if r.Paginate {
+		return r.paginate(reqData, outputPath)
+	}
+
+	if r.FuzzHeaders {
+		return r.fuzz(reqData, outputPath)
+	}
+
+	if r.SmuggleCheck {
+		return r.smuggleCheck(reqData, outputPath)
+	}
+
+	if r.KeepAliveTimeout > 0 {
+		return r.keepAliveProbe(reqData, outputPath)
+	}
+
+	if len(r.H2Priorities) > 0 {
+		return r.priorityTest(reqData, outputPath)
+	}
+
+	outcome := Outcome{Request: reqData}
+
+	// An expected status is never a failure, even a 5xx the request asked for
+	expect := reqData.ExpectStatus
+	if expect == 0 {
+		expect = r.ExpectStatus
+	}
+	assertions := r.Assertions
+	if expect != 0 {
+		assertions = append(slices.Clip(assertions), assertStatus(expect))
+	}
+	failed := func(a Attempt) bool {
+		return a.Failed() && (a.Err != nil || a.StatusCode != expect)
+	}
+
+	var resumeFrom int64
+	if r.Resume {
+		resumeFrom = applyResume(&reqData)
+		outcome.Request = reqData
+	}
+
+	parent := context.Background()
+	if r.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		parent, cancel = context.WithTimeout(parent, r.MaxDuration)
+		defer cancel()
+	}
+
+	for i := 0; ; i++ {
+		// Authorized per attempt since the SigV4 signature covers the request time
+		if err := r.authorize(&reqData); err != nil {
+			printError(err)
+			outcome.Err = err
+			return outcome
+		}
+
+		ctx := parent
+		if r.Events != nil {
+			r.Events.Emit(Event{Event: "request-start", Method: reqData.Method, URL: reqData.URL, Attempt: i + 1})
+			ctx = r.Events.Trace(ctx, reqData, i+1)
+		}
+		if r.Progress > 0 {
+			ctx = withProgress(ctx, r.Progress)
+		}
+		if r.PreserveHeaderCase {
+			ctx = withPreserveHeaderCase(ctx)
+		}
+		if r.MinimalHeaders {
+			ctx = withMinimalHeaders(ctx)
+		}
+		if r.KeepEncoded {
+			ctx = withKeepEncoded(ctx)
+		}
+
+		cancelAttempt := func() {}
+		if r.AttemptTimeout > 0 {
+			ctx, cancelAttempt = context.WithTimeout(ctx, r.AttemptTimeout)
+		}
+
+		start := time.Now()
+		result, err := r.execute(ctx, reqData, outputPath)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			if parent.Err() != nil {
+				err = fmt.Errorf("-max-duration %s exceeded: %w", r.MaxDuration, err)
+			} else {
+				err = fmt.Errorf("attempt timed out after -attempt-timeout %s: %w", r.AttemptTimeout, err)
+			}
+		}
+		cancelAttempt()
+		attempt := NewAttempt(i+1, result, time.Since(start), err)
+		outcome.Attempts = append(outcome.Attempts, attempt)
+
+		if r.Events != nil {
+			r.Events.EmitEnd(reqData, attempt)
+		}
+
+		if err != nil {
+			printError(err)
+		} else {
+			outcome.Result = result
+			runAssertions(result, assertions)
+
+			if r.Cache != nil && !failed(attempt) {
+				if r.Cache.Update(reqData.URL, result.Response) {
+					printInfo("cache hit:", reqData.URL)
+				} else {
+					printInfo("cache miss:", reqData.URL)
+				}
+			}
+
+			report := r.Report
+			report.failed = failed(attempt)
+			err = GenerateReport(outputPath, reqData, result, report)
+
+			if err != nil {
+				printError(err)
+				outcome.Err = err
+				return outcome
+			}
+
+			if reqData.SaveBody != "" {
+				save := saveBody
+				if resumeFrom > 0 {
+					save = func(path string, _ []byte) error { return saveResumed(path, resumeFrom, result) }
+				}
+				if err := save(reqData.SaveBody, result.Body); err != nil {
+					printError(err)
+					outcome.Err = err
+					return outcome
+				}
+			}
+		}
+
+		retryEmpty := r.RetryOnEmpty && err == nil && assertBodyNotEmpty(result) != nil
+		retryBody := r.RetryOnBody != nil && err == nil && r.RetryOnBody.Match(result.Body)
+
+		attempts := r.Retry
+		statusAttempts, retryStatus := r.RetriesPerStatus[attempt.StatusCode]
+		if retryStatus && err == nil {
+			attempts = statusAttempts
+			retryStatus = attempt.StatusCode != expect
+		}
+		if (!failed(attempt) && !retryEmpty && !retryBody && !retryStatus) || i >= attempts-1 {
+			break
+		}
+
+		if parent.Err() != nil {
+			printError("-max-duration", r.MaxDuration, "exceeded, not retrying", reqData.URL)
+			break
+		}
+
+		if r.IdempotentOnly && !retrySafe(reqData) {
+			printWarning("not retrying", reqData.Method, reqData.URL, "without an Idempotency-Key")
+			break
+		}
+
+		if !r.takeRetry() {
+			printError("retry budget exhausted, not retrying", reqData.URL)
+			break
+		}
+
+		base := r.Sleep
+		if r.Backoff == "adaptive" {
+			base = AdaptiveDelay(attempt.Latency)
+		}
+		r.jitterMu.Lock()
+		delay := RetryDelay(base, r.Jitter, r.JitterRand)
+		r.jitterMu.Unlock()
+
+		// The wait ends early when -max-duration runs out, leaving the loop to stop
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-parent.Done():
+			timer.Stop()
+		}
+	}
+
+	if r.Consolidated {
+		err := GenerateConsolidatedReport(r.Report.sink(), outputPath, reqData, outcome.Attempts)
+
+		if err != nil {
+			printError(err)
+			outcome.Err = err
+			return outcome
+		}
+	}
+
+	last := outcome.Attempts[len(outcome.Attempts)-1]
+	if failed(last) {
+		outcome.Err = last.Err
+		if outcome.Err == nil {
+			outcome.Err = fmt.Errorf("server error: %s", last.Status)
+		}
+		return outcome
+	}
+
+	if outcome.Result.Failed() {
+		for _, failure := range outcome.Result.Failures {
+			printError("assertion failed:", failure)
+		}
+		return outcome
+	}
+
+	outcome.Passed = true
+	return outcome
+}
+
+// retrySafe reports whether resending the request cannot create a duplicate
+func retrySafe(reqData RequestData) bool {
+	switch reqData.Method {
+	case http.MethodPost, http.MethodPatch:
+		return hasHeader(reqData.Headers, "Idempotency-Key")
+	}
+	return true
+}
+
+// saveBody writes the raw response body to path, creating its directory if needed
+func saveBody(path string, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, body, 0644)
+}
+
+// takeRetry consumes one retry from the batch budget and reports whether one was available
+func (r *Runner) takeRetry() bool {
+	if r.RetryBudget <= 0 {
+		return true
+	}
+	if r.retriesUsed.Add(1) > int64(r.RetryBudget) {
+		r.retriesUsed.Add(-1)
+		return false
+	}
+	return true
+}
+
+// wait polls the request until it returns the expected status and reports the final response
+func (r *Runner) wait(reqData RequestData, outputPath string) Outcome {
+	outcome := Outcome{Request: reqData}
+
+	result, err := WaitFor(r.Client, reqData, r.WaitStatus, r.WaitInterval, r.WaitTimeout)
+	if err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+	outcome.Result = result
+
+	err = GenerateReport(outputPath, reqData, result, r.Report)
+	if err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	outcome.Passed = true
+	return outcome
+}
+
+// authorize sets the OAuth bearer token and the SigV4 signature of the request. The signature covers
+// the time and host, so every request sent, including the compared and mirrored copies, is signed again.
+func (r *Runner) authorize(reqData *RequestData) error {
+	if r.Tokens != nil {
+		token, err := r.Tokens.Token()
+		if err != nil {
+			return err
+		}
+		reqData.Headers["Authorization"] = "Bearer " + token
+	}
+	if r.SigV4 != nil {
+		return SignSigV4(reqData, *r.SigV4, time.Now())
+	}
+	return nil
+}
+
+// compare sends the request to CompareBase and writes a diff against the primary response
+func (r *Runner) compare(reqData RequestData, primary *Result, outputPath string) {
+	comparedURL, err := rebaseURL(reqData.URL, r.CompareBase)
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	comparedReq := reqData
+	comparedReq.URL = comparedURL
+	comparedReq.Headers = maps.Clone(reqData.Headers)
+	if err := r.authorize(&comparedReq); err != nil {
+		printError("compare:", err)
+		return
+	}
+	compared, err := Execute(context.Background(), r.Client, comparedReq)
+	if err != nil {
+		printError("compare:", err)
+		return
+	}
+
+	if r.Report.NormalizeJSON {
+		primary = &Result{Response: primary.Response, Body: normalizeJSON(primary.Body)}
+		compared.Body = normalizeJSON(compared.Body)
+	}
+	if len(r.IgnoreJSONPaths) > 0 {
+		primary = &Result{Response: primary.Response, Body: stripJSONPaths(primary.Body, r.IgnoreJSONPaths)}
+		compared.Body = stripJSONPaths(compared.Body, r.IgnoreJSONPaths)
+	}
+
+	same, err := GenerateCompareReport(r.Report.sink(), outputPath, reqData, primary, compared, comparedURL)
+	if err != nil {
+		printError(err)
+		return
+	}
+	if !same {
+		printInfo("responses differ:", reqData.URL, comparedURL)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingServer answers every request with a 500
+func failingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRetryBudgetCapsRetriesAcrossBatch(t *testing.T) {
+	srv := failingServer(t)
+	runner := &Runner{Client: srv.Client(), Retry: 3, RetryBudget: 2, Report: ReportOptions{Sink: DiscardSink{}}}
+
+	first := runner.Run(NewURLRequest(srv.URL+"/first"), "first")
+	second := runner.Run(NewURLRequest(srv.URL+"/second"), "second")
+
+	if n := len(first.Attempts); n != 3 {
+		t.Errorf("first request made %d attempts, want 3 using the whole budget of 2 retries", n)
+	}
+	if n := len(second.Attempts); n != 1 {
+		t.Errorf("second request made %d attempts, want 1 with the budget exhausted", n)
+	}
+}
+
+func TestRetriesPerStatusOverridesRetry(t *testing.T) {
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		if r.URL.Path == "/limited" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	retries := retriesPerStatusFlag{}
+	if err := retries.Set("429:5,500:2"); err != nil {
+		t.Fatal(err)
+	}
+	runner := &Runner{Client: srv.Client(), Retry: 3, RetriesPerStatus: retries, Report: ReportOptions{Sink: DiscardSink{}}}
+	runner.Run(NewURLRequest(srv.URL+"/limited"), "limited")
+	runner.Run(NewURLRequest(srv.URL+"/broken"), "broken")
+
+	if calls["/limited"] != 5 || calls["/broken"] != 2 {
+		t.Errorf("server got %d requests for the 429 and %d for the 500, want 5 and 2 instead of -retry 3", calls["/limited"], calls["/broken"])
+	}
+}
+
+func TestIdempotencyKeySharedByRetries(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 2, Idempotency: true, Report: ReportOptions{Sink: DiscardSink{}}}
+	for range 2 {
+		reqData := NewURLRequest(srv.URL)
+		reqData.Method = http.MethodPost
+		if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+			t.Fatalf("request failed: %v", outcome.Err)
+		}
+	}
+
+	if len(keys) != 4 {
+		t.Fatalf("server got %d requests, want 2 attempts of 2 requests", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Errorf("keys = %q, want the retry of each request to resend its key", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("both requests sent key %q, want a new key per request", keys[0])
+	}
+}
+
+func TestSaveBodyWritesExactBytes(t *testing.T) {
+	body := []byte{0x00, 0xff, 0x10, '\r', '\n', 0x80}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "download.http", "# @save-body downloads/file.bin\nGET "+srv.URL+"\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "downloads", "file.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("saved %x, want %x", got, body)
+	}
+}
+
+func TestRetryOnEmptyGetsFullBody(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 1 {
+			w.Write([]byte("full body"))
+		}
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 3, RetryOnEmpty: true, Report: ReportOptions{Sink: DiscardSink{}}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if calls != 2 || string(outcome.Result.Body) != "full body" {
+		t.Errorf("server got %d requests and the result body is %q, want 2 and the full body", calls, outcome.Result.Body)
+	}
+
+	calls = 0
+	runner.RetryOnEmpty = false
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); calls != 1 || len(outcome.Result.Body) != 0 {
+		t.Errorf("without -retry-on-empty the server got %d requests, want the empty body accepted", calls)
+	}
+}
+
+func TestRetryOnBodyRetriesUntilDone(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Write([]byte(`{"status":"pending"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"done"}`))
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 5, RetryOnBody: regexp.MustCompile(`"status":\s*"pending"`), Report: ReportOptions{Sink: DiscardSink{}}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if calls != 3 || string(outcome.Result.Body) != `{"status":"done"}` {
+		t.Errorf("server got %d requests and the result body is %s, want 3 and the done status", calls, outcome.Result.Body)
+	}
+}
+
+func TestIdempotentOnlyDoesNotRetryPOST(t *testing.T) {
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.Method+" "+r.Header.Get("Idempotency-Key")]++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 3, IdempotentOnly: true, Report: ReportOptions{Sink: DiscardSink{}}}
+	post := NewURLRequest(srv.URL)
+	post.Method = http.MethodPost
+	runner.Run(post, "post")
+
+	keyed := NewURLRequest(srv.URL)
+	keyed.Method = http.MethodPost
+	keyed.Headers["Idempotency-Key"] = "k1"
+	runner.Run(keyed, "keyed")
+
+	runner.Run(NewURLRequest(srv.URL), "get")
+
+	want := map[string]int{"POST ": 1, "POST k1": 3, "GET ": 3}
+	for key, n := range want {
+		if calls[key] != n {
+			t.Errorf("%q was sent %d times, want %d", key, calls[key], n)
+		}
+	}
+}
+
+func TestSortReportsIntoPassAndFail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "batch.http", "# @output ok\nGET "+srv.URL+"/ok\n\n###\n# @output broken\nGET "+srv.URL+"/broken\n\n###\n# @output assert\n# @expect 201\nGET "+srv.URL+"/ok\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-sort-reports", "-output", filepath.Join(dir, "out")); code != 1 {
+		t.Fatalf("exit code %d, want 1 for the failed requests: %s", code, stderr)
+	}
+
+	for pattern, want := range map[string]int{"pass/ok|*.txt": 1, "fail/broken|*.txt": 1, "fail/assert|*.txt": 1, "pass/broken|*": 0, "pass/assert|*": 0, "fail/ok|*": 0} {
+		if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) != want {
+			t.Errorf("%s matched %v, want %d reports", pattern, matches, want)
+		}
+	}
+}
+
+func TestAttemptTimeoutRetriesUntilMaxDuration(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the /recovers path answers quickly, from its third call on
+		if r.URL.Path == "/recovers" && calls.Add(1) >= 3 {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 5, AttemptTimeout: 50 * time.Millisecond, Report: ReportOptions{Sink: DiscardSink{}}}
+	outcome := runner.Run(NewURLRequest(srv.URL+"/recovers"), "recovers")
+	if !outcome.Passed || len(outcome.Attempts) != 3 {
+		t.Fatalf("passed %t after %d attempts, want a pass on the 3rd: %v", outcome.Passed, len(outcome.Attempts), outcome.Err)
+	}
+	for _, attempt := range outcome.Attempts[:2] {
+		if attempt.Err == nil || !strings.Contains(attempt.Err.Error(), "attempt timed out after -attempt-timeout 50ms") {
+			t.Errorf("attempt %d error is %v, want the per-attempt timeout", attempt.Number, attempt.Err)
+		}
+	}
+
+	runner = &Runner{Client: srv.Client(), Retry: 100, AttemptTimeout: 50 * time.Millisecond, MaxDuration: 220 * time.Millisecond, Report: ReportOptions{Sink: DiscardSink{}}}
+	start := time.Now()
+	outcome = runner.Run(NewURLRequest(srv.URL+"/hangs"), "hangs")
+	elapsed := time.Since(start)
+	if outcome.Passed || len(outcome.Attempts) < 3 || len(outcome.Attempts) >= 100 {
+		t.Fatalf("passed %t after %d attempts, want several timed out attempts before -max-duration stops the retries", outcome.Passed, len(outcome.Attempts))
+	}
+	if last := outcome.Attempts[len(outcome.Attempts)-1]; last.Err == nil || !strings.Contains(last.Err.Error(), "-max-duration 220ms exceeded") {
+		t.Errorf("last attempt error is %v, want the overall deadline", last.Err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("retries ran for %s, want them stopped at -max-duration 220ms", elapsed)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LoadSchema reads a JSON Schema document from a file
+func LoadSchema(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// ValidateSchema checks value against a JSON Schema and returns every violation found.
+// It supports the commonly used keywords: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength, minimum,
+// maximum, pattern, allOf, anyOf, oneOf, not, nullable and local $ref pointers.
+func ValidateSchema(schema map[string]any, value any) []string {
+	return validateSchemaIn(schema, schema, value)
+}
+
+// validateSchemaIn checks value against a schema nested in root, which its $ref pointers resolve against
+func validateSchemaIn(root, schema map[string]any, value any) []string {
+	v := &schemaValidator{root: root}
+	v.validate(schema, value, "$")
+	return v.errors
+}
+
+type schemaValidator struct {
+	root   map[string]any
+	errors []string
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) validate(schema map[string]any, value any, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		schema = resolved
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return
+		}
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		v.fail(path, "expected %s, got %s", typeNames(t), jsonType(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		v.fail(path, "value %s is not one of the allowed values", compactJSON(value))
+	}
+	if c, ok := schema["const"]; ok && !equalValues(c, value) {
+		v.fail(path, "expected constant %s", compactJSON(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, val, path)
+	case []any:
+		v.validateArray(schema, val, path)
+	case string:
+		if n, ok := number(schema["minLength"]); ok && float64(len([]rune(val))) < n {
+			v.fail(path, "length %d is less than minLength %v", len([]rune(val)), n)
+		}
+		if n, ok := number(schema["maxLength"]); ok && float64(len([]rune(val))) > n {
+			v.fail(path, "length %d is greater than maxLength %v", len([]rune(val)), n)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				v.fail(path, "invalid pattern %q: %v", p, err)
+			} else if !re.MatchString(val) {
+				v.fail(path, "%q does not match pattern %q", val, p)
+			}
+		}
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && val < n {
+			v.fail(path, "%v is less than minimum %v", val, n)
+		}
+		if n, ok := number(schema["maximum"]); ok && val > n {
+			v.fail(path, "%v is greater than maximum %v", val, n)
+		}
+	}
+
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			if s, ok := sub.(map[string]any); ok {
+				v.validate(s, value, path)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && v.countMatches(anyOf, value, path) == 0 {
+		v.fail(path, "value does not match any schema in anyOf")
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if n := v.countMatches(oneOf, value, path); n != 1 {
+			v.fail(path, "value matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, ok := schema["not"].(map[string]any); ok && v.countMatches([]any{not}, value, path) == 1 {
+		v.fail(path, "value must not match the schema in not")
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]any, obj map[string]any, path string) {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				v.fail(path, "missing required property %q", name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+
+	for _, k := range sortedKeys(obj) {
+		if sub, ok := properties[k].(map[string]any); ok {
+			v.validate(sub, obj[k], path+"."+k)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				v.fail(path, "additional property %q is not allowed", k)
+			}
+		case map[string]any:
+			v.validate(extra, obj[k], path+"."+k)
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]any, arr []any, path string) {
+	if n, ok := number(schema["minItems"]); ok && float64(len(arr)) < n {
+		v.fail(path, "%d items is less than minItems %v", len(arr), n)
+	}
+	if n, ok := number(schema["maxItems"]); ok && float64(len(arr)) > n {
+		v.fail(path, "%d items is greater than maxItems %v", len(arr), n)
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		for i, item := range arr {
+			v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// countMatches returns how many of the schemas value satisfies, without recording their errors
+func (v *schemaValidator) countMatches(schemas []any, value any, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		s, ok := sub.(map[string]any)
+		if !ok {
+			continue
+		}
+		trial := &schemaValidator{root: v.root}
+		trial.validate(s, value, path)
+		if len(trial.errors) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolve follows a local JSON pointer such as #/definitions/user
+func (v *schemaValidator) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q, only local references are allowed", ref)
+	}
+
+	var node any = v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if node, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+
+	schema, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q does not point to a schema", ref)
+	}
+	return schema, nil
+}
+
+func matchesType(t any, value any) bool {
+	switch t := t.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []any:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value any) bool {
+	if name == "integer" {
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	if name == "number" {
+		_, ok := value.(float64)
+		return ok
+	}
+	return jsonType(value) == name
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		var names []string
+		for _, n := range list {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonType names the JSON type of a value decoded by encoding/json
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func containsValue(list []any, value any) bool {
+	for _, item := range list {
+		if equalValues(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a, b any) bool {
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// sortedKeys returns the keys of a JSON object in sorted order
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string"}
+	}
+}`
+
+func TestSchemaAssertion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/valid" {
+			w.Write([]byte(`{"id": 7, "name": "Alice"}`))
+			return
+		}
+		w.Write([]byte(`{"id": 0}`))
+	}))
+	defer srv.Close()
+
+	schema, err := LoadSchema(writeFile(t, t.TempDir(), "user.json", userSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Assertions: []Assertion{assertSchema(schema)}, Report: ReportOptions{Sink: DiscardSink{}}}
+
+	if outcome := runner.Run(NewURLRequest(srv.URL+"/valid"), "valid"); !outcome.Passed {
+		t.Errorf("valid body failed: %v", outcome.Result.Failures)
+	}
+
+	outcome := runner.Run(NewURLRequest(srv.URL+"/invalid"), "invalid")
+	if outcome.Passed {
+		t.Fatal("invalid body passed")
+	}
+	failures := strings.Join(outcome.Result.Failures, "\n")
+	for _, want := range []string{"schema: $: missing required property \"name\"", "schema: $.id:"} {
+		if !strings.Contains(failures, want) {
+			t.Errorf("failures are missing %q:\n%s", want, failures)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// VariableScopes holds the variables captured from responses, one scope per source file unless Shared is set
+type VariableScopes struct {
+	// Shared puts every source file in a single scope so captures are visible across files
+	Shared bool
+
+	mu     sync.Mutex
+	scopes map[string]map[string]string
+	// responses holds the decoded JSON bodies of # @name requests, referenced as {{name.field}}
+	responses map[string]map[string]any
+}
+
+// key returns the scope a request from source belongs to
+func (s *VariableScopes) key(source string) string {
+	if s.Shared {
+		return ""
+	}
+	return source
+}
+
+// Vars returns a copy of the variables visible to requests from source
+func (s *VariableScopes) Vars(source string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.scopes[s.key(source)])
+}
+
+// Set stores a variable in the scope of source
+func (s *VariableScopes) Set(source, name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scopes == nil {
+		s.scopes = make(map[string]map[string]string)
+	}
+	key := s.key(source)
+	if s.scopes[key] == nil {
+		s.scopes[key] = make(map[string]string)
+	}
+	s.scopes[key][name] = value
+}
+
+// SetResponse stores the decoded JSON body of the named request in the scope of source
+func (s *VariableScopes) SetResponse(source, name string, doc any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.responses == nil {
+		s.responses = make(map[string]map[string]any)
+	}
+	key := s.key(source)
+	if s.responses[key] == nil {
+		s.responses[key] = make(map[string]any)
+	}
+	s.responses[key][name] = doc
+}
+
+// resolveReferences adds the {{name.field}} placeholders of reqData that point into the response
+// of an earlier named request to vars, evaluating the part after the name as a JSONPath
+func (s *VariableScopes) resolveReferences(reqData RequestData, vars map[string]string) map[string]string {
+	s.mu.Lock()
+	responses := s.responses[s.key(reqData.Source)]
+	s.mu.Unlock()
+	if len(responses) == 0 {
+		return vars
+	}
+
+	texts := []string{reqData.URL, reqData.Body}
+	for _, v := range reqData.Headers {
+		texts = append(texts, v)
+	}
+
+	for _, text := range texts {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			expr := m[1]
+			if _, ok := vars[expr]; ok {
+				continue
+			}
+			end := strings.IndexAny(expr, ".[")
+			if end <= 0 {
+				continue
+			}
+			doc, ok := responses[expr[:end]]
+			if !ok {
+				continue
+			}
+			value, ok, err := lookupJSONPath(doc, "$"+expr[end:])
+			if err != nil || !ok {
+				continue
+			}
+			if vars == nil {
+				vars = make(map[string]string)
+			}
+			vars[expr] = jsonValueString(value)
+		}
+	}
+	return vars
+}
+
+// capture stores the response of a named request and the values of its @capture JSONPaths and @capture-header headers
+func (s *VariableScopes) capture(reqData RequestData, result *Result) error {
+	var doc any
+	var decoded bool
+
+	// A named request's JSON response can be referenced from later requests
+	if reqData.Name != "" && json.Unmarshal(result.Body, &doc) == nil {
+		decoded = true
+		s.SetResponse(reqData.Source, reqData.Name, doc)
+	}
+
+	for _, c := range reqData.Captures {
+		if c.Header != "" {
+			value := result.Response.Header.Get(c.Header)
+			if value == "" {
+				return fmt.Errorf("capture %s: header %s not found in response", c.Name, c.Header)
+			}
+			s.Set(reqData.Source, c.Name, value)
+			continue
+		}
+
+		if !decoded {
+			if err := json.Unmarshal(result.Body, &doc); err != nil {
+				return fmt.Errorf("capture: response is not JSON: %w", err)
+			}
+			decoded = true
+		}
+
+		value, ok, err := lookupJSONPath(doc, c.Path)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("capture %s: %s not found in response", c.Name, c.Path)
+		}
+		s.Set(reqData.Source, c.Name, jsonValueString(value))
+	}
+	return nil
+}
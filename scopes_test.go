@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCapturedVariablesScopedPerFile(t *testing.T) {
+	var gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"token":"abc"}`))
+			return
+		}
+		gotToken = r.Header.Get("X-Token")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.http", "# @capture token $.token\nPOST "+srv.URL+"/login\n")
+	b := writeFile(t, dir, "b.http", "GET "+srv.URL+"/profile\nX-Token: {{token}}\n")
+
+	runMain(t, dir, "-source", a+","+b, "-output", "isolated")
+	if gotToken != "{{token}}" {
+		t.Errorf("b.http sent X-Token %q without -shared-vars, want the placeholder left unresolved", gotToken)
+	}
+
+	gotToken = ""
+	if _, stderr, code := runMain(t, dir, "-source", a+","+b, "-shared-vars", "-output", "shared"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gotToken != "abc" {
+		t.Errorf("b.http sent X-Token %q with -shared-vars, want the token captured in a.http", gotToken)
+	}
+}
+
+func TestCaptureHeaderChainsLocation(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Location", "/users/42")
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		gotPath = r.URL.Path
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "chain.http", "# @capture-header resource = Location\nPOST "+srv.URL+"/users\n\n###\nGET "+srv.URL+"{{resource}}/profile\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if gotPath != "/users/42/profile" {
+		t.Errorf("second request went to %q, want the captured Location /users/42/profile", gotPath)
+	}
+}
+
+func TestBodyEmbedsIDFromNamedResponse(t *testing.T) {
+	var echoed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/parents" {
+			w.Write([]byte(`{"data":{"id":"p-17"}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		echoed = string(body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "chain.http", "# @name created\nPOST "+srv.URL+"/parents\n\n###\nPOST "+srv.URL+"/children\nContent-Type: application/json\n\n{\"parentId\": \"{{created.data.id}}\"}\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := `{"parentId": "p-17"}`; echoed != want {
+		t.Errorf("server echoed %s, want %s", echoed, want)
+	}
+	if report := readReport(t, filepath.Join(dir, "out-2|*.txt")); !strings.Contains(report, "Response Body:\n{\"parentId\": \"p-17\"}") {
+		t.Errorf("second report does not show the echoed id:\n%s", report)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runScript executes a shell command with the request, and the response when there is one, in its environment
+func runScript(command string, reqData RequestData, result *Result) error {
+	cmd := scriptCommand(command, reqData)
+	cmd.Stdout = os.Stdout
+
+	if result != nil {
+		cmd.Env = append(cmd.Env,
+			fmt.Sprintf("HTTP2TEST_STATUS=%d", result.Response.StatusCode),
+			fmt.Sprintf("HTTP2TEST_LATENCY_MS=%d", result.Latency.Milliseconds()),
+		)
+	}
+
+	return cmd.Run()
+}
+
+// transformBody pipes the request body through a shell command and returns its output as the new body
+func transformBody(command string, reqData RequestData) (string, error) {
+	cmd := scriptCommand(command, reqData)
+	cmd.Stdin = strings.NewReader(reqData.Body)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// scriptCommand builds the sh -c command for a script with the request in its environment
+func scriptCommand(command string, reqData RequestData) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"HTTP2TEST_METHOD="+reqData.Method,
+		"HTTP2TEST_URL="+reqData.URL,
+	)
+	return cmd
+}
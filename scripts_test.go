@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptsRunAroundRequest(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "log")
+	appendLog := func(line string) {
+		f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		f.WriteString(line + "\n")
+		f.Close()
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appendLog("request")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	runner := &Runner{
+		Client:     srv.Client(),
+		Retry:      1,
+		PreScript:  `echo "pre $HTTP2TEST_METHOD" >> ` + log,
+		PostScript: `echo "post $HTTP2TEST_STATUS" >> ` + log,
+		Report:     ReportOptions{Sink: DiscardSink{}},
+	}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "pre GET\nrequest\npost 201\n"; got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
+
+func TestFailingPreScriptAbortsRequest(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 1, PreScript: "exit 3", Report: ReportOptions{Sink: DiscardSink{}}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+
+	if outcome.Passed || outcome.Err == nil || !strings.HasPrefix(outcome.Err.Error(), "pre-script:") {
+		t.Errorf("outcome error = %v, want a pre-script failure", outcome.Err)
+	}
+	if called {
+		t.Error("request was sent after the pre-script failed")
+	}
+}
+
+func TestBodyTransformPipesBodyThroughCommand(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, string(body))
+	}))
+	defer srv.Close()
+
+	reqData := NewURLRequest(srv.URL)
+	reqData.Method = http.MethodPost
+	reqData.Body = `{"name":"alice"}`
+
+	runner := &Runner{Client: srv.Client(), Retry: 1, BodyTransform: "tr a-z A-Z", Report: ReportOptions{Sink: DiscardSink{}}}
+	if outcome := runner.Run(reqData, "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if len(got) != 1 || got[0] != `{"NAME":"ALICE"}` {
+		t.Errorf("server got %q, want the upper-cased body", got)
+	}
+
+	runner.BodyTransform = "exit 1"
+	outcome := runner.Run(reqData, "out")
+	if outcome.Err == nil || !strings.HasPrefix(outcome.Err.Error(), "body-transform:") {
+		t.Errorf("outcome error = %v, want a body-transform failure", outcome.Err)
+	}
+	if len(got) != 1 {
+		t.Error("request was sent after the body transform failed")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretPrefix marks {{secret:NAME}} placeholders, which resolve from -secrets-file
+const secretPrefix = "secret:"
+
+// ReadSecretsFile parses a file of NAME=value lines, skipping blank lines and # comments
+func ReadSecretsFile(filePath string) (map[string]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s:%d: invalid secret, expected NAME=value", filePath, lineNumber)
+		}
+		secrets[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// secretValues returns the non-empty values of secrets, the strings masked in reports
+func secretValues(secrets map[string]string) []string {
+	var values []string
+	for _, value := range secrets {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// maskSecrets replaces every secret value in text with ***
+func maskSecrets(text string, values []string) string {
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, redactedValue)
+	}
+	return text
+}
+
+// maskRequestSecrets returns a copy of reqData with secret values masked in the URL, headers and body
+func maskRequestSecrets(reqData RequestData, values []string) RequestData {
+	if len(values) == 0 {
+		return reqData
+	}
+	out := reqData
+	out.URL = maskSecrets(reqData.URL, values)
+	out.Body = maskSecrets(reqData.Body, values)
+	out.Headers = make(map[string]string, len(reqData.Headers))
+	for k, v := range reqData.Headers {
+		out.Headers[k] = maskSecrets(v, values)
+	}
+	return out
+}
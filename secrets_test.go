@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretResolvesFromFileAndIsRedacted(t *testing.T) {
+	var gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotAuth, gotBody = r.Header.Get("X-Api-Token"), string(body)
+		// Echo the token back so the response side of the report is checked too
+		w.Write([]byte("issued to " + gotAuth))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	secrets := writeFile(t, dir, "secrets.env", "# vault export\ntoken = s3cr3t-value\n")
+	source := writeFile(t, dir, "login.http", "POST "+srv.URL+"/login\nX-Api-Token: {{secret:token}}\n\n{\"token\":\"{{secret:token}}\"}\n")
+	stdout, stderr, code := runMain(t, dir, "-source", source, "-secrets-file", secrets, "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	if gotAuth != "s3cr3t-value" || gotBody != `{"token":"s3cr3t-value"}` {
+		t.Errorf("server got X-Api-Token %q and body %q, want the value from the secrets file", gotAuth, gotBody)
+	}
+	report := readReport(t, filepath.Join(dir, "out|*.txt"))
+	if strings.Contains(report, "s3cr3t-value") || strings.Contains(stdout+stderr, "s3cr3t-value") {
+		t.Errorf("the secret leaks into the output:\n%s\n%s%s", report, stdout, stderr)
+	}
+	for _, want := range []string{"X-Api-Token: ***", `{"token":"***"}`, "issued to ***"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report is missing %q:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// shorthandItemPattern matches a "key=value" or "key:=raw" JSON shorthand item
+var shorthandItemPattern = regexp.MustCompile(`^([^=:\s]+)(:?=)(.*)$`)
+
+// expandJSONShorthand turns a body of whitespace-separated "key=value" and "key:=raw" items into a JSON object.
+// "=" values become strings and ":=" values are inserted as raw JSON, like httpie. Keys keep their order.
+// It reports false when the body is JSON or no line is shorthand, so regular bodies are left alone,
+// and fails when only some of the non-blank lines are.
+func expandJSONShorthand(body string) (string, bool, error) {
+	if json.Valid([]byte(body)) {
+		return body, false, nil
+	}
+
+	var matches [][]string
+	var invalid []string
+	for _, line := range strings.Split(body, "\n") {
+		items := strings.Fields(line)
+		if len(items) == 0 {
+			continue
+		}
+
+		lineMatches, ok := matchShorthandItems(items)
+		if !ok {
+			invalid = append(invalid, strings.TrimSpace(line))
+			continue
+		}
+		matches = append(matches, lineMatches...)
+	}
+	if len(matches) == 0 {
+		return body, false, nil
+	}
+	if len(invalid) > 0 {
+		return "", false, fmt.Errorf("JSON shorthand body line is not key=value or key:=raw items: %s", invalid[0])
+	}
+
+	var b strings.Builder
+	b.WriteString("{")
+	for i, m := range matches {
+		key, op, value := m[1], m[2], m[3]
+
+		if op == ":=" {
+			if !json.Valid([]byte(value)) {
+				return "", false, fmt.Errorf("invalid raw JSON value for %s: %s", key, value)
+			}
+		} else {
+			quoted, err := json.Marshal(value)
+			if err != nil {
+				return "", false, err
+			}
+			value = string(quoted)
+		}
+
+		quotedKey, err := json.Marshal(key)
+		if err != nil {
+			return "", false, err
+		}
+
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.Write(quotedKey)
+		b.WriteString(":")
+		b.WriteString(value)
+	}
+	b.WriteString("}")
+
+	return b.String(), true, nil
+}
+
+// matchShorthandItems matches every item of a line, reporting false when any is not shorthand
+func matchShorthandItems(items []string) ([][]string, bool) {
+	var matches [][]string
+	for _, item := range items {
+		m := shorthandItemPattern.FindStringSubmatch(item)
+		if m == nil {
+			return nil, false
+		}
+		matches = append(matches, m)
+	}
+	return matches, true
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJSONShorthandBody(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "shorthand.http", "POST "+srv.URL+"\nContent-Type: application/json\n\nname=Alice age:=30\n")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", "out"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if want := `{"name":"Alice","age":30}`; got != want {
+		t.Errorf("server got body %s, want %s", got, want)
+	}
+}
+
+func TestJSONShorthandLeavesOtherBodiesAlone(t *testing.T) {
+	for _, body := range []string{`{"query": "a=b"}`, "[\n  \"a=b\",\n  \"c\"\n]", "plain text"} {
+		expanded, ok, err := expandJSONShorthand(body)
+		if err != nil || ok || expanded != body {
+			t.Errorf("expandJSONShorthand(%q) = %q, %t, %v, want the body unchanged", body, expanded, ok, err)
+		}
+	}
+
+	multiline, ok, err := expandJSONShorthand("name=Alice\n\ntags:=[\"a\"]")
+	if err != nil || !ok || multiline != `{"name":"Alice","tags":["a"]}` {
+		t.Errorf("multi-line shorthand = %q, %t, %v", multiline, ok, err)
+	}
+
+	if _, _, err := expandJSONShorthand("name=Alice\nnot shorthand"); err == nil || !strings.Contains(err.Error(), "not shorthand") {
+		t.Errorf("mixed body returned %v, want an error naming the bad line", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SigV4Config holds the credentials and scope used to sign requests with AWS Signature Version 4
+type SigV4Config struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+}
+
+// sigV4SignedHeaders are the headers covered by the signature, in canonical order
+const sigV4SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+
+// SignSigV4 sets the x-amz-date, x-amz-content-sha256 and Authorization headers of the request
+func SignSigV4(reqData *RequestData, config SigV4Config, now time.Time) error {
+	u, err := url.Parse(reqData.URL)
+	if err != nil {
+		return err
+	}
+
+	host := u.Host
+	for k, v := range reqData.Headers {
+		if strings.EqualFold(k, "Host") {
+			host = v
+		}
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(reqData.Body)
+
+	canonicalRequest := strings.Join([]string{
+		reqData.Method,
+		canonicalURI(u, config.Service),
+		canonicalQuery(u.Query()),
+		"host:" + host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		sigV4SignedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, config.Region, config.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, config.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	reqData.Headers["X-Amz-Date"] = amzDate
+	reqData.Headers["X-Amz-Content-Sha256"] = payloadHash
+	reqData.Headers["Authorization"] = fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKey, scope, sigV4SignedHeaders, signature)
+	return nil
+}
+
+// canonicalURI encodes each path segment, twice for every service except s3, which signs the path encoded once
+func canonicalURI(u *url.URL, service string) string {
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, segment := range segments {
+		// Decoding first keeps %2F and other escapes the URL already has from being encoded again
+		if decoded, err := url.PathUnescape(segment); err == nil {
+			segment = decoded
+		}
+		segment = awsEscape(segment)
+		if service != "s3" {
+			segment = awsEscape(segment)
+		}
+		segments[i] = segment
+	}
+
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+// canonicalQuery encodes the query parameters sorted by name and then value
+func canonicalQuery(query url.Values) string {
+	var pairs [][2]string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{awsEscape(name), awsEscape(value)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// awsEscape percent-encodes everything except the RFC 3986 unreserved characters
+func awsEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
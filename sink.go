@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ReportSink is where generated reports are written
+type ReportSink interface {
+	// Create opens a new report with the given name
+	Create(name string) (io.WriteCloser, error)
+}
+
+// FileSink writes each report to its own file, relative to Dir when it is set
+type FileSink struct {
+	Dir string
+}
+
+func (s FileSink) Create(name string) (io.WriteCloser, error) {
+	if s.Dir != "" {
+		name = filepath.Join(s.Dir, name)
+	}
+	// Reports may go into a directory of their own, such as the pass/ and fail/ of -sort-reports
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(name)
+}
+
+// StdoutSink writes every report to stdout, each preceded by its name
+type StdoutSink struct{}
+
+func (StdoutSink) Create(name string) (io.WriteCloser, error) {
+	if _, err := fmt.Fprintf(stdout, "==> %s <==\n", name); err != nil {
+		return nil, err
+	}
+	return nopWriteCloser{stdout}, nil
+}
+
+// DiscardSink drops every report, for runs that only print results such as -status-only
+type DiscardSink struct{}
+
+func (DiscardSink) Create(string) (io.WriteCloser, error) {
+	return nopWriteCloser{io.Discard}, nil
+}
+
+// AppendSink appends every report to the single file at Path, each preceded by a line with its name and time
+type AppendSink struct {
+	Path string
+	// mu keeps reports closed by parallel requests from interleaving
+	mu sync.Mutex
+}
+
+func (s *AppendSink) Create(name string) (io.WriteCloser, error) {
+	return &appendReport{sink: s, name: name}, nil
+}
+
+// appendReport buffers a report and appends it to the sink file in one write when it is closed
+type appendReport struct {
+	bytes.Buffer
+	sink *AppendSink
+	name string
+}
+
+func (r *appendReport) Close() error {
+	r.sink.mu.Lock()
+	defer r.sink.mu.Unlock()
+
+	file, err := os.OpenFile(r.sink.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(file, "==> %s %s <==\n%s\n", r.name, time.Now().Format(time.RFC3339), r.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// newReportSink returns the sink for a -report-sink value
+func newReportSink(kind, dir string) (ReportSink, error) {
+	switch kind {
+	case "", "file":
+		return FileSink{Dir: dir}, nil
+	case "stdout":
+		return StdoutSink{}, nil
+	}
+	return nil, fmt.Errorf("unknown report sink %q, expected file or stdout", kind)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memorySink keeps reports in memory so tests can read them without touching the disk
+type memorySink struct {
+	mu      sync.Mutex
+	reports map[string]*bytes.Buffer
+}
+
+func (s *memorySink) Create(name string) (io.WriteCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reports == nil {
+		s.reports = make(map[string]*bytes.Buffer)
+	}
+	buf := &bytes.Buffer{}
+	s.reports[name] = buf
+	return nopWriteCloser{buf}, nil
+}
+
+// names returns the names of the reports written, sorted
+func (s *memorySink) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.reports))
+	for name := range s.reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// report returns the only report whose name contains part
+func (s *memorySink) report(t *testing.T, part string) string {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []string
+	for name, buf := range s.reports {
+		if strings.Contains(name, part) {
+			found = append(found, buf.String())
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("%d reports named *%s*, want 1: %v", len(found), part, s.reports)
+	}
+	return found[0]
+}
+
+func TestReportsGoThroughCustomSink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("in memory"))
+	}))
+	defer srv.Close()
+
+	// Reports written to the working directory would show up here
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Consolidated: true, Report: ReportOptions{Sink: sink, Formats: []string{"txt", "json"}}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	names := sink.names()
+	for _, suffix := range []string{"-attempts.txt", "-status:200.json", "-status:200.txt"} {
+		if !slices.ContainsFunc(names, func(name string) bool { return strings.HasSuffix(name, suffix) }) {
+			t.Errorf("sink got reports %q, want one ending in %s", names, suffix)
+		}
+	}
+	if len(names) != 3 {
+		t.Errorf("sink got %d reports, want 3", len(names))
+	}
+	if report := sink.report(t, "-status:200.txt"); !strings.Contains(report, "in memory") {
+		t.Errorf("txt report does not hold the body:\n%s", report)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("reports were written to disk: %v", entries)
+	}
+}
+
+func TestOutputAppendAccumulatesRunsInOrder(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, "run %d", calls)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	logPath := "monitor.log"
+	for range 2 {
+		if _, stderr, code := runMain(t, dir, "-url", srv.URL, "-output-append", logPath); code != 0 {
+			t.Fatalf("exit code %d: %s", code, stderr)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, logPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	separators := regexp.MustCompile(`(?m)^==> monitor\|\d+-status:200\.txt \d{4}-\d\d-\d\dT\S+ <==$`).FindAllStringIndex(log, -1)
+	if len(separators) != 2 {
+		t.Fatalf("log has %d report separators, want 2:\n%s", len(separators), log)
+	}
+	first, second := strings.Index(log, "run 1"), strings.Index(log, "run 2")
+	if first < separators[0][1] || first > separators[1][0] || second < separators[1][1] {
+		t.Errorf("reports are not appended in run order after their separators:\n%s", log)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("working directory holds %d files, want only the log", len(entries))
+	}
+}
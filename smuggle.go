@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smuggleCheckTimeout bounds each check when no -timeout is set, a server waiting for more body is a finding
+const smuggleCheckTimeout = 10 * time.Second
+
+// smuggleProbe is a request with ambiguous message framing
+type smuggleProbe struct {
+	Name    string
+	Headers [][2]string
+	Body    string
+}
+
+// smuggleProbes returns the framing conflicts -smuggle-check sends. The bodies only carry a
+// one byte prefix, so a server that desyncs is detected without smuggling a real request.
+func smuggleProbes() []smuggleProbe {
+	return []smuggleProbe{
+		// Front ends honoring Content-Length forward the X, chunked back ends leave it queued
+		{Name: "cl-te", Headers: [][2]string{{"Content-Length", "6"}, {"Transfer-Encoding", "chunked"}}, Body: "0\r\n\r\nX"},
+		// Front ends honoring chunked forward the whole body, Content-Length back ends stop early
+		{Name: "te-cl", Headers: [][2]string{{"Content-Length", "3"}, {"Transfer-Encoding", "chunked"}}, Body: "1\r\nX\r\n0\r\n\r\n"},
+		{Name: "te-te", Headers: [][2]string{{"Transfer-Encoding", "chunked"}, {"Transfer-Encoding", "x"}, {"Content-Length", "6"}}, Body: "0\r\n\r\nX"},
+		{Name: "te-space", Headers: [][2]string{{"Transfer-Encoding ", "chunked"}, {"Content-Length", "6"}}, Body: "0\r\n\r\nX"},
+		{Name: "duplicate-cl", Headers: [][2]string{{"Content-Length", "1"}, {"Content-Length", "2"}}, Body: "XX"},
+	}
+}
+
+// smuggleResult is what the server did with one probe
+type smuggleResult struct {
+	Probe   string
+	Raw     []byte
+	Status  string
+	Err     error
+	Latency time.Duration
+}
+
+// smuggleCheck sends every ambiguous framing probe to the request URL and reports how the server answered.
+// The check is informational, it only fails the run when a probe could not be written.
+func (r *Runner) smuggleCheck(reqData RequestData, outputPath string) Outcome {
+	outcome := Outcome{Request: reqData}
+
+	u, err := url.Parse(reqData.URL)
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+
+	timeout := r.Client.Timeout
+	if timeout <= 0 {
+		timeout = smuggleCheckTimeout
+	}
+
+	host := u.Host
+	for k, v := range reqData.Headers {
+		if strings.EqualFold(k, "Host") {
+			host = v
+		}
+	}
+
+	var results []smuggleResult
+	for i, probe := range smuggleProbes() {
+		headers := append([][2]string{{"Host", host}}, probe.Headers...)
+		raw := buildRawRequest("POST", u, headers, probe.Body)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		response, err := exchangeRaw(ctx, r.Client, u, "POST", raw)
+		cancel()
+
+		result := smuggleResult{Probe: probe.Name, Raw: raw, Err: err, Latency: time.Since(start)}
+		attempt := Attempt{Number: i + 1, Latency: result.Latency, Err: err}
+		if response != nil {
+			result.Status = response.Status
+			attempt.StatusCode, attempt.Status = response.StatusCode, response.Status
+			printInfo("smuggle", probe.Name+":", response.Status)
+		} else {
+			printInfo("smuggle", probe.Name+":", "error:", err)
+		}
+		outcome.Attempts = append(outcome.Attempts, attempt)
+		results = append(results, result)
+	}
+
+	if err := GenerateSmuggleReport(r.Report.sink(), outputPath, reqData, results); err != nil {
+		printError(err)
+		outcome.Err = err
+		return outcome
+	}
+
+	outcome.Passed = true
+	return outcome
+}
+
+// GenerateSmuggleReport writes the exact bytes of every probe with the status or error it got
+func GenerateSmuggleReport(sink ReportSink, outputPath string, reqData RequestData, results []smuggleResult) error {
+	file, err := sink.Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-smuggle.txt")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request URL: %s\nProbes: %d\n", reqData.URL, len(results)))
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		status := result.Status
+		if result.Err != nil {
+			status = "error: " + result.Err.Error()
+		}
+		_, err = io.WriteString(file, fmt.Sprintf("\nProbe: %s\nResponse: %s (%.1fms)\nSent: %s\n", result.Probe, status, milliseconds(result.Latency), strconv.Quote(string(result.Raw))))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startRawRecorder accepts connections, records the bytes each one sends until it is quiet for 100ms and answers 200
+func startRawRecorder(t *testing.T) (string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var data []byte
+			buf := make([]byte, 4096)
+			for {
+				conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+				n, err := conn.Read(buf)
+				data = append(data, buf[:n]...)
+				if err != nil {
+					break
+				}
+			}
+			received <- string(data)
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSmuggleCheckSendsRawBytes(t *testing.T) {
+	addr, received := startRawRecorder(t)
+
+	sink := &memorySink{}
+	runner := &Runner{Client: &http.Client{}, Retry: 1, SmuggleCheck: true, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest("http://"+addr+"/upload"), "out"); !outcome.Passed {
+		t.Fatalf("smuggle check failed: %v", outcome.Err)
+	}
+
+	want := map[string]string{
+		"cl-te":        "Content-Length: 6\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n0\r\n\r\nX",
+		"te-cl":        "Content-Length: 3\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n1\r\nX\r\n0\r\n\r\n",
+		"te-te":        "Transfer-Encoding: chunked\r\nTransfer-Encoding: x\r\nContent-Length: 6\r\nConnection: close\r\n\r\n0\r\n\r\nX",
+		"te-space":     "Transfer-Encoding : chunked\r\nContent-Length: 6\r\nConnection: close\r\n\r\n0\r\n\r\nX",
+		"duplicate-cl": "Content-Length: 1\r\nContent-Length: 2\r\nConnection: close\r\n\r\nXX",
+	}
+	for _, probe := range smuggleProbes() {
+		got := <-received
+		if expected := "POST /upload HTTP/1.1\r\nHost: " + addr + "\r\n" + want[probe.Name]; got != expected {
+			t.Errorf("%s sent %q, want %q", probe.Name, got, expected)
+		}
+	}
+
+	report := sink.report(t, "-smuggle.txt")
+	if !strings.Contains(report, "Probe: cl-te\nResponse: 200 OK") || !strings.Contains(report, `Sent: "POST /upload HTTP/1.1\r\nHost: `) {
+		t.Errorf("smuggle report does not record the probes and their responses:\n%s", report)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// sourceFetchTimeout bounds fetching a remote -source
+const sourceFetchTimeout = 30 * time.Second
+
+// isRemoteSource reports whether a -source path is an http(s):// URL instead of a file
+func isRemoteSource(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// openSource opens a -source file, or fetches it when the path is an http(s):// URL
+func openSource(path string) (io.ReadCloser, error) {
+	if !isRemoteSource(path) {
+		return os.Open(path)
+	}
+
+	client := &http.Client{Timeout: sourceFetchTimeout}
+	response, err := client.Get(path)
+	if err != nil {
+		return nil, fmt.Errorf("fetching source: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("fetching source %s: %s", path, response.Status)
+	}
+	return response.Body, nil
+}
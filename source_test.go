@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteSourceFetchedAndParsed(t *testing.T) {
+	var gotBody string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+	}))
+	defer target.Close()
+
+	definitions := "# @name create\nPOST " + target.URL + "/items\nContent-Type: application/json\n\n{\"id\":1}\n"
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/shared/api.http" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(definitions))
+	}))
+	defer repo.Close()
+
+	requests, err := ReadHTTPFile(repo.URL+"/shared/api.http", ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("parsed %d requests, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.Name != "create" || got.Method != "POST" || got.URL != target.URL+"/items" || got.Headers["Content-Type"] != "application/json" || got.Body != `{"id":1}` {
+		t.Errorf("parsed %+v, want the named POST with its header and body", got)
+	}
+
+	dir := t.TempDir()
+	if _, stderr, code := runMain(t, dir, "-source", repo.URL+"/shared/api.http", "-output", "out"); code != 0 || gotBody != `{"id":1}` {
+		t.Errorf("running the remote source: exit code %d and the target got %q, want 0 and the body: %s", code, gotBody, stderr)
+	}
+
+	_, stderr, code := runMain(t, dir, "-source", repo.URL+"/missing.http", "-output", "missing")
+	if code == 0 || !strings.Contains(stderr, "fetching source "+repo.URL+"/missing.http: 404 Not Found") {
+		t.Errorf("missing remote source: exit code %d, want a failure naming the 404: %s", code, stderr)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// SSEEvent is one server-sent event read from a text/event-stream response
+type SSEEvent struct {
+	Event string `json:"event"`
+	ID    string `json:"id,omitempty"`
+	Data  string `json:"data"`
+}
+
+// ExecuteSSE sends the request and reads server-sent events from the response until
+// maxEvents have arrived or the stream ends, 0 means no limit
+func ExecuteSSE(ctx context.Context, client *http.Client, reqData RequestData, maxEvents int) (*Result, error) {
+	var events []SSEEvent
+	result, err := execute(ctx, client, reqData, func(r io.Reader) ([]byte, error) {
+		var body []byte
+		var err error
+		body, events, err = readSSE(r, maxEvents)
+		return body, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Events = events
+
+	if mediaType, _, _ := mime.ParseMediaType(result.Response.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		result.Failures = append(result.Failures, fmt.Sprintf("expected a text/event-stream response, got %q", result.Response.Header.Get("Content-Type")))
+	}
+	return result, nil
+}
+
+// readSSE parses event:, id: and data: fields into events, returning the raw stream read so far.
+// An event is dispatched at the blank line that ends it, lines starting with : are comments.
+func readSSE(r io.Reader, maxEvents int) ([]byte, []SSEEvent, error) {
+	var raw bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(r, &raw))
+
+	var events []SSEEvent
+	var event SSEEvent
+	var data []string
+	for maxEvents <= 0 || len(events) < maxEvents {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return raw.Bytes(), events, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if data != nil {
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				event.Data = strings.Join(data, "\n")
+				events = append(events, event)
+			}
+			event, data = SSEEvent{ID: event.ID}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "id":
+			event.ID = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	return raw.Bytes(), events, nil
+}
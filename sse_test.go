@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSSEEventsInReport(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": keep-alive\n\nevent: greeting\nid: 1\ndata: hello\n\ndata: line one\ndata: line two\n\nevent: extra\ndata: not read\n\n"))
+		w.(http.Flusher).Flush()
+		// The stream stays open, only -sse-max-events ends the read
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, SSE: true, SSEMaxEvents: 2, Report: ReportOptions{Sink: sink}}
+	outcome := runner.Run(NewURLRequest(srv.URL), "out")
+	if !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+	if len(outcome.Result.Events) != 2 {
+		t.Fatalf("read %d events, want 2: %+v", len(outcome.Result.Events), outcome.Result.Events)
+	}
+
+	report := sink.report(t, ".txt")
+	want := "\nServer-Sent Events: 2\n[1] event: greeting\nid: 1\ndata: hello\n[2] event: message\nid: 1\ndata: line one\ndata: line two\n"
+	if !strings.Contains(report, want) {
+		t.Errorf("report does not list the two events:\n%s", report)
+	}
+	if _, events, _ := strings.Cut(report, "Server-Sent Events:"); strings.Contains(events, "event: extra") {
+		t.Errorf("report lists an event beyond -sse-max-events:\n%s", report)
+	}
+}
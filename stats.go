@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// LatencyStats summarizes the latencies of a run in milliseconds
+type LatencyStats struct {
+	Count  int     `json:"count"`
+	MinMS  float64 `json:"min_ms"`
+	MeanMS float64 `json:"mean_ms"`
+	P50MS  float64 `json:"p50_ms"`
+	P95MS  float64 `json:"p95_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// NewLatencyStats computes the latency distribution of the given samples
+func NewLatencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+
+	return LatencyStats{
+		Count:  len(sorted),
+		MinMS:  milliseconds(sorted[0]),
+		MeanMS: milliseconds(total / time.Duration(len(sorted))),
+		P50MS:  milliseconds(Percentile(sorted, 50)),
+		P95MS:  milliseconds(Percentile(sorted, 95)),
+		P99MS:  milliseconds(Percentile(sorted, 99)),
+		MaxMS:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// Percentile returns the nearest-rank percentile p of sorted latencies
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
+
+// String renders the stats as a one line summary
+func (s LatencyStats) String() string {
+	return fmt.Sprintf("latency n=%d min=%.1fms mean=%.1fms p50=%.1fms p95=%.1fms p99=%.1fms max=%.1fms",
+		s.Count, s.MinMS, s.MeanMS, s.P50MS, s.P95MS, s.P99MS, s.MaxMS)
+}
+
+// outcomeLatencies collects the latency of every outcome that received a response
+func outcomeLatencies(outcomes []Outcome) []time.Duration {
+	var latencies []time.Duration
+	for _, o := range outcomes {
+		if o.Result != nil {
+			latencies = append(latencies, o.Latency())
+		}
+	}
+	return latencies
+}
+
+// ConnReuse counts how many responses came over a reused keep-alive connection and how many over a new one
+type ConnReuse struct {
+	Reused int     `json:"reused"`
+	New    int     `json:"new"`
+	Rate   float64 `json:"rate"`
+}
+
+// NewConnReuse computes the connection reuse of every outcome that received a response
+func NewConnReuse(outcomes []Outcome) ConnReuse {
+	var reuse ConnReuse
+	for _, o := range outcomes {
+		if o.Result == nil {
+			continue
+		}
+		if o.Result.ConnReused {
+			reuse.Reused++
+		} else {
+			reuse.New++
+		}
+	}
+	if total := reuse.Reused + reuse.New; total > 0 {
+		reuse.Rate = float64(reuse.Reused) / float64(total)
+	}
+	return reuse
+}
+
+// String renders the reuse as a one line summary
+func (c ConnReuse) String() string {
+	return fmt.Sprintf("connection reuse %.1f%%: %d reused, %d new", c.Rate*100, c.Reused, c.New)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
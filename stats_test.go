@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPercentileNearestRank(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 95: 95 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := Percentile(sorted, p); got != want {
+			t.Errorf("p%v = %s, want %s", p, got, want)
+		}
+	}
+}
+
+func TestPercentileGates(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "-url", srv.URL, "-repeat", "5", "-expect-p95", "10s", "-expect-p99", "10s", "-output", "fast")
+	if code != 0 || !strings.Contains(stdout, "latency n=5") {
+		t.Errorf("gates met: exit code %d, want 0 with latency stats of 5 runs:\n%s%s", code, stdout, stderr)
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL, "-repeat", "5", "-expect-p95", "1ms", "-expect-p99", "1ms", "-output", "slow")
+	if code != 1 || !strings.Contains(stderr, "exceeds -expect-p95 1ms") || !strings.Contains(stderr, "exceeds -expect-p99 1ms") {
+		t.Errorf("gates missed: exit code %d, want 1 with both gates failing: %s", code, stderr)
+	}
+}
+
+func TestKeepaliveProbeReportsReuseRate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "-url", srv.URL, "-repeat", "10", "-keepalive-probe", "-output", "kept")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout+stderr, "connection reuse 90.0%: 9 reused, 1 new") {
+		t.Errorf("output does not report 9 of 10 requests reusing the connection:\n%s%s", stdout, stderr)
+	}
+
+	stdout, stderr, code = runMain(t, dir, "-url", srv.URL, "-repeat", "4", "-keepalive-probe", "-disable-keepalive", "-output", "closed")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout+stderr, "connection reuse 0.0%: 0 reused, 4 new") {
+		t.Errorf("output does not report every request opening a connection with -disable-keepalive:\n%s%s", stdout, stderr)
+	}
+}
+
+func TestWarmupRunsExcludedFromLatencySummary(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The warmup requests are slow, like a cold cache
+		if calls.Add(1) <= 2 {
+			time.Sleep(300 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	summaryPath := filepath.Join(dir, "summary.json")
+	stdout, stderr, code := runMain(t, dir, "-url", srv.URL, "-warmup", "2", "-measure", "5", "-summary-json", summaryPath, "-output", "bench")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if calls.Load() != 7 || strings.Count(stdout, "warmup:") != 2 {
+		t.Errorf("server got %d requests with %d warmup lines, want 7 with 2 warmups", calls.Load(), strings.Count(stdout, "warmup:"))
+	}
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Total != 5 || summary.Latency == nil || summary.Latency.Count != 5 {
+		t.Fatalf("summary has %d requests and latency stats %+v, want 5 measured ones", summary.Total, summary.Latency)
+	}
+	if summary.Latency.MaxMS >= 300 {
+		t.Errorf("latency max is %.1fms, want the slow warmup runs left out", summary.Latency.MaxMS)
+	}
+}
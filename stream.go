@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ExecuteStream sends the request and appends every chunk of the response body to w as it arrives,
+// each under the time it was read. Reading stops when the server closes the body or, when duration
+// is set, once it has elapsed since the request was sent, which does not count as a failure.
+func ExecuteStream(ctx context.Context, client *http.Client, reqData RequestData, w io.Writer, duration time.Duration) (*Result, error) {
+	streamCtx := ctx
+	if duration > 0 {
+		var cancel context.CancelFunc
+		streamCtx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, err := execute(streamCtx, client, reqData, func(r io.Reader) ([]byte, error) {
+		return streamChunks(r, w, start)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The body was cut off by -stream-duration rather than by a timeout of the request
+	if duration > 0 && errors.Is(streamCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		result.ReadErr = nil
+		result.Failures = nil
+		_, err = io.WriteString(w, fmt.Sprintf("[%s +%s] stream duration %s elapsed\n", time.Now().Format(time.RFC3339Nano), time.Since(start).Round(time.Millisecond), duration))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// streamChunks copies r to w chunk by chunk, each preceded by a timestamp line, and returns everything read
+func streamChunks(r io.Reader, w io.Writer, start time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			body.Write(buf[:n])
+			_, werr := io.WriteString(w, fmt.Sprintf("[%s +%s] %d bytes\n%s\n", time.Now().Format(time.RFC3339Nano), time.Since(start).Round(time.Millisecond), n, buf[:n]))
+			if werr != nil {
+				return body.Bytes(), werr
+			}
+		}
+		if err == io.EOF {
+			return body.Bytes(), nil
+		}
+		if err != nil {
+			return body.Bytes(), err
+		}
+	}
+}
+
+// stream sends the request with ExecuteStream, writing the chunks to a -stream.txt report next to the others
+func (r *Runner) stream(ctx context.Context, reqData RequestData, outputPath string) (*Result, error) {
+	file, err := r.Report.sink().Create(outputPath + "|" + fmt.Sprintf("%v", time.Now().Unix()) + "-stream.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, fmt.Sprintf("Request Method: %s\nRequest URL: %s\n\n", reqData.Method, reqData.URL))
+	if err != nil {
+		return nil, err
+	}
+	return ExecuteStream(ctx, r.Client, reqData, file, r.StreamDuration)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chunkLine matches the timestamp line streamChunks writes before each chunk
+var chunkLine = regexp.MustCompile(`\[(\S+) \+\S+\] \d+ bytes\n(chunk-\d+)\n`)
+
+func TestStreamWritesTimestampedChunksInOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "chunk-%d", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Stream: true, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request failed: %v", outcome.Err)
+	}
+
+	report := sink.report(t, "-stream.txt")
+	matches := chunkLine.FindAllStringSubmatch(report, -1)
+	if len(matches) != 3 {
+		t.Fatalf("stream report has %d timestamped chunks, want 3:\n%s", len(matches), report)
+	}
+	var last time.Time
+	for i, m := range matches {
+		if want := fmt.Sprintf("chunk-%d", i+1); m[2] != want {
+			t.Errorf("chunk %d is %s, want %s", i+1, m[2], want)
+		}
+		at, err := time.Parse(time.RFC3339Nano, m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && at.Sub(last) < 40*time.Millisecond {
+			t.Errorf("chunk %d was stamped %s after the previous one, want the 50ms the server waited", i+1, at.Sub(last))
+		}
+		last = at
+	}
+}
+
+func TestStreamDurationStopsEndlessStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; r.Context().Err() == nil; i++ {
+			fmt.Fprintf(w, "chunk-%d", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	sink := &memorySink{}
+	runner := &Runner{Client: srv.Client(), Retry: 1, Stream: true, StreamDuration: 150 * time.Millisecond, Report: ReportOptions{Sink: sink}}
+	if outcome := runner.Run(NewURLRequest(srv.URL), "out"); !outcome.Passed {
+		t.Fatalf("request cut off by -stream-duration failed: %v", outcome.Err)
+	}
+	if report := sink.report(t, "-stream.txt"); !strings.Contains(report, "stream duration 150ms elapsed\n") || !strings.Contains(report, "chunk-1\n") {
+		t.Errorf("stream report does not end with the elapsed duration:\n%s", report)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Summary aggregates the outcomes of a batch run
+type Summary struct {
+	Total    int            `json:"total"`
+	Passed   int            `json:"passed"`
+	Failed   int            `json:"failed"`
+	Pass     bool           `json:"pass"`
+	Latency  *LatencyStats  `json:"latency,omitempty"`
+	Failures []string       `json:"failures,omitempty"`
+	Requests []SummaryEntry `json:"requests"`
+
+	// ConnReuse is set by -keepalive-probe
+	ConnReuse *ConnReuse `json:"conn_reuse,omitempty"`
+	// Canary is set by -canary-url
+	Canary *CanaryComparison `json:"canary,omitempty"`
+	// Ramp is set by -ramp-to, one entry per concurrency level
+	Ramp []RampLevel `json:"ramp,omitempty"`
+}
+
+// SummaryEntry is the outcome of a single request in a Summary
+type SummaryEntry struct {
+	Method    string   `json:"method"`
+	URL       string   `json:"url"`
+	Status    int      `json:"status,omitempty"`
+	LatencyMS int64    `json:"latency_ms"`
+	Attempts  int      `json:"attempts"`
+	Passed    bool     `json:"passed"`
+	Error     string   `json:"error,omitempty"`
+	Failures  []string `json:"failures,omitempty"`
+
+	// ErrorMessage is the error field of a failed JSON response
+	ErrorMessage string `json:"error_message,omitempty"`
+	// TraceID is the -trace-id-header value of the response
+	TraceID string `json:"trace_id,omitempty"`
+	// Extracted is the -extract value of the response, ExtractMissing says why there is none
+	Extracted      string `json:"extracted,omitempty"`
+	ExtractMissing string `json:"extract_missing,omitempty"`
+}
+
+// NewSummary builds a Summary from the outcomes of a batch
+func NewSummary(outcomes []Outcome) Summary {
+	summary := Summary{Total: len(outcomes), Requests: []SummaryEntry{}}
+
+	for _, o := range outcomes {
+		entry := SummaryEntry{
+			Method:    o.Request.Method,
+			URL:       o.Request.URL,
+			LatencyMS: o.Latency().Milliseconds(),
+			Attempts:  len(o.Attempts),
+			Passed:    o.Passed,
+
+			ErrorMessage:   o.ErrorMessage,
+			TraceID:        o.TraceID,
+			Extracted:      o.Extracted,
+			ExtractMissing: o.ExtractMissing,
+		}
+		if o.Result != nil {
+			entry.Status = o.Result.Response.StatusCode
+			entry.Failures = o.Result.Failures
+		}
+		if o.Err != nil {
+			entry.Error = o.Err.Error()
+		}
+
+		if o.Passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		summary.Requests = append(summary.Requests, entry)
+	}
+
+	if latencies := outcomeLatencies(outcomes); len(latencies) > 0 {
+		stats := NewLatencyStats(latencies)
+		summary.Latency = &stats
+	}
+
+	summary.Pass = summary.Failed == 0
+	return summary
+}
+
+// WriteSummaryJSON writes the summary as indented JSON
+func WriteSummaryJSON(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Fail records a run level failure, such as an unmet latency gate, and marks the summary failed
+func (s *Summary) Fail(failure string) {
+	s.Failures = append(s.Failures, failure)
+	s.Pass = false
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSummaryJSONOfThreeRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "batch.http", fmt.Sprintf("GET %[1]s/one\n\n###\nPOST %[1]s/two\n\n###\nGET %[1]s/fail\n", srv.URL))
+	summaryPath := filepath.Join(dir, "summary.json")
+	if _, stderr, code := runMain(t, dir, "-source", source, "-output", "out", "-summary-json", summaryPath); code != 1 {
+		t.Fatalf("exit code %d, want 1 for the failed request: %s", code, stderr)
+	}
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Total != 3 || summary.Passed != 2 || summary.Failed != 1 || summary.Pass {
+		t.Errorf("summary total %d passed %d failed %d pass %t, want 3, 2, 1 and false", summary.Total, summary.Passed, summary.Failed, summary.Pass)
+	}
+	want := []SummaryEntry{
+		{Method: "GET", URL: srv.URL + "/one", Status: 200, Passed: true},
+		{Method: "POST", URL: srv.URL + "/two", Status: 200, Passed: true},
+		{Method: "GET", URL: srv.URL + "/fail", Status: 500, Error: "server error: 500 Internal Server Error"},
+	}
+	for i, entry := range summary.Requests {
+		got := SummaryEntry{Method: entry.Method, URL: entry.URL, Status: entry.Status, Passed: entry.Passed, Error: entry.Error}
+		if i >= len(want) || fmt.Sprint(got) != fmt.Sprint(want[i]) {
+			t.Errorf("request %d = %+v, want %+v", i+1, got, want)
+		}
+	}
+	if len(summary.Requests) != 3 {
+		t.Errorf("%d requests in the summary, want 3", len(summary.Requests))
+	}
+	if summary.Latency == nil {
+		t.Error("summary has no latency stats")
+	}
+}
+
+func TestSummaryShowsErrorFieldOfFailedRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"email is required","detail":{"reason":"missing field"}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for _, tt := range []struct{ field, want string }{{"", "email is required"}, {"$.detail.reason", "missing field"}} {
+		summaryPath := filepath.Join(dir, "summary.json")
+		args := []string{"-url", srv.URL, "-expect-status", "200", "-output", "out", "-summary-json", summaryPath}
+		if tt.field != "" {
+			args = append(args, "-error-field", tt.field)
+		}
+		_, stderr, code := runMain(t, dir, args...)
+		if code != 1 || !strings.Contains(stderr, "error message: "+tt.want) {
+			t.Errorf("-error-field %q: exit code %d, want 1 with the message on stderr: %s", tt.field, code, stderr)
+		}
+
+		data, err := os.ReadFile(summaryPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var summary Summary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			t.Fatal(err)
+		}
+		if len(summary.Requests) != 1 || summary.Requests[0].ErrorMessage != tt.want {
+			t.Errorf("-error-field %q: summary entries %+v, want the error message %q", tt.field, summary.Requests, tt.want)
+		}
+	}
+}
+
+func TestTraceIDHeaderCapturedAndPrinted(t *testing.T) {
+	const traceID = "Root=1-67891233-abcdef012345678912345678"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Trace-Id", traceID)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	summaryPath := filepath.Join(dir, "summary.json")
+	stdout, _, _ := runMain(t, dir, "-url", srv.URL, "-retry", "1", "-trace-id-header", "X-Amzn-Trace-Id", "-format", "txt,json", "-summary-json", summaryPath, "-output", "out")
+
+	if !strings.Contains(stdout, "[trace "+traceID+"]") {
+		t.Errorf("status line does not show the trace ID:\n%s", stdout)
+	}
+	if report := readReport(t, filepath.Join(dir, "out|*.txt")); !strings.Contains(report, "Trace ID (X-Amzn-Trace-Id): "+traceID+"\n") {
+		t.Errorf("txt report does not show the trace ID:\n%s", report)
+	}
+	if report := readReport(t, filepath.Join(dir, "out|*.json")); !strings.Contains(report, `"trace_id": "`+traceID+`"`) {
+		t.Errorf("json report does not hold the trace ID:\n%s", report)
+	}
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary struct {
+		Requests []struct {
+			TraceID string `json:"trace_id"`
+		} `json:"requests"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Requests) != 1 || summary.Requests[0].TraceID != traceID {
+		t.Errorf("summary does not hold the trace ID:\n%s", data)
+	}
+}
+
+func TestMinSuccessRateGatesBatch(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// One request in ten fails
+		if calls.Add(1)%10 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	stdout, stderr, code := runMain(t, dir, "-url", srv.URL, "-repeat", "10", "-min-success-rate", "0.85", "-output", "lenient")
+	if code != 0 || !strings.Contains(stdout, "success rate 90.0% meets -min-success-rate 85.0%") {
+		t.Errorf("at 0.85: exit code %d, want 0 with the rate met:\n%s%s", code, stdout, stderr)
+	}
+
+	_, stderr, code = runMain(t, dir, "-url", srv.URL, "-repeat", "10", "-min-success-rate", "0.95", "-output", "strict")
+	if code != 1 || !strings.Contains(stderr, "success rate 90.0% is below -min-success-rate 95.0%") {
+		t.Errorf("at 0.95: exit code %d, want 1 with the rate below the threshold: %s", code, stderr)
+	}
+}
+
+func TestExtractPrintsValueAndReportsMissingPointer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/created" {
+			w.Write([]byte(`{"data": {"id": 42}}`))
+			return
+		}
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	source := writeFile(t, dir, "batch.http", fmt.Sprintf("POST %[1]s/created\n\n###\nGET %[1]s/empty\n", srv.URL))
+	summaryPath := filepath.Join(dir, "summary.json")
+	stdout, stderr, code := runMain(t, dir, "-source", source, "-extract", "/data/id", "-summary-json", summaryPath, "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d, want 0 since a missing value only warns: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "extract /data/id: 42") {
+		t.Errorf("output does not print the extracted id:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "extract /data/id from "+srv.URL+"/empty: not found") {
+		t.Errorf("missing pointer is not reported: %s", stderr)
+	}
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Requests) != 2 || summary.Requests[0].Extracted != "42" || summary.Requests[1].ExtractMissing != "not found" {
+		t.Errorf("summary requests are %+v, want 42 extracted from the first and not found for the second", summary.Requests)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// Tee writes every response body to a file and to stdout as well
+type Tee struct {
+	mu   sync.Mutex
+	file *os.File
+	w    io.Writer
+}
+
+// NewTee creates the -tee file, stdout is written alongside it
+func NewTee(path string) (*Tee, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Tee{file: file, w: io.MultiWriter(file, stdout)}, nil
+}
+
+// Write writes the whole body, whatever -max-response-bytes truncates in the report
+func (t *Tee) Write(body []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := t.w.Write(body); err != nil {
+		printError("tee:", err)
+	}
+}
+
+func (t *Tee) Close() error {
+	return t.file.Close()
+}
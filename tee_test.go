@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTeeWritesFullBodyToFileAndStdout(t *testing.T) {
+	body := strings.Repeat("0123456789abcdef", 1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "body.out")
+	stdout, stderr, code := runMain(t, dir, "-url", srv.URL, "-tee", path, "-max-response-bytes", "100", "-output", "out")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	// -max-response-bytes only truncates the report, the tee gets every byte
+	if data, err := os.ReadFile(path); err != nil || string(data) != body {
+		t.Errorf("tee file has %d bytes, want the full %d byte body: %v", len(data), len(body), err)
+	}
+	if !strings.Contains(stdout, body) {
+		t.Errorf("stdout does not contain the full %d byte body", len(body))
+	}
+	if report := readReport(t, filepath.Join(dir, "out|*.txt")); strings.Contains(report, body) {
+		t.Error("report holds the full body, want it truncated by -max-response-bytes")
+	}
+}
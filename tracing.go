@@ -0,0 +1,26 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// newTraceparent returns a W3C trace context header for a new sampled trace
+func newTraceparent() string {
+	var traceID [16]byte
+	var spanID [8]byte
+	rand.Read(traceID[:])
+	rand.Read(spanID[:])
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(traceID[:]), hex.EncodeToString(spanID[:]))
+}
+
+// addTraceHeaders sets X-Request-ID and traceparent unless the request already carries them
+func addTraceHeaders(reqData *RequestData) {
+	if !hasHeader(reqData.Headers, "X-Request-ID") {
+		reqData.Headers["X-Request-ID"] = newUUID()
+	}
+	if !hasHeader(reqData.Headers, "traceparent") {
+		reqData.Headers["traceparent"] = newTraceparent()
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestTraceHeadersSent(t *testing.T) {
+	traceparentPattern := regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`)
+
+	var traceparents, requestIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+	}))
+	defer srv.Close()
+
+	runner := &Runner{Client: srv.Client(), Retry: 1, TraceHeaders: true, Report: ReportOptions{Sink: DiscardSink{}}}
+	runner.Run(NewURLRequest(srv.URL), "first")
+	runner.Run(NewURLRequest(srv.URL), "second")
+
+	for _, tp := range traceparents {
+		if !traceparentPattern.MatchString(tp) {
+			t.Errorf("traceparent = %q, want version 00 with a trace ID, span ID and sampled flag", tp)
+		}
+	}
+	if len(traceparents) != 2 || traceparents[0] == traceparents[1] || requestIDs[0] == "" || requestIDs[0] == requestIDs[1] {
+		t.Errorf("traceparents %q and request IDs %q, want new ones per request", traceparents, requestIDs)
+	}
+
+	own := NewURLRequest(srv.URL)
+	own.Headers["traceparent"] = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	runner.Run(own, "own")
+	if got := traceparents[2]; got != own.Headers["traceparent"] {
+		t.Errorf("traceparent = %q, want the request's own header kept", got)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var placeholderPattern = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+
+// Substitute expands {{name}} placeholders from vars and {{$name}} dynamic variables, leaving unknown ones as is
+func Substitute(text string, vars map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		expr := placeholderPattern.FindStringSubmatch(match)[1]
+
+		if strings.HasPrefix(expr, "$") {
+			if value, ok := dynamicVariable(expr); ok {
+				return value
+			}
+			return match
+		}
+
+		if value, ok := vars[expr]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// SubstituteRequest returns a copy of reqData with placeholders expanded in the URL, headers and body
+func SubstituteRequest(reqData RequestData, vars map[string]string) RequestData {
+	out := reqData
+	out.URL = Substitute(reqData.URL, vars)
+	out.Body = Substitute(reqData.Body, vars)
+	out.Headers = make(map[string]string, len(reqData.Headers))
+	for k, v := range reqData.Headers {
+		out.Headers[k] = Substitute(v, vars)
+	}
+	if reqData.Trailers != nil {
+		out.Trailers = make(map[string]string, len(reqData.Trailers))
+		for k, v := range reqData.Trailers {
+			out.Trailers[k] = Substitute(v, vars)
+		}
+	}
+	return out
+}
+
+// dynamicVariable evaluates the VS Code REST Client style dynamic variables
+func dynamicVariable(expr string) (string, bool) {
+	fields := strings.Fields(expr)
+
+	switch fields[0] {
+	case "$uuid", "$guid":
+		return newUUID(), true
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), true
+	case "$randomInt":
+		min, max := int64(0), int64(1000)
+		if len(fields) == 3 {
+			var err1, err2 error
+			min, err1 = strconv.ParseInt(fields[1], 10, 64)
+			max, err2 = strconv.ParseInt(fields[2], 10, 64)
+			if err1 != nil || err2 != nil || max <= min {
+				return "", false
+			}
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(max-min))
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatInt(min+n.Int64(), 10), true
+	}
+
+	return "", false
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDynamicVariableShapes(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	out := Substitute("{{$uuid}} {{$timestamp}} {{$randomInt 10 20}} {{$unknown}} {{name}}", map[string]string{"name": "Alice"})
+	fields := strings.Fields(out)
+	if len(fields) != 5 {
+		t.Fatalf("Substitute gave %q, want five fields", out)
+	}
+
+	if !uuidPattern.MatchString(fields[0]) {
+		t.Errorf("$uuid = %q, want a version 4 UUID", fields[0])
+	}
+	if ts, err := strconv.ParseInt(fields[1], 10, 64); err != nil || ts < 1e9 {
+		t.Errorf("$timestamp = %q, want Unix seconds", fields[1])
+	}
+	if n, err := strconv.Atoi(fields[2]); err != nil || n < 10 || n >= 20 {
+		t.Errorf("$randomInt 10 20 = %q, want 10 to 19", fields[2])
+	}
+	if fields[3] != "{{$unknown}}" {
+		t.Errorf("unknown dynamic variable = %q, want it left as is", fields[3])
+	}
+	if fields[4] != "Alice" {
+		t.Errorf("{{name}} = %q, want Alice", fields[4])
+	}
+}
+
+func TestUUIDDiffersPerPlaceholder(t *testing.T) {
+	out := strings.Fields(Substitute("{{$uuid}} {{$uuid}}", nil))
+	if len(out) != 2 || out[0] == out[1] {
+		t.Errorf("two $uuid placeholders gave %q, want two different UUIDs", out)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// WaitFor polls the request until it returns the expected status or the timeout passes
+func WaitFor(client *http.Client, reqData RequestData, status int, interval, timeout time.Duration) (*Result, error) {
+	deadline := time.Now().Add(timeout)
+	var last string
+
+	for {
+		result, err := Execute(context.Background(), client, reqData)
+		if err == nil && result.Response.StatusCode == status {
+			return result, nil
+		}
+
+		if err != nil {
+			last = err.Error()
+		} else {
+			last = result.Response.Status
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for status %d, last result: %s", timeout, status, last)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// WaitForPort polls the host:port address until it accepts a TCP connection or the timeout passes
+func WaitForPort(address string, interval, timeout time.Duration) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("invalid -wait-for-port %q: %w", address, err)
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		conn, err := net.DialTimeout("tcp", address, max(time.Until(deadline), 0))
+		if err == nil {
+			return conn.Close()
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for port %s, last error: %v", timeout, address, err)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForPollsUntilStatus(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ready"))
+	}))
+	defer srv.Close()
+
+	result, err := WaitFor(srv.Client(), NewURLRequest(srv.URL), http.StatusOK, 10*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Response.StatusCode != http.StatusOK || string(result.Body) != "ready" {
+		t.Errorf("got %s %q, want 200 ready", result.Response.Status, result.Body)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("polled %d times, want 2", n)
+	}
+}
+
+func TestWaitForPortSucceedsOncePortOpens(t *testing.T) {
+	// Reserve a free port, then free it so nothing listens until the server starts
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	const delay = 200 * time.Millisecond
+	started := make(chan *httptest.Server, 1)
+	go func() {
+		time.Sleep(delay)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			started <- nil
+			return
+		}
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.Listener = ln
+		srv.Start()
+		started <- srv
+	}()
+
+	start := time.Now()
+	if err := WaitForPort(addr, 20*time.Millisecond, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < delay {
+		t.Errorf("WaitForPort returned after %s, before the port opened at %s", waited, delay)
+	}
+	srv := <-started
+	if srv == nil {
+		t.Fatal("delayed server could not listen on the port")
+	}
+	srv.Close()
+
+	if err := WaitForPort(addr, 20*time.Millisecond, 100*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("closed port: error %v, want a timeout", err)
+	}
+}
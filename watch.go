@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// watchFile polls the file and calls onChange whenever its modification time or size changes, until ctx is done
+func watchFile(ctx context.Context, path string, interval time.Duration, onChange func()) error {
+	last, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Editors often replace the file on save, keep polling until it is back
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+
+		last = info
+		onChange()
+	}
+}
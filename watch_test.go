@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFileRerunsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.http")
+	if err := os.WriteFile(path, []byte("GET http://a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- watchFile(ctx, path, 10*time.Millisecond, func() { changes <- struct{}{} })
+	}()
+
+	// Nothing changed yet, so the watcher must not rerun
+	select {
+	case <-changes:
+		t.Fatal("rerun before the file changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("GET http://b/longer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-ctx.Done():
+		t.Fatal("no rerun after the file changed")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watchFile returned %v after being stopped", err)
+	}
+}